@@ -12,8 +12,12 @@ import (
 	"github.com/cmdb-lite/backend/internal/auth"
 	"github.com/cmdb-lite/backend/internal/config"
 	"github.com/cmdb-lite/backend/internal/database"
+	"github.com/cmdb-lite/backend/internal/events"
+	"github.com/cmdb-lite/backend/internal/outbox"
 	"github.com/cmdb-lite/backend/internal/repositories"
 	"github.com/cmdb-lite/backend/internal/router"
+	"github.com/cmdb-lite/backend/internal/trash"
+	"github.com/cmdb-lite/backend/internal/webhook"
 )
 
 type HealthResponse struct {
@@ -55,8 +59,35 @@ func main() {
 	// Create repositories wrapper
 	repoDB := repositories.NewDB(db.DB)
 
+	// Stream changes to clients; every replica listens for the changes committed through any
+	// of them
+	streamCtx, stopStreaming := context.WithCancel(context.Background())
+	defer stopStreaming()
+	hub := events.NewHub(repositories.NewEventPostgresRepository(repoDB.DB))
+	go hub.Listen(streamCtx, cfg.DatabaseURL())
+
 	// Setup router with all endpoints
-	r := router.SetupRouter(repoDB, jwtManager, passwordManager, cfg)
+	r := router.SetupRouter(repoDB, jwtManager, passwordManager, cfg, hub)
+
+	// Purge CIs that have been in the trash longer than the retention period
+	purgeCtx, stopPurging := context.WithCancel(context.Background())
+	defer stopPurging()
+	purger := trash.NewPurger(repositories.NewPostgresUnitOfWork(repoDB.DB), cfg.TrashRetention, cfg.TrashPurgeInterval)
+	go purger.Run(purgeCtx)
+
+	// Deliver webhook events; every replica runs a dispatcher
+	deliverCtx, stopDelivering := context.WithCancel(context.Background())
+	defer stopDelivering()
+	dispatcher := webhook.NewDispatcher(repositories.NewWebhookPostgresRepository(repoDB.DB),
+		cfg.WebhookTimeout, cfg.WebhookMaxAttempts, cfg.WebhookDeliveryInterval)
+	go dispatcher.Run(deliverCtx)
+
+	// Relay the outbox's domain events; every replica runs a relay
+	relayCtx, stopRelaying := context.WithCancel(context.Background())
+	defer stopRelaying()
+	relay := outbox.NewRelay(repositories.NewOutboxPostgresRepository(repoDB.DB), outbox.NewLogPublisher(),
+		cfg.OutboxRelayInterval, cfg.OutboxLease, cfg.OutboxRetention)
+	go relay.Run(relayCtx)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -64,6 +95,9 @@ func main() {
 		Handler: r,
 	}
 
+	// End the event streams when shutting down, since they never become idle
+	server.RegisterOnShutdown(stopStreaming)
+
 	// Start server in a goroutine
 	go func() {
 		log.Printf("Server listening on port %d", cfg.ServerPort)
@@ -18,6 +18,26 @@ type Config struct {
 	DatabaseName string
 	JWTSecret    string
 	
+	// Pagination configuration
+	CursorSecret string
+	
+	// Impact analysis configuration
+	ImpactRelationshipTypes []string
+	
+	// Trash configuration
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	// Webhook configuration
+	WebhookDeliveryInterval time.Duration
+	WebhookMaxAttempts      int
+	WebhookTimeout          time.Duration
+
+	// Outbox configuration
+	OutboxRelayInterval time.Duration
+	OutboxLease         time.Duration
+	OutboxRetention     time.Duration
+	
 	// Token configuration
 	AccessTokenDuration  time.Duration
 	RefreshTokenDuration time.Duration
@@ -42,6 +62,7 @@ type Config struct {
 	CORSAllowedOrigins []string
 	CORSAllowedMethods []string
 	CORSAllowedHeaders []string
+	CORSExposedHeaders []string
 	CORSAllowCredentials bool
 }
 
@@ -77,11 +98,32 @@ func Load() *Config {
 		
 		// CORS configuration
 		CORSAllowedOrigins:   getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"*"}),
-		CORSAllowedMethods:   getEnvAsSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		CORSAllowedHeaders:   getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"}),
+		CORSAllowedMethods:   getEnvAsSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		CORSAllowedHeaders:   getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization", "If-Match"}),
+		CORSExposedHeaders:   getEnvAsSlice("CORS_EXPOSED_HEADERS", []string{"ETag"}),
 		CORSAllowCredentials: getEnvAsBool("CORS_ALLOW_CREDENTIALS", true),
+		
+		// Impact analysis configuration; unset means the impact handler's defaults
+		ImpactRelationshipTypes: getEnvAsSlice("IMPACT_RELATIONSHIP_TYPES", nil),
+		
+		// Trash configuration
+		TrashRetention:     getEnvAsDuration("TRASH_RETENTION", "720h"), // 30 days
+		TrashPurgeInterval: getEnvAsDuration("TRASH_PURGE_INTERVAL", "1h"),
+
+		// Webhook configuration
+		WebhookDeliveryInterval: getEnvAsDuration("WEBHOOK_DELIVERY_INTERVAL", "5s"),
+		WebhookMaxAttempts:      getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 10),
+		WebhookTimeout:          getEnvAsDuration("WEBHOOK_TIMEOUT", "10s"),
+
+		// Outbox configuration
+		OutboxRelayInterval: getEnvAsDuration("OUTBOX_RELAY_INTERVAL", "1s"),
+		OutboxLease:         getEnvAsDuration("OUTBOX_LEASE", "1m"),
+		OutboxRetention:     getEnvAsDuration("OUTBOX_RETENTION", "168h"), // 7 days
 	}
 	
+	// Pagination cursors are signed with the JWT secret unless a dedicated secret is set
+	cfg.CursorSecret = getEnv("CURSOR_SECRET", cfg.JWTSecret)
+	
 	return cfg
 }
 
@@ -0,0 +1,288 @@
+// Package csvimport reads configuration items from CSV files, using a mapping from the
+// columns of the file to CI fields.
+package csvimport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cmdb-lite/backend/internal/models"
+)
+
+// Targets of mapped columns. Attribute targets are the prefix followed by a dot-separated
+// path, e.g. attributes.os.name.
+const (
+	TargetName      = "name"
+	TargetType      = "type"
+	TargetTags      = "tags"
+	AttributePrefix = "attributes."
+)
+
+// DefaultTagSeparator separates the tags in a cell when the mapping sets no separator
+const DefaultTagSeparator = ";"
+
+// ExternalIDMapping names the column holding the rows' external IDs and their source
+type ExternalIDMapping struct {
+	Source string `json:"source"`
+	Column string `json:"column"`
+}
+
+// Mapping maps the columns of a CSV file, by header, to CI fields. Columns that are not
+// mapped are ignored. With an external ID, rows are matched to the CIs that have it.
+type Mapping struct {
+	Columns      map[string]string  `json:"columns"`
+	ExternalID   *ExternalIDMapping `json:"external_id,omitempty"`
+	TagSeparator string             `json:"tag_separator,omitempty"`
+}
+
+// MappingError is a mapping that cannot be applied to a file. Details lists the problems by
+// field, like validation errors.
+type MappingError struct {
+	Details map[string][]string
+}
+
+// Error implements the error interface
+func (e *MappingError) Error() string {
+	return "invalid mapping"
+}
+
+// Row is a data row of a CSV file read with a mapping. Line is the line the row starts on,
+// counting the header as line 1. ExternalID is empty unless the mapping has one.
+type Row struct {
+	Line       int
+	CI         *models.CI
+	ExternalID string
+}
+
+// Read reads the rows of a CSV file whose first line is the header. It returns a
+// *MappingError if the mapping does not fit the header and any other error if the file is
+// not valid CSV. Cells are trimmed; empty attribute cells are left out of the attributes.
+func Read(r io.Reader, mapping Mapping) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, &MappingError{Details: map[string][]string{"file": {"the file has no header"}}}
+		}
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if details := mapping.validate(columns); len(details) > 0 {
+		return nil, &MappingError{Details: details}
+	}
+
+	separator := mapping.TagSeparator
+	if separator == "" {
+		separator = DefaultTagSeparator
+	}
+
+	rows := []Row{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		row := Row{
+			Line: line,
+			CI:   &models.CI{Attributes: models.JSONBMap{}, Tags: []string{}},
+		}
+		for column, target := range mapping.Columns {
+			value := cell(record, columns[column])
+			switch {
+			case target == TargetName:
+				row.CI.Name = value
+			case target == TargetType:
+				row.CI.Type = value
+			case target == TargetTags:
+				row.CI.Tags = splitTags(value, separator)
+			case value != "":
+				setAttribute(row.CI.Attributes, strings.Split(strings.TrimPrefix(target, AttributePrefix), "."), value)
+			}
+		}
+
+		if mapping.ExternalID != nil {
+			row.ExternalID = cell(record, columns[mapping.ExternalID.Column])
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// validate checks the mapping against the columns of the file, given by header
+func (m *Mapping) validate(columns map[string]int) map[string][]string {
+	details := make(map[string][]string)
+	addError := func(field, message string) {
+		details[field] = append(details[field], message)
+	}
+
+	if len(m.Columns) == 0 {
+		addError("columns", "at least one column must be mapped")
+	}
+
+	// Check the columns in a stable order so the messages are too
+	names := make([]string, 0, len(m.Columns))
+	for name := range m.Columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	targets := make(map[string]string, len(names))
+	for _, name := range names {
+		target := m.Columns[name]
+		if _, ok := columns[name]; !ok {
+			addError("columns", fmt.Sprintf("column '%s' is not in the file", name))
+		}
+		if !validTarget(target) {
+			addError("columns", fmt.Sprintf("column '%s' has an invalid target '%s'", name, target))
+			continue
+		}
+		if other, ok := targets[target]; ok {
+			addError("columns", fmt.Sprintf("columns '%s' and '%s' are mapped to '%s'", other, name, target))
+			continue
+		}
+		targets[target] = name
+	}
+
+	for _, target := range []string{TargetName, TargetType} {
+		if _, ok := targets[target]; !ok {
+			addError("columns", fmt.Sprintf("a column must be mapped to '%s'", target))
+		}
+	}
+
+	// An attribute cannot be both a value and an object of other attributes
+	for target, name := range targets {
+		for other, otherName := range targets {
+			if strings.HasPrefix(other, target+".") {
+				addError("columns", fmt.Sprintf("columns '%s' and '%s' are mapped to overlapping attributes", name, otherName))
+			}
+		}
+	}
+
+	if m.ExternalID != nil {
+		if m.ExternalID.Source == "" || len(m.ExternalID.Source) > 100 {
+			addError("external_id.source", "source must be between 1 and 100 characters long")
+		}
+		if _, ok := columns[m.ExternalID.Column]; !ok {
+			addError("external_id.column", fmt.Sprintf("column '%s' is not in the file", m.ExternalID.Column))
+		}
+	}
+
+	for field := range details {
+		sort.Strings(details[field])
+	}
+	return details
+}
+
+// validTarget reports whether a column can be mapped to target
+func validTarget(target string) bool {
+	switch target {
+	case TargetName, TargetType, TargetTags:
+		return true
+	}
+	if !strings.HasPrefix(target, AttributePrefix) {
+		return false
+	}
+	for _, key := range strings.Split(strings.TrimPrefix(target, AttributePrefix), ".") {
+		if key == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// cell returns the trimmed value of a record's column, or "" if the record is too short
+func cell(record []string, column int) string {
+	if column >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[column])
+}
+
+// splitTags splits a cell into its non-empty tags
+func splitTags(value, separator string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(value, separator) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// setAttribute sets the attribute at path, creating the objects on the way
+func setAttribute(attributes map[string]interface{}, path []string, value string) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := attributes[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			attributes[key] = child
+		}
+		attributes = child
+	}
+	attributes[path[len(path)-1]] = value
+}
+
+// Coerce converts the attribute values read from a file, which are all strings, to the
+// integer, number or boolean type the CI type's JSON Schema declares for them. Values that
+// do not parse are left as strings for schema validation to reject.
+func Coerce(attributes map[string]interface{}, schema map[string]interface{}) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	for key, value := range attributes {
+		property, _ := properties[key].(map[string]interface{})
+		switch value := value.(type) {
+		case map[string]interface{}:
+			Coerce(value, property)
+		case string:
+			attributes[key] = coerceValue(value, property)
+		}
+	}
+}
+
+// coerceValue converts a value to the first type of the schema it parses as
+func coerceValue(value string, schema map[string]interface{}) interface{} {
+	var types []interface{}
+	switch schemaType := schema["type"].(type) {
+	case string:
+		types = []interface{}{schemaType}
+	case []interface{}:
+		types = schemaType
+	}
+
+	for _, schemaType := range types {
+		switch schemaType {
+		case "string":
+			return value
+		case "integer":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return n
+			}
+		case "number":
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				return f
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(value); err == nil {
+				return b
+			}
+		}
+	}
+	return value
+}
@@ -0,0 +1,195 @@
+package csvimport
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRead(t *testing.T) {
+	mapping := Mapping{
+		Columns: map[string]string{
+			"Hostname": TargetName,
+			"Kind":     TargetType,
+			"Labels":   TargetTags,
+			"CPU":      "attributes.cpu",
+			"OS":       "attributes.os.name",
+		},
+		ExternalID: &ExternalIDMapping{Source: "hostname", Column: "Hostname"},
+	}
+	file := "Hostname,Kind,Labels,CPU,OS,Owner\n" +
+		"web-01,server,prod; web ;,4,Ubuntu,ops\n" +
+		"\"db-\n01\",database,,,,\n" +
+		" web-02 ,server\n"
+
+	rows, err := Read(strings.NewReader(file), mapping)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "web-01", rows[0].ExternalID)
+	assert.Equal(t, &models.CI{
+		Name:       "web-01",
+		Type:       "server",
+		Tags:       []string{"prod", "web"},
+		Attributes: models.JSONBMap{"cpu": "4", "os": map[string]interface{}{"name": "Ubuntu"}},
+	}, rows[0].CI)
+
+	// A quoted cell may span lines, and empty cells set no attributes
+	assert.Equal(t, 3, rows[1].Line)
+	assert.Equal(t, "db-\n01", rows[1].CI.Name)
+	assert.Equal(t, []string{}, rows[1].CI.Tags)
+	assert.Equal(t, models.JSONBMap{}, rows[1].CI.Attributes)
+
+	// Missing cells are empty and cells are trimmed
+	assert.Equal(t, 5, rows[2].Line)
+	assert.Equal(t, "web-02", rows[2].ExternalID)
+	assert.Equal(t, "server", rows[2].CI.Type)
+	assert.Equal(t, models.JSONBMap{}, rows[2].CI.Attributes)
+}
+
+func TestRead_TagSeparator(t *testing.T) {
+	mapping := Mapping{
+		Columns:      map[string]string{"name": TargetName, "type": TargetType, "tags": TargetTags},
+		TagSeparator: "|",
+	}
+
+	rows, err := Read(strings.NewReader("name,type,tags\nweb-01,server,prod|web;eu\n"), mapping)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, []string{"prod", "web;eu"}, rows[0].CI.Tags)
+	assert.Empty(t, rows[0].ExternalID)
+}
+
+func TestRead_InvalidMapping(t *testing.T) {
+	header := "name,type,cpu,os\n"
+
+	tests := []struct {
+		name     string
+		file     string
+		mapping  Mapping
+		expected map[string][]string
+	}{
+		{
+			name:     "Empty file",
+			file:     "",
+			mapping:  Mapping{Columns: map[string]string{"name": TargetName, "type": TargetType}},
+			expected: map[string][]string{"file": {"the file has no header"}},
+		},
+		{
+			name:    "No columns",
+			file:    header,
+			mapping: Mapping{},
+			expected: map[string][]string{"columns": {
+				"a column must be mapped to 'name'",
+				"a column must be mapped to 'type'",
+				"at least one column must be mapped",
+			}},
+		},
+		{
+			name:     "Unknown column",
+			file:     header,
+			mapping:  Mapping{Columns: map[string]string{"name": TargetName, "type": TargetType, "ram": "attributes.ram"}},
+			expected: map[string][]string{"columns": {"column 'ram' is not in the file"}},
+		},
+		{
+			name:    "Invalid targets",
+			file:    header,
+			mapping: Mapping{Columns: map[string]string{"name": TargetName, "type": TargetType, "cpu": "cpu", "os": "attributes.os."}},
+			expected: map[string][]string{"columns": {
+				"column 'cpu' has an invalid target 'cpu'",
+				"column 'os' has an invalid target 'attributes.os.'",
+			}},
+		},
+		{
+			name:     "Duplicate target",
+			file:     header,
+			mapping:  Mapping{Columns: map[string]string{"name": TargetName, "os": TargetName, "type": TargetType}},
+			expected: map[string][]string{"columns": {"columns 'name' and 'os' are mapped to 'name'"}},
+		},
+		{
+			name:     "Overlapping attributes",
+			file:     header,
+			mapping:  Mapping{Columns: map[string]string{"name": TargetName, "type": TargetType, "cpu": "attributes.hw", "os": "attributes.hw.os"}},
+			expected: map[string][]string{"columns": {"columns 'cpu' and 'os' are mapped to overlapping attributes"}},
+		},
+		{
+			name: "Invalid external ID",
+			file: header,
+			mapping: Mapping{
+				Columns:    map[string]string{"name": TargetName, "type": TargetType},
+				ExternalID: &ExternalIDMapping{Column: "serial"},
+			},
+			expected: map[string][]string{
+				"external_id.source": {"source must be between 1 and 100 characters long"},
+				"external_id.column": {"column 'serial' is not in the file"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := Read(strings.NewReader(tt.file), tt.mapping)
+			assert.Nil(t, rows)
+
+			var mappingError *MappingError
+			require.ErrorAs(t, err, &mappingError)
+			assert.Equal(t, tt.expected, mappingError.Details)
+		})
+	}
+}
+
+func TestRead_InvalidCSV(t *testing.T) {
+	mapping := Mapping{Columns: map[string]string{"name": TargetName, "type": TargetType}}
+
+	_, err := Read(strings.NewReader("name,type\n\"web-01,server\n"), mapping)
+	require.Error(t, err)
+
+	var mappingError *MappingError
+	assert.False(t, errors.As(err, &mappingError))
+}
+
+func TestCoerce(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"cpu":        map[string]interface{}{"type": "integer"},
+			"load":       map[string]interface{}{"type": "number"},
+			"monitored":  map[string]interface{}{"type": "boolean"},
+			"serial":     map[string]interface{}{"type": "string"},
+			"port":       map[string]interface{}{"type": []interface{}{"null", "integer", "string"}},
+			"os":         map[string]interface{}{"properties": map[string]interface{}{"version": map[string]interface{}{"type": "number"}}},
+			"memory":     map[string]interface{}{"type": "integer"},
+			"datacenter": map[string]interface{}{},
+		},
+	}
+	attributes := map[string]interface{}{
+		"cpu":        "4",
+		"load":       "0.75",
+		"monitored":  "true",
+		"serial":     "0042",
+		"port":       "8080",
+		"os":         map[string]interface{}{"version": "22.04"},
+		"datacenter": "eu-1",
+		"ram":        "16",
+		"memory":     "lots",
+	}
+
+	Coerce(attributes, schema)
+
+	assert.Equal(t, map[string]interface{}{
+		"cpu":        int64(4),
+		"load":       0.75,
+		"monitored":  true,
+		"serial":     "0042",
+		"port":       int64(8080),
+		"os":         map[string]interface{}{"version": 22.04},
+		"datacenter": "eu-1",
+		"ram":        "16",
+		"memory":     "lots",
+	}, attributes)
+}
@@ -0,0 +1,223 @@
+// Package events fans the change feed out to streaming clients. Every backend replica listens
+// for the notifications PostgreSQL sends as changes commit, reads the changed events and hands
+// each to the subscriptions whose filter it matches.
+package events
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+)
+
+// Channel is the PostgreSQL notification channel of the change feed. The audit log trigger
+// sends each event's sequence number on it.
+const Channel = "cmdb_events"
+
+// subscriptionBuffer is the number of events a subscription holds for its client. A client
+// that falls further behind is dropped and resumes from its last event.
+const subscriptionBuffer = 256
+
+// catchUpBatchSize is the number of events read at once when catching up
+const catchUpBatchSize = 500
+
+// Entity types of the change feed
+const (
+	EntityTypeCI           = "configuration_item"
+	EntityTypeRelationship = "relationship"
+	EntityTypeUser         = "user"
+)
+
+// EntityTypes are the types of entities whose changes are in the change feed
+var EntityTypes = []string{EntityTypeCI, EntityTypeRelationship, EntityTypeUser}
+
+// Filter selects the events of a subscription. Empty lists match every event; CITypes and
+// Tags only restrict CI events, matching CIs of any of the types that have any of the tags.
+type Filter struct {
+	EntityTypes []string
+	CITypes     []string
+	Tags        []string
+}
+
+// Matches reports whether an event passes the filter
+func (f Filter) Matches(event *models.Event) bool {
+	if len(f.EntityTypes) > 0 && !contains(f.EntityTypes, event.EntityType) {
+		return false
+	}
+	if event.EntityType != EntityTypeCI {
+		return true
+	}
+
+	if len(f.CITypes) > 0 {
+		ciType, _ := event.Details["type"].(string)
+		matched := false
+		for _, t := range f.CITypes {
+			if strings.EqualFold(t, ciType) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.Tags) > 0 {
+		for _, tag := range f.Tags {
+			if contains(event.Tags, tag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// Subscription receives the events of the change feed that match its filter
+type Subscription struct {
+	filter Filter
+	events chan *models.Event
+}
+
+// Events returns the subscription's events. The channel is closed when the subscription is
+// dropped for falling behind or the hub is closed.
+func (s *Subscription) Events() <-chan *models.Event {
+	return s.events
+}
+
+// Hub hands the events of the change feed to subscriptions
+type Hub struct {
+	repo repositories.EventRepository
+
+	mu            sync.Mutex
+	subscriptions map[*Subscription]struct{}
+	lastSeq       int64
+	closed        bool
+}
+
+// NewHub creates a new Hub reading events from the repository
+func NewHub(repo repositories.EventRepository) *Hub {
+	return &Hub{
+		repo:          repo,
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe creates a subscription to the events published from now on that match the
+// filter. Subscribing to a closed hub returns a subscription whose channel is closed.
+func (h *Hub) Subscribe(filter Filter) *Subscription {
+	subscription := &Subscription{
+		filter: filter,
+		events: make(chan *models.Event, subscriptionBuffer),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(subscription.events)
+		return subscription
+	}
+	h.subscriptions[subscription] = struct{}{}
+	return subscription
+}
+
+// Unsubscribe ends a subscription. It is safe to call more than once.
+func (h *Hub) Unsubscribe(subscription *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscriptions[subscription]; ok {
+		delete(h.subscriptions, subscription)
+		close(subscription.events)
+	}
+}
+
+// Publish reads the events with the sequence numbers and hands them to the subscriptions
+func (h *Hub) Publish(ctx context.Context, seqs []int64) error {
+	events, err := h.repo.GetBySeqs(ctx, seqs)
+	if err != nil {
+		return err
+	}
+
+	h.broadcast(events)
+	return nil
+}
+
+// CatchUp publishes the events recorded after the latest one the hub has seen, e.g. those
+// whose notifications were lost while the hub's connection was down
+func (h *Hub) CatchUp(ctx context.Context) error {
+	for {
+		h.mu.Lock()
+		lastSeq := h.lastSeq
+		h.mu.Unlock()
+
+		events, err := h.repo.ListAfter(ctx, lastSeq, catchUpBatchSize)
+		if err != nil {
+			return err
+		}
+
+		h.broadcast(events)
+		if len(events) < catchUpBatchSize {
+			return nil
+		}
+	}
+}
+
+// Close drops all subscriptions and refuses new ones
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for subscription := range h.subscriptions {
+		delete(h.subscriptions, subscription)
+		close(subscription.events)
+	}
+}
+
+// broadcast hands events to the subscriptions whose filter they match. A subscription with a
+// full buffer is dropped rather than holding up the others.
+func (h *Hub) broadcast(events []*models.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, event := range events {
+		if event.Seq > h.lastSeq {
+			h.lastSeq = event.Seq
+		}
+
+		for subscription := range h.subscriptions {
+			if !subscription.filter.Matches(event) {
+				continue
+			}
+			select {
+			case subscription.events <- event:
+			default:
+				delete(h.subscriptions, subscription)
+				close(subscription.events)
+			}
+		}
+	}
+}
+
+// setLastSeq records the latest event the hub has seen
+func (h *Hub) setLastSeq(seq int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastSeq = seq
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEventRepository serves the configured events by sequence number
+type fakeEventRepository struct {
+	repositories.EventRepository
+	events []*models.Event
+	err    error
+}
+
+func (r *fakeEventRepository) GetBySeqs(ctx context.Context, seqs []int64) ([]*models.Event, error) {
+	var events []*models.Event
+	for _, event := range r.events {
+		for _, seq := range seqs {
+			if event.Seq == seq {
+				events = append(events, event)
+			}
+		}
+	}
+	return events, r.err
+}
+
+func (r *fakeEventRepository) ListAfter(ctx context.Context, afterSeq int64, limit int) ([]*models.Event, error) {
+	var events []*models.Event
+	for _, event := range r.events {
+		if event.Seq > afterSeq && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, r.err
+}
+
+// newCIEvent returns an event of a change to a CI of the type with the tags
+func newCIEvent(seq int64, ciType string, tags ...string) *models.Event {
+	return &models.Event{
+		Seq:        seq,
+		ID:         uuid.New(),
+		EntityType: EntityTypeCI,
+		EntityID:   uuid.New(),
+		Action:     "update",
+		Details:    models.JSONBMap{"name": "web-01", "type": ciType},
+		Tags:       pq.StringArray(tags),
+	}
+}
+
+// newRelationshipEvent returns an event of a change to a relationship
+func newRelationshipEvent(seq int64) *models.Event {
+	return &models.Event{
+		Seq:        seq,
+		ID:         uuid.New(),
+		EntityType: EntityTypeRelationship,
+		EntityID:   uuid.New(),
+		Action:     "create",
+		Details:    models.JSONBMap{"type": "depends_on"},
+	}
+}
+
+// received drains the events waiting in a subscription
+func received(subscription *Subscription) []int64 {
+	var seqs []int64
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return seqs
+			}
+			seqs = append(seqs, event.Seq)
+		default:
+			return seqs
+		}
+	}
+}
+
+func TestFilter_Matches(t *testing.T) {
+	server := newCIEvent(1, "server", "prod", "web")
+	database := newCIEvent(2, "database", "staging")
+	relationship := newRelationshipEvent(3)
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		event    *models.Event
+		expected bool
+	}{
+		{"Empty filter matches CIs", Filter{}, server, true},
+		{"Empty filter matches relationships", Filter{}, relationship, true},
+		{"Entity type matches", Filter{EntityTypes: []string{EntityTypeCI}}, server, true},
+		{"Entity type excludes", Filter{EntityTypes: []string{EntityTypeCI}}, relationship, false},
+		{"CI type matches ignoring case", Filter{CITypes: []string{"Server"}}, server, true},
+		{"CI type excludes", Filter{CITypes: []string{"server"}}, database, false},
+		{"CI type leaves relationships alone", Filter{CITypes: []string{"server"}}, relationship, true},
+		{"Any tag matches", Filter{Tags: []string{"staging", "web"}}, server, true},
+		{"Tag excludes", Filter{Tags: []string{"prod"}}, database, false},
+		{"Tag leaves relationships alone", Filter{Tags: []string{"prod"}}, relationship, true},
+		{"CI type and tag must both match", Filter{CITypes: []string{"database"}, Tags: []string{"prod"}}, database, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.filter.Matches(tt.event))
+		})
+	}
+}
+
+func TestHub_Publish(t *testing.T) {
+	repo := &fakeEventRepository{events: []*models.Event{
+		newCIEvent(1, "server"),
+		newRelationshipEvent(2),
+		newCIEvent(3, "database"),
+	}}
+	hub := NewHub(repo)
+
+	all := hub.Subscribe(Filter{})
+	servers := hub.Subscribe(Filter{EntityTypes: []string{EntityTypeCI}, CITypes: []string{"server"}})
+
+	require.NoError(t, hub.Publish(context.Background(), []int64{1, 2, 3}))
+
+	assert.Equal(t, []int64{1, 2, 3}, received(all))
+	assert.Equal(t, []int64{1}, received(servers))
+
+	// An ended subscription gets nothing more
+	hub.Unsubscribe(servers)
+	hub.Unsubscribe(servers)
+	require.NoError(t, hub.Publish(context.Background(), []int64{1}))
+	assert.Equal(t, []int64{1}, received(all))
+	_, open := <-servers.Events()
+	assert.False(t, open)
+}
+
+func TestHub_PublishError(t *testing.T) {
+	repoErr := errors.New("connection refused")
+	hub := NewHub(&fakeEventRepository{err: repoErr})
+
+	assert.ErrorIs(t, hub.Publish(context.Background(), []int64{1}), repoErr)
+}
+
+func TestHub_DropsSlowSubscriptions(t *testing.T) {
+	repo := &fakeEventRepository{}
+	seqs := make([]int64, 0, subscriptionBuffer+1)
+	for seq := int64(1); seq <= subscriptionBuffer+1; seq++ {
+		repo.events = append(repo.events, newCIEvent(seq, "server"))
+		seqs = append(seqs, seq)
+	}
+	hub := NewHub(repo)
+
+	slow := hub.Subscribe(Filter{})
+	require.NoError(t, hub.Publish(context.Background(), seqs))
+
+	// The subscription keeps the events it buffered and is then closed
+	assert.Len(t, received(slow), subscriptionBuffer)
+	_, open := <-slow.Events()
+	assert.False(t, open)
+}
+
+func TestHub_CatchUp(t *testing.T) {
+	repo := &fakeEventRepository{}
+	for seq := int64(1); seq <= catchUpBatchSize+10; seq++ {
+		repo.events = append(repo.events, newCIEvent(seq, "server"))
+	}
+	hub := NewHub(repo)
+	hub.setLastSeq(catchUpBatchSize - 5)
+
+	subscription := hub.Subscribe(Filter{})
+	require.NoError(t, hub.CatchUp(context.Background()))
+
+	// Only the events after the latest one seen are published
+	seqs := received(subscription)
+	require.Len(t, seqs, 15)
+	assert.Equal(t, int64(catchUpBatchSize-4), seqs[0])
+	assert.Equal(t, int64(catchUpBatchSize+10), seqs[14])
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub(&fakeEventRepository{})
+	subscription := hub.Subscribe(Filter{})
+
+	hub.Close()
+
+	_, open := <-subscription.Events()
+	assert.False(t, open)
+
+	// Subscriptions to a closed hub are closed right away
+	_, open = <-hub.Subscribe(Filter{}).Events()
+	assert.False(t, open)
+	hub.Unsubscribe(subscription)
+}
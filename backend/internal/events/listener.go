@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Reconnection and health checks of the listener's connection
+const (
+	minReconnectInterval = 10 * time.Second
+	maxReconnectInterval = time.Minute
+	pingInterval         = 90 * time.Second
+)
+
+// maxNotificationBatch is the number of notifications read before their events are published
+const maxNotificationBatch = 500
+
+// Listen listens for the change feed's notifications on a dedicated connection to the
+// database and publishes their events until the context is cancelled, then closes the hub.
+// Events recorded while the connection was down are published once it is back.
+func (h *Hub) Listen(ctx context.Context, databaseURL string) {
+	defer h.Close()
+
+	listener := pq.NewListener(databaseURL, minReconnectInterval, maxReconnectInterval,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Printf("Change feed listener: %v", err)
+			}
+		})
+	defer listener.Close()
+
+	if err := listener.Listen(Channel); err != nil {
+		log.Printf("Failed to listen for changes: %v", err)
+		return
+	}
+
+	// Start from the latest event; older ones are replayed per client
+	lastSeq, err := h.repo.LatestSeq(ctx)
+	if err != nil {
+		log.Printf("Failed to get latest event: %v", err)
+	}
+	h.setLastSeq(lastSeq)
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			if err := listener.Ping(); err != nil {
+				log.Printf("Change feed listener: %v", err)
+			}
+
+		case notification := <-listener.Notify:
+			// A nil notification follows a reconnection, after which notifications may
+			// have been lost
+			if notification == nil {
+				if err := h.CatchUp(ctx); err != nil {
+					log.Printf("Failed to catch up on changes: %v", err)
+				}
+				continue
+			}
+
+			// Publish the notifications that are already waiting together
+			seqs := appendSeq(nil, notification)
+			reconnected := false
+		batch:
+			for len(seqs) < maxNotificationBatch {
+				select {
+				case notification := <-listener.Notify:
+					if notification == nil {
+						reconnected = true
+						break batch
+					}
+					seqs = appendSeq(seqs, notification)
+				default:
+					break batch
+				}
+			}
+
+			if err := h.Publish(ctx, seqs); err != nil {
+				log.Printf("Failed to publish changes: %v", err)
+			}
+			if reconnected {
+				if err := h.CatchUp(ctx); err != nil {
+					log.Printf("Failed to catch up on changes: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// appendSeq appends the sequence number a notification carries
+func appendSeq(seqs []int64, notification *pq.Notification) []int64 {
+	seq, err := strconv.ParseInt(notification.Extra, 10, 64)
+	if err != nil {
+		log.Printf("Ignoring invalid change notification %q", notification.Extra)
+		return seqs
+	}
+	return append(seqs, seq)
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestDatabase connects to the migrated database named by TEST_DATABASE_URL, skipping the
+// test when there is none
+func openTestDatabase(t *testing.T) *sqlx.DB {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" || testing.Short() {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sqlx.Connect("postgres", databaseURL)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// insertAuditLog records a change to a CI in a transaction and returns the audit log's ID
+func insertAuditLog(t *testing.T, tx *sqlx.Tx) uuid.UUID {
+	id := uuid.New()
+	_, err := tx.Exec(`
+		INSERT INTO audit_logs (id, entity_type, entity_id, action, changed_by, changed_at, details)
+		VALUES ($1, 'configuration_item', $2, 'update', 'order-test', $3, '{}')
+	`, id, uuid.New(), time.Now())
+	require.NoError(t, err)
+	return id
+}
+
+// eventIDs returns the IDs of the events among ids, in order
+func eventIDs(events []*models.Event, ids ...uuid.UUID) []uuid.UUID {
+	var result []uuid.UUID
+	for _, event := range events {
+		for _, id := range ids {
+			if event.ID == id {
+				result = append(result, id)
+			}
+		}
+	}
+	return result
+}
+
+func TestEventOrder_InterleavedTransactions(t *testing.T) {
+	db := openTestDatabase(t)
+	repo := repositories.NewEventPostgresRepository(db)
+	ctx := context.Background()
+
+	latestSeq, err := repo.LatestSeq(ctx)
+	require.NoError(t, err)
+
+	// The first transaction records its change before the second one but commits after it
+	first, err := db.Beginx()
+	require.NoError(t, err)
+	defer first.Rollback()
+	second, err := db.Beginx()
+	require.NoError(t, err)
+	defer second.Rollback()
+
+	firstID := insertAuditLog(t, first)
+	secondID := insertAuditLog(t, second)
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM audit_logs WHERE id = ANY($1::uuid[])`, pq.Array([]string{firstID.String(), secondID.String()}))
+	})
+
+	require.NoError(t, second.Commit())
+
+	// A client receives the second change and later resumes after it
+	events, err := repo.ListAfter(ctx, latestSeq, 1000)
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{secondID}, eventIDs(events, firstID, secondID))
+	var resumeSeq int64
+	for _, event := range events {
+		if event.ID == secondID {
+			resumeSeq = event.Seq
+		}
+	}
+
+	require.NoError(t, first.Commit())
+
+	// The change committed after the one the client received comes after it in the feed
+	events, err = repo.ListAfter(ctx, resumeSeq, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{firstID}, eventIDs(events, firstID, secondID))
+}
@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// WithAttributes wraps edges so that only relationships whose dotted attribute paths equal the
+// given values are returned. Values are compared as PostgreSQL's #>> operator renders them, so
+// the traversals here agree with the SQL filters: strings as is, anything else as JSON.
+func WithAttributes(edges EdgesFunc, attributes map[string]string) EdgesFunc {
+	if len(attributes) == 0 {
+		return edges
+	}
+
+	return func(ctx context.Context, ciID uuid.UUID) ([]*models.Relationship, error) {
+		relationships, err := edges(ctx, ciID)
+		if err != nil {
+			return nil, err
+		}
+
+		var matching []*models.Relationship
+		for _, rel := range relationships {
+			if matchesAttributes(rel.Attributes, attributes) {
+				matching = append(matching, rel)
+			}
+		}
+		return matching, nil
+	}
+}
+
+// matchesAttributes reports whether every attribute path holds the expected value
+func matchesAttributes(attributes models.JSONBMap, expected map[string]string) bool {
+	for path, want := range expected {
+		got, ok := attributeText(attributes, strings.Split(path, "."))
+		if !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// attributeText returns the value at the path rendered as text, or false if there is none
+func attributeText(attributes map[string]interface{}, path []string) (string, bool) {
+	value, ok := attributes[path[0]]
+	if !ok || value == nil {
+		return "", false
+	}
+	if len(path) > 1 {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		return attributeText(nested, path[1:])
+	}
+
+	if s, ok := value.(string); ok {
+		return s, true
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	return string(encoded), true
+}
@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAttributes(t *testing.T) {
+	// Setup test data: three links out of the app with different attributes
+	app, db, cache, queue := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	g := &testGraph{}
+	g.link(app, "connects_to", db).Attributes = models.JSONBMap{
+		"protocol": "tcp",
+		"port":     float64(5432),
+		"link":     map[string]interface{}{"encrypted": true},
+	}
+	g.link(app, "connects_to", cache).Attributes = models.JSONBMap{"protocol": "tcp", "port": float64(6379)}
+	g.link(app, "connects_to", queue)
+
+	tests := []struct {
+		name       string
+		attributes map[string]string
+		expected   []uuid.UUID
+	}{
+		{
+			name:     "No filter",
+			expected: []uuid.UUID{db, cache, queue},
+		},
+		{
+			name:       "String value",
+			attributes: map[string]string{"protocol": "tcp"},
+			expected:   []uuid.UUID{db, cache},
+		},
+		{
+			name:       "Number value",
+			attributes: map[string]string{"port": "6379"},
+			expected:   []uuid.UUID{cache},
+		},
+		{
+			name:       "Nested path",
+			attributes: map[string]string{"link.encrypted": "true"},
+			expected:   []uuid.UUID{db},
+		},
+		{
+			name:       "Every path must match",
+			attributes: map[string]string{"protocol": "tcp", "port": "5432"},
+			expected:   []uuid.UUID{db},
+		},
+		{
+			name:       "Missing attribute",
+			attributes: map[string]string{"bandwidth": "1G"},
+			expected:   []uuid.UUID{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relationships, err := WithAttributes(g.outgoing, tt.attributes)(context.Background(), app)
+
+			require.NoError(t, err)
+			targets := []uuid.UUID{}
+			for _, rel := range relationships {
+				targets = append(targets, rel.TargetID)
+			}
+			assert.ElementsMatch(t, tt.expected, targets)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package graph
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ClosesCycle reports whether a new relationship from source to target would close a directed
+// cycle, which is the case when source can already be reached from target. It returns the
+// cycle as the CIs visited from source through target back to source, or nil if there is none.
+// The search is breadth first, so the returned cycle is a shortest one.
+func ClosesCycle(ctx context.Context, source, target uuid.UUID, outgoing EdgesFunc) ([]uuid.UUID, error) {
+	if source == target {
+		return []uuid.UUID{source, source}, nil
+	}
+
+	parents := map[uuid.UUID]uuid.UUID{target: uuid.Nil}
+	frontier := []uuid.UUID{target}
+
+	for len(frontier) > 0 {
+		var next []uuid.UUID
+		for _, current := range frontier {
+			relationships, err := outgoing(ctx, current)
+			if err != nil {
+				return nil, err
+			}
+			for _, rel := range relationships {
+				if _, seen := parents[rel.TargetID]; seen {
+					continue
+				}
+				parents[rel.TargetID] = current
+
+				if rel.TargetID == source {
+					// Walk back to the target, then prepend the new relationship's source
+					var back []uuid.UUID
+					for ci := source; ci != uuid.Nil; ci = parents[ci] {
+						back = append(back, ci)
+					}
+					cycle := []uuid.UUID{source}
+					for i := len(back) - 1; i >= 0; i-- {
+						cycle = append(cycle, back[i])
+					}
+					return cycle, nil
+				}
+				next = append(next, rel.TargetID)
+			}
+		}
+		frontier = next
+	}
+
+	return nil, nil
+}
+
+// Cycle is a group of CIs that can each reach every other CI of the group through
+// relationships. Path is a shortest cycle through the first CI, as the CIs visited from it back
+// to itself, and Relationships are the relationships along that cycle.
+type Cycle struct {
+	CIs           []uuid.UUID `json:"cis"`
+	Path          []uuid.UUID `json:"path"`
+	Relationships []uuid.UUID `json:"relationships"`
+}
+
+// FindCycles finds the directed cycles formed by the relationships. Cycles that share CIs are
+// reported once, as the strongly connected group they form, so the result stays small however
+// many distinct cycles run through a group.
+func FindCycles(relationships []*models.Relationship) []*Cycle {
+	outgoing := make(map[uuid.UUID][]*models.Relationship)
+	var nodes []uuid.UUID
+	for _, rel := range relationships {
+		for _, id := range []uuid.UUID{rel.SourceID, rel.TargetID} {
+			if _, ok := outgoing[id]; !ok {
+				outgoing[id] = nil
+				nodes = append(nodes, id)
+			}
+		}
+		outgoing[rel.SourceID] = append(outgoing[rel.SourceID], rel)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return compareIDs(nodes[i], nodes[j]) < 0 })
+	for _, rels := range outgoing {
+		sort.Slice(rels, func(i, j int) bool {
+			if c := compareIDs(rels[i].TargetID, rels[j].TargetID); c != 0 {
+				return c < 0
+			}
+			return compareIDs(rels[i].ID, rels[j].ID) < 0
+		})
+	}
+
+	cycles := []*Cycle{}
+	for _, group := range stronglyConnected(nodes, outgoing) {
+		if cycle := shortestCycle(group, outgoing); cycle != nil {
+			cycles = append(cycles, cycle)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return compareIDs(cycles[i].CIs[0], cycles[j].CIs[0]) < 0 })
+	return cycles
+}
+
+// stronglyConnected splits the graph into its strongly connected components using Tarjan's
+// algorithm
+func stronglyConnected(nodes []uuid.UUID, outgoing map[uuid.UUID][]*models.Relationship) [][]uuid.UUID {
+	index := make(map[uuid.UUID]int)
+	lowLink := make(map[uuid.UUID]int)
+	onStack := make(map[uuid.UUID]bool)
+	var stack []uuid.UUID
+	var groups [][]uuid.UUID
+
+	var visit func(ci uuid.UUID)
+	visit = func(ci uuid.UUID) {
+		index[ci] = len(index)
+		lowLink[ci] = index[ci]
+		stack = append(stack, ci)
+		onStack[ci] = true
+
+		for _, rel := range outgoing[ci] {
+			next := rel.TargetID
+			if _, visited := index[next]; !visited {
+				visit(next)
+				if lowLink[next] < lowLink[ci] {
+					lowLink[ci] = lowLink[next]
+				}
+			} else if onStack[next] && index[next] < lowLink[ci] {
+				lowLink[ci] = index[next]
+			}
+		}
+
+		if lowLink[ci] == index[ci] {
+			var group []uuid.UUID
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				group = append(group, top)
+				if top == ci {
+					break
+				}
+			}
+			groups = append(groups, group)
+		}
+	}
+
+	for _, ci := range nodes {
+		if _, visited := index[ci]; !visited {
+			visit(ci)
+		}
+	}
+	return groups
+}
+
+// shortestCycle returns a shortest cycle through the first CI of a strongly connected group,
+// or nil if the group is a single CI without a relationship to itself
+func shortestCycle(group []uuid.UUID, outgoing map[uuid.UUID][]*models.Relationship) *Cycle {
+	members := make(map[uuid.UUID]bool, len(group))
+	for _, ci := range group {
+		members[ci] = true
+	}
+	sort.Slice(group, func(i, j int) bool { return compareIDs(group[i], group[j]) < 0 })
+	start := group[0]
+
+	// Breadth first from the start until a relationship leads back to it; parents holds the
+	// relationship each CI was reached by
+	parents := map[uuid.UUID]*models.Relationship{start: nil}
+	frontier := []uuid.UUID{start}
+	for len(frontier) > 0 {
+		var next []uuid.UUID
+		for _, current := range frontier {
+			for _, rel := range outgoing[current] {
+				if !members[rel.TargetID] {
+					continue
+				}
+				if rel.TargetID == start {
+					cycle := &Cycle{CIs: group}
+					chain := []*models.Relationship{rel}
+					for ci := current; ci != start; ci = parents[ci].SourceID {
+						chain = append(chain, parents[ci])
+					}
+					cycle.Path = []uuid.UUID{start}
+					for i := len(chain) - 1; i >= 0; i-- {
+						cycle.Path = append(cycle.Path, chain[i].TargetID)
+						cycle.Relationships = append(cycle.Relationships, chain[i].ID)
+					}
+					return cycle
+				}
+				if _, seen := parents[rel.TargetID]; seen {
+					continue
+				}
+				parents[rel.TargetID] = rel
+				next = append(next, rel.TargetID)
+			}
+		}
+		frontier = next
+	}
+	return nil
+}
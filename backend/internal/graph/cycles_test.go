@@ -0,0 +1,126 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClosesCycle(t *testing.T) {
+	// Setup test data: app -> service -> database, app -> cache
+	app, service, database, cache := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	g := &testGraph{}
+	g.link(app, "depends_on", service)
+	g.link(service, "depends_on", database)
+	g.link(app, "depends_on", cache)
+
+	tests := []struct {
+		name          string
+		source        uuid.UUID
+		target        uuid.UUID
+		expectedCycle []uuid.UUID
+	}{
+		{
+			name:          "Closing a long cycle",
+			source:        database,
+			target:        app,
+			expectedCycle: []uuid.UUID{database, app, service, database},
+		},
+		{
+			name:          "Closing a two-node cycle",
+			source:        service,
+			target:        app,
+			expectedCycle: []uuid.UUID{service, app, service},
+		},
+		{
+			name:          "Self reference",
+			source:        cache,
+			target:        cache,
+			expectedCycle: []uuid.UUID{cache, cache},
+		},
+		{
+			name:   "Parallel path is not a cycle",
+			source: cache,
+			target: database,
+		},
+		{
+			name:   "Shortcut is not a cycle",
+			source: app,
+			target: database,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cycle, err := ClosesCycle(context.Background(), tt.source, tt.target, g.outgoing)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCycle, cycle)
+		})
+	}
+}
+
+func TestFindCycles(t *testing.T) {
+	a, b, c, d, e := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name          string
+		links         [][2]uuid.UUID
+		expectedSizes []int
+	}{
+		{
+			name:          "No cycles",
+			links:         [][2]uuid.UUID{{a, b}, {b, c}, {a, c}},
+			expectedSizes: []int{},
+		},
+		{
+			name:          "Single cycle",
+			links:         [][2]uuid.UUID{{a, b}, {b, c}, {c, a}, {c, d}},
+			expectedSizes: []int{3},
+		},
+		{
+			name:          "Overlapping cycles form one group",
+			links:         [][2]uuid.UUID{{a, b}, {b, a}, {b, c}, {c, a}},
+			expectedSizes: []int{3},
+		},
+		{
+			name:          "Separate cycles and a self reference",
+			links:         [][2]uuid.UUID{{a, b}, {b, a}, {c, d}, {d, c}, {e, e}},
+			expectedSizes: []int{1, 2, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &testGraph{}
+			for _, link := range tt.links {
+				g.link(link[0], "depends_on", link[1])
+			}
+			byID := make(map[uuid.UUID]uuid.UUID)
+			for _, rel := range g.relationships {
+				byID[rel.ID] = rel.SourceID
+			}
+
+			cycles := FindCycles(g.relationships)
+
+			sizes := []int{}
+			for _, cycle := range cycles {
+				sizes = append(sizes, len(cycle.CIs))
+
+				// The path starts and ends at the first CI, stays within the group and
+				// follows the listed relationships
+				require.Len(t, cycle.Path, len(cycle.Relationships)+1)
+				assert.Equal(t, cycle.CIs[0], cycle.Path[0])
+				assert.Equal(t, cycle.CIs[0], cycle.Path[len(cycle.Path)-1])
+				for i, relID := range cycle.Relationships {
+					assert.Contains(t, cycle.CIs, cycle.Path[i])
+					assert.Equal(t, cycle.Path[i], byID[relID])
+				}
+			}
+			assert.ElementsMatch(t, tt.expectedSizes, sizes)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Package graph implements traversals over the CI relationship graph that are easier to
+// express as graph algorithms than as SQL: impact analysis, path finding and cycle detection.
+package graph
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// Impact analysis limits
+const (
+	MaxImpactDepth = 20
+	MaxImpactNodes = 1000
+)
+
+// DefaultDependencyTypes are the relationship types that carry a dependency from source to
+// target, e.g. "app depends_on db": when the target goes down the source is affected
+var DefaultDependencyTypes = []string{"depends_on", "runs_on"}
+
+// EdgesFunc returns the relationships on one side of a CI, e.g. those whose target is the
+// CI. RelationshipRepository.GetBySourceCI and GetByTargetCI satisfy it.
+type EdgesFunc func(ctx context.Context, ciID uuid.UUID) ([]*models.Relationship, error)
+
+// LevelEdgesFunc returns the relationships on one side of any of the CIs, so a breadth first
+// walk can fetch a whole level at once. RelationshipRepository.GetByTargetCIs satisfies it
+// once bound to the types and attributes to follow.
+type LevelEdgesFunc func(ctx context.Context, ciIDs []uuid.UUID) ([]*models.Relationship, error)
+
+// ImpactOptions configures an impact analysis. Zero limits mean the package maximums.
+type ImpactOptions struct {
+	DependencyTypes []string
+	MaxDepth        int
+	MaxNodes        int
+}
+
+// ImpactedCI is a CI affected by an outage of the root. Path lists the CIs from the root to
+// the affected CI and Relationships the relationships between them, so len(Path) is
+// Distance+1 and len(Relationships) is Distance.
+type ImpactedCI struct {
+	ID            uuid.UUID   `json:"id"`
+	Distance      int         `json:"distance"`
+	Path          []uuid.UUID `json:"path"`
+	Relationships []uuid.UUID `json:"relationships"`
+}
+
+// ImpactResult is the outcome of an impact analysis. Truncated is set when the analysis
+// stopped at MaxNodes.
+type ImpactResult struct {
+	Impacted  []*ImpactedCI
+	Truncated bool
+}
+
+// Impact finds every CI that transitively depends on the root by walking the dependency
+// relationships from target to source, breadth first. Each CI is reported once with one of
+// its shortest paths; the root itself is not included. incoming is called once per level, so
+// the number of calls is bounded by the depth.
+func Impact(ctx context.Context, rootID uuid.UUID, incoming LevelEdgesFunc, opts ImpactOptions) (*ImpactResult, error) {
+	maxDepth := opts.MaxDepth
+	if maxDepth <= 0 || maxDepth > MaxImpactDepth {
+		maxDepth = MaxImpactDepth
+	}
+	maxNodes := opts.MaxNodes
+	if maxNodes <= 0 || maxNodes > MaxImpactNodes {
+		maxNodes = MaxImpactNodes
+	}
+
+	dependencyTypes := make(map[string]bool, len(opts.DependencyTypes))
+	for _, t := range opts.DependencyTypes {
+		dependencyTypes[t] = true
+	}
+
+	result := &ImpactResult{Impacted: []*ImpactedCI{}}
+	root := &ImpactedCI{ID: rootID, Path: []uuid.UUID{rootID}, Relationships: []uuid.UUID{}}
+	visited := map[uuid.UUID]bool{rootID: true}
+	frontier := []*ImpactedCI{root}
+
+	for depth := 1; depth <= maxDepth && len(frontier) > 0; depth++ {
+		ids := make([]uuid.UUID, len(frontier))
+		for i, current := range frontier {
+			ids[i] = current.ID
+		}
+		relationships, err := incoming(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		byTarget := make(map[uuid.UUID][]*models.Relationship)
+		for _, rel := range relationships {
+			byTarget[rel.TargetID] = append(byTarget[rel.TargetID], rel)
+		}
+
+		var next []*ImpactedCI
+		for _, current := range frontier {
+			relationships := byTarget[current.ID]
+			sortRelationships(relationships)
+
+			for _, rel := range relationships {
+				if !dependencyTypes[rel.Type] || visited[rel.SourceID] {
+					continue
+				}
+				if len(result.Impacted) == maxNodes {
+					result.Truncated = true
+					return result, nil
+				}
+				visited[rel.SourceID] = true
+
+				impacted := &ImpactedCI{
+					ID:            rel.SourceID,
+					Distance:      depth,
+					Path:          append(append([]uuid.UUID{}, current.Path...), rel.SourceID),
+					Relationships: append(append([]uuid.UUID{}, current.Relationships...), rel.ID),
+				}
+				result.Impacted = append(result.Impacted, impacted)
+				next = append(next, impacted)
+			}
+		}
+		frontier = next
+	}
+
+	return result, nil
+}
+
+// sortRelationships orders relationships by source and ID so traversals are deterministic
+func sortRelationships(relationships []*models.Relationship) {
+	sort.Slice(relationships, func(i, j int) bool {
+		if c := compareIDs(relationships[i].SourceID, relationships[j].SourceID); c != 0 {
+			return c < 0
+		}
+		return compareIDs(relationships[i].ID, relationships[j].ID) < 0
+	})
+}
+
+// compareIDs orders UUIDs by their bytes
+func compareIDs(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
@@ -0,0 +1,160 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testGraph is an in-memory relationship graph for traversal tests
+type testGraph struct {
+	relationships []*models.Relationship
+}
+
+// link adds a relationship from source to target and returns it
+func (g *testGraph) link(source uuid.UUID, relType string, target uuid.UUID) *models.Relationship {
+	rel := &models.Relationship{ID: uuid.New(), SourceID: source, TargetID: target, Type: relType}
+	g.relationships = append(g.relationships, rel)
+	return rel
+}
+
+// incoming returns the relationships whose target is the CI
+func (g *testGraph) incoming(ctx context.Context, ciID uuid.UUID) ([]*models.Relationship, error) {
+	var result []*models.Relationship
+	for _, rel := range g.relationships {
+		if rel.TargetID == ciID {
+			result = append(result, rel)
+		}
+	}
+	return result, nil
+}
+
+// incomingLevel returns the relationships whose target is any of the CIs
+func (g *testGraph) incomingLevel(ctx context.Context, ciIDs []uuid.UUID) ([]*models.Relationship, error) {
+	var result []*models.Relationship
+	for _, ciID := range ciIDs {
+		relationships, _ := g.incoming(ctx, ciID)
+		result = append(result, relationships...)
+	}
+	return result, nil
+}
+
+func TestImpact(t *testing.T) {
+	// Setup test data: app and worker run on a server that depends on storage; the
+	// monitoring CI only connects to the app, which does not carry a dependency
+	storage, server, app, worker, frontend, monitoring := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	g := &testGraph{}
+	serverOnStorage := g.link(server, "depends_on", storage)
+	appOnServer := g.link(app, "runs_on", server)
+	g.link(worker, "runs_on", server)
+	frontendOnApp := g.link(frontend, "depends_on", app)
+	g.link(monitoring, "connects_to", app)
+	g.link(storage, "depends_on", frontend) // cycle back to the root
+
+	tests := []struct {
+		name              string
+		root              uuid.UUID
+		opts              ImpactOptions
+		expectedDistances map[uuid.UUID]int
+		expectedTruncated bool
+	}{
+		{
+			name: "Transitive dependents",
+			root: storage,
+			opts: ImpactOptions{DependencyTypes: DefaultDependencyTypes},
+			expectedDistances: map[uuid.UUID]int{
+				server: 1, app: 2, worker: 2, frontend: 3,
+			},
+		},
+		{
+			name: "Depth limit",
+			root: storage,
+			opts: ImpactOptions{DependencyTypes: DefaultDependencyTypes, MaxDepth: 1},
+			expectedDistances: map[uuid.UUID]int{
+				server: 1,
+			},
+		},
+		{
+			name:              "Node limit",
+			root:              storage,
+			opts:              ImpactOptions{DependencyTypes: DefaultDependencyTypes, MaxNodes: 2},
+			expectedDistances: map[uuid.UUID]int{server: 1, app: 2},
+			expectedTruncated: true,
+		},
+		{
+			name: "Only the given relationship types",
+			root: app,
+			opts: ImpactOptions{DependencyTypes: []string{"connects_to"}},
+			expectedDistances: map[uuid.UUID]int{
+				monitoring: 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Impact(context.Background(), tt.root, g.incomingLevel, tt.opts)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedTruncated, result.Truncated)
+
+			distances := make(map[uuid.UUID]int)
+			for _, impacted := range result.Impacted {
+				distances[impacted.ID] = impacted.Distance
+				assert.Len(t, impacted.Path, impacted.Distance+1)
+				assert.Len(t, impacted.Relationships, impacted.Distance)
+				assert.Equal(t, tt.root, impacted.Path[0])
+				assert.Equal(t, impacted.ID, impacted.Path[len(impacted.Path)-1])
+			}
+			if tt.expectedTruncated {
+				assert.Len(t, distances, len(tt.expectedDistances))
+				return
+			}
+			assert.Equal(t, tt.expectedDistances, distances)
+		})
+	}
+
+	t.Run("Path follows the relationships", func(t *testing.T) {
+		result, err := Impact(context.Background(), storage, g.incomingLevel, ImpactOptions{DependencyTypes: DefaultDependencyTypes})
+		require.NoError(t, err)
+
+		for _, impacted := range result.Impacted {
+			if impacted.ID == frontend {
+				assert.Equal(t, []uuid.UUID{storage, server, app, frontend}, impacted.Path)
+				assert.Equal(t, []uuid.UUID{serverOnStorage.ID, appOnServer.ID, frontendOnApp.ID}, impacted.Relationships)
+			}
+		}
+	})
+
+	t.Run("One call per level", func(t *testing.T) {
+		var calls [][]uuid.UUID
+		counting := func(ctx context.Context, ciIDs []uuid.UUID) ([]*models.Relationship, error) {
+			calls = append(calls, ciIDs)
+			return g.incomingLevel(ctx, ciIDs)
+		}
+
+		_, err := Impact(context.Background(), storage, counting, ImpactOptions{DependencyTypes: DefaultDependencyTypes})
+		require.NoError(t, err)
+
+		require.Len(t, calls, 4)
+		assert.Equal(t, []uuid.UUID{storage}, calls[0])
+		assert.Equal(t, []uuid.UUID{server}, calls[1])
+		assert.ElementsMatch(t, []uuid.UUID{app, worker}, calls[2])
+		assert.Equal(t, []uuid.UUID{frontend}, calls[3])
+	})
+
+	t.Run("Errors are returned", func(t *testing.T) {
+		failing := func(ctx context.Context, ciIDs []uuid.UUID) ([]*models.Relationship, error) {
+			return nil, errors.New("database unavailable")
+		}
+
+		_, err := Impact(context.Background(), storage, failing, ImpactOptions{DependencyTypes: DefaultDependencyTypes})
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,258 @@
+package graph
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// Directions in which relationships are followed
+const (
+	DirectionOut  = "out"
+	DirectionIn   = "in"
+	DirectionBoth = "both"
+)
+
+// Path search modes
+const (
+	ModeShortest = "shortest"
+	ModeAll      = "all"
+)
+
+// Path search limits
+const (
+	DefaultPathDepth = 6
+	MaxPathDepth     = 10
+	MaxPaths         = 100
+)
+
+// PathOptions configures a path search. Direction "out" follows relationships from source
+// to target, "in" from target to source and "both" ignores their direction. Zero limits mean
+// DefaultPathDepth and MaxPaths.
+type PathOptions struct {
+	Mode      string
+	Direction string
+	MaxDepth  int
+	MaxPaths  int
+}
+
+// Path is a chain of relationships between two CIs. CIs lists the CIs along the path, so
+// len(CIs) is len(Relationships)+1.
+type Path struct {
+	CIs           []uuid.UUID            `json:"cis"`
+	Relationships []*models.Relationship `json:"relationships"`
+}
+
+// PathResult is the outcome of a path search. Truncated is set when more paths exist than
+// MaxPaths.
+type PathResult struct {
+	Paths     []*Path
+	Truncated bool
+}
+
+// step is a relationship leading from a CI to a neighbour
+type step struct {
+	rel  *models.Relationship
+	next uuid.UUID
+}
+
+// neighbourhood loads and caches the steps out of each CI in the chosen direction
+type neighbourhood struct {
+	outgoing  EdgesFunc
+	incoming  EdgesFunc
+	direction string
+	cache     map[uuid.UUID][]step
+}
+
+// steps returns the relationships that can be followed from the CI, in a stable order
+func (n *neighbourhood) steps(ctx context.Context, ciID uuid.UUID) ([]step, error) {
+	if steps, ok := n.cache[ciID]; ok {
+		return steps, nil
+	}
+
+	var steps []step
+	if n.direction != DirectionIn {
+		relationships, err := n.outgoing(ctx, ciID)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range relationships {
+			steps = append(steps, step{rel: rel, next: rel.TargetID})
+		}
+	}
+	if n.direction != DirectionOut {
+		relationships, err := n.incoming(ctx, ciID)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range relationships {
+			steps = append(steps, step{rel: rel, next: rel.SourceID})
+		}
+	}
+
+	sort.Slice(steps, func(i, j int) bool {
+		if c := compareIDs(steps[i].next, steps[j].next); c != 0 {
+			return c < 0
+		}
+		return compareIDs(steps[i].rel.ID, steps[j].rel.ID) < 0
+	})
+	n.cache[ciID] = steps
+	return steps, nil
+}
+
+// Paths finds the relationship chains from one CI to another of at most MaxDepth
+// relationships. ModeShortest returns every shortest path; ModeAll returns every path that
+// visits no CI twice, shortest first.
+func Paths(ctx context.Context, from, to uuid.UUID, outgoing, incoming EdgesFunc, opts PathOptions) (*PathResult, error) {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultPathDepth
+	}
+	if opts.MaxPaths <= 0 {
+		opts.MaxPaths = MaxPaths
+	}
+
+	result := &PathResult{Paths: []*Path{}}
+	if from == to {
+		result.Paths = append(result.Paths, &Path{CIs: []uuid.UUID{from}, Relationships: []*models.Relationship{}})
+		return result, nil
+	}
+
+	n := &neighbourhood{
+		outgoing:  outgoing,
+		incoming:  incoming,
+		direction: opts.Direction,
+		cache:     make(map[uuid.UUID][]step),
+	}
+
+	var err error
+	if opts.Mode == ModeAll {
+		err = allPaths(ctx, n, from, to, opts, result)
+	} else {
+		err = shortestPaths(ctx, n, from, to, opts, result)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// shortestPaths runs a breadth-first search that records every parent at the shortest
+// distance, then enumerates the shortest paths back from the target
+func shortestPaths(ctx context.Context, n *neighbourhood, from, to uuid.UUID, opts PathOptions, result *PathResult) error {
+	distance := map[uuid.UUID]int{from: 0}
+	parents := make(map[uuid.UUID][]step)
+	frontier := []uuid.UUID{from}
+
+	for depth := 1; depth <= opts.MaxDepth && len(frontier) > 0; depth++ {
+		var next []uuid.UUID
+		for _, current := range frontier {
+			steps, err := n.steps(ctx, current)
+			if err != nil {
+				return err
+			}
+			for _, s := range steps {
+				if d, seen := distance[s.next]; seen {
+					if d == depth {
+						parents[s.next] = append(parents[s.next], step{rel: s.rel, next: current})
+					}
+					continue
+				}
+				distance[s.next] = depth
+				parents[s.next] = []step{{rel: s.rel, next: current}}
+				next = append(next, s.next)
+			}
+		}
+		if _, found := distance[to]; found {
+			break
+		}
+		frontier = next
+	}
+
+	if _, found := distance[to]; !found {
+		return nil
+	}
+
+	// Walk back from the target; each parent step points one hop closer to the source
+	var walk func(ci uuid.UUID, cis []uuid.UUID, relationships []*models.Relationship)
+	walk = func(ci uuid.UUID, cis []uuid.UUID, relationships []*models.Relationship) {
+		if ci == from {
+			result.Paths = append(result.Paths, reversePath(append(cis, ci), relationships))
+			return
+		}
+		for _, parent := range parents[ci] {
+			if len(result.Paths) == opts.MaxPaths {
+				result.Truncated = true
+				return
+			}
+			walk(parent.next, append(cis[:len(cis):len(cis)], ci), append(relationships[:len(relationships):len(relationships)], parent.rel))
+		}
+	}
+	walk(to, nil, nil)
+	return nil
+}
+
+// allPaths runs an iterative deepening depth-first search for every path that does not revisit
+// a CI, collecting the paths of each length in turn so a truncated result keeps the shortest
+func allPaths(ctx context.Context, n *neighbourhood, from, to uuid.UUID, opts PathOptions, result *PathResult) error {
+	onPath := map[uuid.UUID]bool{from: true}
+
+	var walk func(ci uuid.UUID, length int, cis []uuid.UUID, relationships []*models.Relationship) error
+	walk = func(ci uuid.UUID, length int, cis []uuid.UUID, relationships []*models.Relationship) error {
+		steps, err := n.steps(ctx, ci)
+		if err != nil {
+			return err
+		}
+		for _, s := range steps {
+			if result.Truncated {
+				return nil
+			}
+			if len(relationships)+1 == length {
+				if s.next != to {
+					continue
+				}
+				if len(result.Paths) == opts.MaxPaths {
+					result.Truncated = true
+					return nil
+				}
+				result.Paths = append(result.Paths, &Path{
+					CIs:           append(append([]uuid.UUID{}, cis...), to),
+					Relationships: append(append([]*models.Relationship{}, relationships...), s.rel),
+				})
+				continue
+			}
+			if s.next == to || onPath[s.next] {
+				continue
+			}
+			onPath[s.next] = true
+			if err := walk(s.next, length, append(cis, s.next), append(relationships, s.rel)); err != nil {
+				return err
+			}
+			onPath[s.next] = false
+		}
+		return nil
+	}
+
+	for length := 1; length <= opts.MaxDepth && !result.Truncated; length++ {
+		if err := walk(from, length, []uuid.UUID{from}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reversePath builds a path from CIs and relationships collected target first
+func reversePath(cis []uuid.UUID, relationships []*models.Relationship) *Path {
+	path := &Path{
+		CIs:           make([]uuid.UUID, len(cis)),
+		Relationships: make([]*models.Relationship, len(relationships)),
+	}
+	for i, ci := range cis {
+		path.CIs[len(cis)-1-i] = ci
+	}
+	for i, rel := range relationships {
+		path.Relationships[len(relationships)-1-i] = rel
+	}
+	return path
+}
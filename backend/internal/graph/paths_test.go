@@ -0,0 +1,132 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// outgoing returns the relationships whose source is the CI
+func (g *testGraph) outgoing(ctx context.Context, ciID uuid.UUID) ([]*models.Relationship, error) {
+	var result []*models.Relationship
+	for _, rel := range g.relationships {
+		if rel.SourceID == ciID {
+			result = append(result, rel)
+		}
+	}
+	return result, nil
+}
+
+func TestPaths(t *testing.T) {
+	// Setup test data:
+	//
+	//	service -> api -> database
+	//	service -> cache -> database
+	//	service -> queue -> worker -> database
+	//	backup -> database
+	service, api, cache, queue, worker, database, backup := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	g := &testGraph{}
+	g.link(service, "depends_on", api)
+	g.link(api, "connects_to", database)
+	g.link(service, "depends_on", cache)
+	g.link(cache, "connects_to", database)
+	g.link(service, "depends_on", queue)
+	g.link(queue, "connects_to", worker)
+	g.link(worker, "connects_to", database)
+	g.link(backup, "connects_to", database)
+
+	tests := []struct {
+		name              string
+		from              uuid.UUID
+		to                uuid.UUID
+		opts              PathOptions
+		expectedLengths   []int
+		expectedTruncated bool
+	}{
+		{
+			name:            "Every shortest path",
+			from:            service,
+			to:              database,
+			opts:            PathOptions{Mode: ModeShortest, Direction: DirectionOut},
+			expectedLengths: []int{2, 2},
+		},
+		{
+			name:            "All paths, shortest first",
+			from:            service,
+			to:              database,
+			opts:            PathOptions{Mode: ModeAll, Direction: DirectionOut},
+			expectedLengths: []int{2, 2, 3},
+		},
+		{
+			name:            "All paths within the depth limit",
+			from:            service,
+			to:              database,
+			opts:            PathOptions{Mode: ModeAll, Direction: DirectionOut, MaxDepth: 2},
+			expectedLengths: []int{2, 2},
+		},
+		{
+			name:            "Direction is followed",
+			from:            database,
+			to:              service,
+			opts:            PathOptions{Mode: ModeShortest, Direction: DirectionOut},
+			expectedLengths: []int{},
+		},
+		{
+			name:            "Reverse direction",
+			from:            database,
+			to:              service,
+			opts:            PathOptions{Mode: ModeShortest, Direction: DirectionIn},
+			expectedLengths: []int{2, 2},
+		},
+		{
+			name:            "Direction is ignored",
+			from:            backup,
+			to:              service,
+			opts:            PathOptions{Mode: ModeShortest, Direction: DirectionBoth},
+			expectedLengths: []int{3, 3},
+		},
+		{
+			name:              "Path limit",
+			from:              service,
+			to:                database,
+			opts:              PathOptions{Mode: ModeAll, Direction: DirectionOut, MaxPaths: 1},
+			expectedLengths:   []int{2},
+			expectedTruncated: true,
+		},
+		{
+			name:            "Same CI",
+			from:            service,
+			to:              service,
+			opts:            PathOptions{Mode: ModeShortest, Direction: DirectionBoth},
+			expectedLengths: []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Paths(context.Background(), tt.from, tt.to, g.outgoing, g.incoming, tt.opts)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedTruncated, result.Truncated)
+
+			lengths := []int{}
+			for _, path := range result.Paths {
+				lengths = append(lengths, len(path.Relationships))
+
+				// Every path starts and ends at the requested CIs and each relationship
+				// joins consecutive CIs
+				require.Len(t, path.CIs, len(path.Relationships)+1)
+				assert.Equal(t, tt.from, path.CIs[0])
+				assert.Equal(t, tt.to, path.CIs[len(path.CIs)-1])
+				for i, rel := range path.Relationships {
+					assert.ElementsMatch(t, []uuid.UUID{path.CIs[i], path.CIs[i+1]}, []uuid.UUID{rel.SourceID, rel.TargetID})
+				}
+			}
+			assert.Equal(t, tt.expectedLengths, lengths)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package graph_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestDatabase connects to the migrated database named by TEST_DATABASE_URL, skipping the
+// test when there is none
+func openTestDatabase(t *testing.T) *sqlx.DB {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" || testing.Short() {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sqlx.Connect("postgres", databaseURL)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestTraverse_DenseGraph(t *testing.T) {
+	db := openTestDatabase(t)
+	repo := repositories.NewRelationshipPostgresRepository(db)
+
+	// Every CI is related to every other one, so the number of paths between two CIs grows
+	// factorially with the depth
+	ids := make([]uuid.UUID, 60)
+	idStrings := make([]string, len(ids))
+	for i := range ids {
+		ids[i] = uuid.New()
+		idStrings[i] = ids[i].String()
+	}
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM configuration_items WHERE id = ANY($1::uuid[])`, pq.Array(idStrings))
+	})
+
+	_, err := db.Exec(`
+		INSERT INTO configuration_items (id, name, type, attributes, tags)
+		SELECT id, 'dense-' || id, 'server', '{}', '{}' FROM unnest($1::uuid[]) AS id
+	`, pq.Array(idStrings))
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO relationships (id, source_id, target_id, type, attributes)
+		SELECT uuid_generate_v4(), source.id, target.id, 'connects_to', '{}'
+		FROM unnest($1::uuid[]) AS source(id), unnest($1::uuid[]) AS target(id)
+		WHERE source.id < target.id
+	`, pq.Array(idStrings))
+	require.NoError(t, err)
+
+	// The walk enters each CI once, so it finishes long before the paths could be listed
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("Enters each CI once", func(t *testing.T) {
+		result, err := repo.Traverse(ctx, ids[0], repositories.GraphFilter{Depth: 10, Direction: repositories.GraphDirectionBoth, MaxNodes: 1000})
+		require.NoError(t, err)
+
+		require.Len(t, result.Nodes, len(ids))
+		assert.False(t, result.Truncated)
+		assert.Equal(t, ids[0], result.Nodes[0].ID)
+		assert.Equal(t, 0, result.Nodes[0].Depth)
+		seen := make(map[uuid.UUID]bool)
+		for _, node := range result.Nodes[1:] {
+			assert.False(t, seen[node.ID])
+			seen[node.ID] = true
+			assert.Equal(t, 1, node.Depth)
+		}
+	})
+
+	t.Run("Stops at the node limit", func(t *testing.T) {
+		result, err := repo.Traverse(ctx, ids[0], repositories.GraphFilter{Depth: 10, Direction: repositories.GraphDirectionBoth, MaxNodes: 10})
+		require.NoError(t, err)
+
+		assert.Len(t, result.Nodes, 10)
+		assert.True(t, result.Truncated)
+	})
+}
@@ -3,10 +3,10 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"github.com/cmdb-lite/backend/internal/middleware"
 	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/pagination"
 	"github.com/cmdb-lite/backend/internal/repositories"
 	"github.com/cmdb-lite/backend/internal/validation"
 	"github.com/google/uuid"
@@ -70,13 +70,16 @@ func (h *AuditLogHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
 
 // GetAllAuditLogs handles retrieving all audit logs with pagination
 // @Summary Get all audit logs
-// @Description Get all audit logs with pagination
+// @Description Get all audit logs with pagination. With Accept set to text/csv, application/x-ndjson or
+// @Description application/yaml, all matching audit logs are streamed in that format instead and pagination
+// @Description parameters are ignored.
 // @Tags audit-logs
 // @Accept json
-// @Produce json
+// @Produce json,text/csv,application/x-ndjson,application/yaml
 // @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Number of items per page" default(10)
+// @Param cursor query string false "Opaque next_cursor or prev_cursor from a previous page; overrides page"
 // @Param entity_type query string false "Filter by entity type"
 // @Param entity_id query string false "Filter by entity ID"
 // @Param changed_by query string false "Filter by user who made the change"
@@ -86,85 +89,22 @@ func (h *AuditLogHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
 // @Failure 500 {object} map[string]string
 // @Router /audit-logs [get]
 func (h *AuditLogHandler) GetAllAuditLogs(w http.ResponseWriter, r *http.Request) {
-	// Get pagination parameters from query string
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-	entityType := r.URL.Query().Get("entity_type")
-	entityIDStr := r.URL.Query().Get("entity_id")
-	changedBy := r.URL.Query().Get("changed_by")
-
-	// Set default values
-	page := 1
-	limit := 10
-
-	// Parse page parameter
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
+	// Get filter parameters from query string
+	filter := repositories.AuditLogFilter{
+		EntityType: r.URL.Query().Get("entity_type"),
+		ChangedBy:  r.URL.Query().Get("changed_by"),
 	}
 
-	// Parse limit parameter
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
-	}
-
-	// Get audit logs based on filters
-	var auditLogs []*models.AuditLog
-	var err error
-
-	if entityType != "" {
-		// Filter by entity type
-		auditLogs, err = h.auditRepo.GetByEntityType(r.Context(), entityType)
-	} else if entityIDStr != "" {
-		// Filter by entity ID
+	if entityIDStr := r.URL.Query().Get("entity_id"); entityIDStr != "" {
 		entityID, err := uuid.Parse(entityIDStr)
 		if err != nil {
 			middleware.RespondWithValidationError(w, "Invalid entity ID format", nil)
 			return
 		}
-		auditLogs, err = h.auditRepo.GetByEntityID(r.Context(), entityID)
-	} else if changedBy != "" {
-		// Filter by changed by
-		auditLogs, err = h.auditRepo.GetByChangedBy(r.Context(), changedBy)
-	} else {
-		// Get all audit logs
-		auditLogs, err = h.auditRepo.GetAll(r.Context())
-	}
-
-	if err != nil {
-		middleware.RespondWithInternalError(w, "Failed to get audit logs", nil)
-		return
+		filter.EntityID = entityID
 	}
 
-	// Calculate pagination
-	total := len(auditLogs)
-	start := (page - 1) * limit
-	end := start + limit
-
-	if start > total {
-		start = total
-	}
-	if end > total {
-		end = total
-	}
-
-	paginatedAuditLogs := auditLogs[start:end]
-
-	// Create response
-	response := map[string]interface{}{
-		"data":       paginatedAuditLogs,
-		"pagination": map[string]interface{}{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-		},
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	h.respondWithAuditLogPage(w, r, filter)
 }
 
 // GetAuditLogsByEntityType handles retrieving audit logs by entity type
@@ -177,6 +117,7 @@ func (h *AuditLogHandler) GetAllAuditLogs(w http.ResponseWriter, r *http.Request
 // @Param entity_type path string true "Entity type"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Number of items per page" default(10)
+// @Param cursor query string false "Opaque next_cursor or prev_cursor from a previous page; overrides page"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
@@ -192,61 +133,7 @@ func (h *AuditLogHandler) GetAuditLogsByEntityType(w http.ResponseWriter, r *htt
 		return
 	}
 
-	// Get pagination parameters from query string
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-
-	// Set default values
-	page := 1
-	limit := 10
-
-	// Parse page parameter
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
-	}
-
-	// Parse limit parameter
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
-	}
-
-	// Get audit logs by entity type
-	auditLogs, err := h.auditRepo.GetByEntityType(r.Context(), entityType)
-	if err != nil {
-		middleware.RespondWithInternalError(w, "Failed to get audit logs", nil)
-		return
-	}
-
-	// Calculate pagination
-	total := len(auditLogs)
-	start := (page - 1) * limit
-	end := start + limit
-
-	if start > total {
-		start = total
-	}
-	if end > total {
-		end = total
-	}
-
-	paginatedAuditLogs := auditLogs[start:end]
-
-	// Create response
-	response := map[string]interface{}{
-		"data":       paginatedAuditLogs,
-		"pagination": map[string]interface{}{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-		},
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	h.respondWithAuditLogPage(w, r, repositories.AuditLogFilter{EntityType: entityType})
 }
 
 // GetAuditLogsByEntityID handles retrieving audit logs by entity ID
@@ -259,6 +146,7 @@ func (h *AuditLogHandler) GetAuditLogsByEntityType(w http.ResponseWriter, r *htt
 // @Param entity_id path string true "Entity ID"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Number of items per page" default(10)
+// @Param cursor query string false "Opaque next_cursor or prev_cursor from a previous page; overrides page"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
@@ -280,61 +168,7 @@ func (h *AuditLogHandler) GetAuditLogsByEntityID(w http.ResponseWriter, r *http.
 		return
 	}
 
-	// Get pagination parameters from query string
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-
-	// Set default values
-	page := 1
-	limit := 10
-
-	// Parse page parameter
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
-	}
-
-	// Parse limit parameter
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
-	}
-
-	// Get audit logs by entity ID
-	auditLogs, err := h.auditRepo.GetByEntityID(r.Context(), entityID)
-	if err != nil {
-		middleware.RespondWithInternalError(w, "Failed to get audit logs", nil)
-		return
-	}
-
-	// Calculate pagination
-	total := len(auditLogs)
-	start := (page - 1) * limit
-	end := start + limit
-
-	if start > total {
-		start = total
-	}
-	if end > total {
-		end = total
-	}
-
-	paginatedAuditLogs := auditLogs[start:end]
-
-	// Create response
-	response := map[string]interface{}{
-		"data":       paginatedAuditLogs,
-		"pagination": map[string]interface{}{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-		},
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	h.respondWithAuditLogPage(w, r, repositories.AuditLogFilter{EntityID: entityID})
 }
 
 // GetAuditLogsByChangedBy handles retrieving audit logs by the user who made the change
@@ -347,6 +181,7 @@ func (h *AuditLogHandler) GetAuditLogsByEntityID(w http.ResponseWriter, r *http.
 // @Param changed_by path string true "Username"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Number of items per page" default(10)
+// @Param cursor query string false "Opaque next_cursor or prev_cursor from a previous page; overrides page"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
@@ -362,61 +197,7 @@ func (h *AuditLogHandler) GetAuditLogsByChangedBy(w http.ResponseWriter, r *http
 		return
 	}
 
-	// Get pagination parameters from query string
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-
-	// Set default values
-	page := 1
-	limit := 10
-
-	// Parse page parameter
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
-	}
-
-	// Parse limit parameter
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
-	}
-
-	// Get audit logs by changed by
-	auditLogs, err := h.auditRepo.GetByChangedBy(r.Context(), changedBy)
-	if err != nil {
-		middleware.RespondWithInternalError(w, "Failed to get audit logs", nil)
-		return
-	}
-
-	// Calculate pagination
-	total := len(auditLogs)
-	start := (page - 1) * limit
-	end := start + limit
-
-	if start > total {
-		start = total
-	}
-	if end > total {
-		end = total
-	}
-
-	paginatedAuditLogs := auditLogs[start:end]
-
-	// Create response
-	response := map[string]interface{}{
-		"data":       paginatedAuditLogs,
-		"pagination": map[string]interface{}{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-		},
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	h.respondWithAuditLogPage(w, r, repositories.AuditLogFilter{ChangedBy: changedBy})
 }
 
 // DeleteAuditLog handles deleting an audit log
@@ -469,4 +250,77 @@ func (h *AuditLogHandler) DeleteAuditLog(w http.ResponseWriter, r *http.Request)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Audit log deleted successfully"})
-}
\ No newline at end of file
+}
+
+// respondWithAuditLogPage writes one page of the audit logs matching the filter, using the
+// page, limit and cursor query parameters, or all of them if the client asks for an export
+// format
+func (h *AuditLogHandler) respondWithAuditLogPage(w http.ResponseWriter, r *http.Request, filter repositories.AuditLogFilter) {
+	if mediaType := exportFormat(r); mediaType != "" {
+		h.exportAuditLogs(w, r, filter, mediaType)
+		return
+	}
+
+	// Get pagination parameters from query string
+	pageReq, err := parsePageRequest(r.URL.Query())
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid cursor", nil)
+		return
+	}
+
+	// Count all matching audit logs
+	total, err := h.auditRepo.Count(r.Context(), filter)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get audit logs", nil)
+		return
+	}
+
+	// Get the requested page of audit logs
+	filter.Limit = pageReq.FetchLimit()
+	if pageReq.Cursor != nil {
+		filter.Cursor = pageReq.Cursor
+	} else {
+		filter.Offset = pageReq.Offset()
+	}
+	auditLogs, err := h.auditRepo.List(r.Context(), filter)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get audit logs", nil)
+		return
+	}
+
+	auditLogs, paginationInfo := paginate(auditLogs, pageReq, total, true, func(auditLog *models.AuditLog) pagination.Cursor {
+		return pagination.Cursor{Timestamp: auditLog.ChangedAt, ID: auditLog.ID}
+	})
+
+	// Create response
+	response := map[string]interface{}{
+		"data":       auditLogs,
+		"pagination": paginationInfo,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// auditLogExportFields are the audit log fields of a CSV export, before the detail columns
+var auditLogExportFields = []string{"id", "entity_type", "entity_id", "action", "changed_by", "changed_at"}
+
+// exportAuditLogs streams the audit logs matching the filter, ignoring pagination, in an
+// export format
+func (h *AuditLogHandler) exportAuditLogs(w http.ResponseWriter, r *http.Request, filter repositories.AuditLogFilter, mediaType string) {
+	table := exportTable{fields: auditLogExportFields, jsonField: "details"}
+	if mediaType == csvMediaType {
+		paths, err := h.auditRepo.DetailPaths(r.Context(), filter)
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to export audit logs", nil)
+			return
+		}
+		table.paths = paths
+	}
+
+	exporter := newExporter(w, mediaType, table)
+	err := h.auditRepo.Stream(r.Context(), filter, func(auditLog *models.AuditLog) error {
+		return exporter.write(auditLog)
+	})
+	exporter.finish(err, "Failed to export audit logs")
+}
@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/history"
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// maxBulkOperations is the largest number of operations in a bulk request, which keeps every
+// batch statement well below PostgreSQL's limit of 65535 parameters
+const maxBulkOperations = 1000
+
+// Bulk operation types
+const (
+	bulkOpUpsert = "upsert"
+	bulkOpDelete = "delete"
+)
+
+// errBulkRolledBack rolls back an atomic bulk request in which an operation failed
+var errBulkRolledBack = errors.New("bulk request rolled back")
+
+// bulkItem is a bulk operation that passed validation. id and version are those of the CI
+// the operation refers to; ci is the CI sent with an upsert.
+type bulkItem struct {
+	index   int
+	op      string
+	id      uuid.UUID
+	version int
+	ci      *models.CI
+}
+
+// bulkWrite is a CI written by a bulk request and its state before the write, which is nil
+// for a create
+type bulkWrite struct {
+	index  int
+	ci     *models.CI
+	before *models.CI
+}
+
+// BulkCIs handles creating, updating and deleting many CIs in one request
+// @Summary Bulk create, update and delete CIs
+// @Description Apply a list of upsert and delete operations with batched statements and return the outcome of each.
+// @Description An upsert updates the CI with its ID if there is one and creates it otherwise; a delete moves a CI to
+// @Description the trash. A version in an operation must equal the stored version. Failed operations do not stop the
+// @Description others unless atomic is true, in which case nothing is saved if any operation fails.
+// @Tags cis
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param operations body []models.BulkCIOperation true "Operations to apply"
+// @Param atomic query bool false "Save nothing if any operation fails" default(false)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cis/bulk [post]
+func (h *CIHandler) BulkCIs(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	atomic := false
+	if atomicStr := r.URL.Query().Get("atomic"); atomicStr != "" {
+		var err error
+		if atomic, err = strconv.ParseBool(atomicStr); err != nil {
+			middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+				"atomic": []string{"atomic must be true or false"},
+			})
+			return
+		}
+	}
+
+	var operations []models.BulkCIOperation
+	if err := json.NewDecoder(r.Body).Decode(&operations); err != nil {
+		middleware.RespondWithValidationError(w, "Invalid request body", nil)
+		return
+	}
+	if len(operations) == 0 || len(operations) > maxBulkOperations {
+		middleware.RespondWithValidationError(w, "Invalid request body", map[string]interface{}{
+			"operations": []string{fmt.Sprintf("between 1 and %d operations are required", maxBulkOperations)},
+		})
+		return
+	}
+
+	// Check every operation on its own before touching the database
+	results := make([]models.BulkCIResult, len(operations))
+	items := h.validateBulkOperations(r.Context(), operations, results)
+
+	// Apply the valid operations and write their audit logs in one transaction. An atomic
+	// request is rolled back as soon as one operation has failed.
+	err := errBulkRolledBack
+	if !atomic || len(items) == len(operations) {
+		err = h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+			failed, err := h.applyBulkOperations(r.Context(), tx, username, items, results)
+			if err == nil && atomic && failed {
+				return errBulkRolledBack
+			}
+			return err
+		})
+	}
+	if err != nil && !errors.Is(err, errBulkRolledBack) {
+		middleware.RespondWithInternalError(w, "Failed to apply bulk operations", nil)
+		return
+	}
+
+	committed := err == nil
+	succeeded := 0
+	for i := range results {
+		if results[i].Error != nil {
+			continue
+		}
+		if !committed {
+			results[i].ID = nil
+			results[i].Version = 0
+			bulkFailure(&results[i], models.ErrorTypeFailedDependency, "Rolled back because another operation failed", nil)
+			continue
+		}
+		succeeded++
+	}
+
+	// Create response
+	response := map[string]interface{}{
+		"atomic":    atomic,
+		"committed": committed,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// validateBulkOperations checks each operation on its own, records those that fail in results
+// and returns the others. CI types are looked up once per request.
+func (h *CIHandler) validateBulkOperations(ctx context.Context, operations []models.BulkCIOperation, results []models.BulkCIResult) []bulkItem {
+	ciTypes := make(map[string]*models.CIType)
+	seen := make(map[uuid.UUID]bool)
+	items := make([]bulkItem, 0, len(operations))
+
+	for i, operation := range operations {
+		results[i] = models.BulkCIResult{Index: i, Op: operation.Op}
+		item := bulkItem{index: i, op: operation.Op}
+
+		var validationError *models.ErrorResponse
+		switch operation.Op {
+		case bulkOpUpsert:
+			if operation.CI == nil {
+				validationError = bulkValidationError("ci", "ci is required for an upsert")
+				break
+			}
+			item.ci = operation.CI
+			item.id = operation.CI.ID
+			item.version = operation.CI.Version
+			if item.ci.Attributes == nil {
+				item.ci.Attributes = models.JSONBMap{}
+			}
+			if item.ci.Tags == nil {
+				item.ci.Tags = []string{}
+			}
+			validationError = h.validator.Validate(*item.ci)
+			if validationError == nil {
+				validationError = h.validateBulkCIType(ctx, item.ci, ciTypes)
+			}
+		case bulkOpDelete:
+			if operation.ID == uuid.Nil {
+				validationError = bulkValidationError("id", "id is required for a delete")
+				break
+			}
+			item.id = operation.ID
+			item.version = operation.Version
+		default:
+			validationError = bulkValidationError("op", "op must be one of: upsert delete")
+		}
+
+		if validationError == nil && item.id != uuid.Nil {
+			if seen[item.id] {
+				validationError = bulkValidationError("id", "the CI appears in more than one operation")
+			}
+			seen[item.id] = true
+		}
+
+		if validationError != nil {
+			results[i].Status = models.GetHTTPStatusForError(models.ErrorTypeValidation)
+			results[i].Error = validationError
+			continue
+		}
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// validateBulkCIType checks the CI against its CI type like validateCIType, caching the CI
+// types looked up
+func (h *CIHandler) validateBulkCIType(ctx context.Context, ci *models.CI, ciTypes map[string]*models.CIType) *models.ErrorResponse {
+	ciType := h.getCachedCIType(ctx, ci.Type, ciTypes)
+	if ciType == nil {
+		return unregisteredCITypeError(ci.Type)
+	}
+	return h.validator.ValidateAttributes(ciType.Schema, ci.Attributes)
+}
+
+// getCachedCIType retrieves a CI type by name through a cache of the CI types looked up. It
+// returns nil if the type is not registered.
+func (h *CIHandler) getCachedCIType(ctx context.Context, name string, ciTypes map[string]*models.CIType) *models.CIType {
+	ciType, ok := ciTypes[name]
+	if !ok {
+		var err error
+		if ciType, err = h.ciTypeRepo.GetByName(ctx, name); err != nil {
+			ciType = nil
+		}
+		ciTypes[name] = ciType
+	}
+	return ciType
+}
+
+// applyBulkOperations applies the valid operations with one batch statement per kind of write
+// and writes an audit log for each CI written. The outcome of each operation is recorded in
+// results; failed reports whether any operation failed.
+func (h *CIHandler) applyBulkOperations(ctx context.Context, tx repositories.Repos, username string, items []bulkItem, results []models.BulkCIResult) (bool, error) {
+	failed := false
+
+	// Load the existing CIs the operations refer to
+	ids := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		if item.id != uuid.Nil {
+			ids = append(ids, item.id)
+		}
+	}
+	existing := make(map[uuid.UUID]*models.CI, len(ids))
+	if len(ids) > 0 {
+		cis, err := tx.CIs.List(ctx, repositories.CIFilter{IDs: ids})
+		if err != nil {
+			return false, err
+		}
+		for _, ci := range cis {
+			existing[ci.ID] = ci
+		}
+	}
+
+	// Sort the operations into creates, updates and deletes
+	now := time.Now()
+	var creates, updates, deletes []*bulkWrite
+	for _, item := range items {
+		current := existing[item.id]
+		switch {
+		case item.op == bulkOpDelete && current == nil:
+			bulkFailure(&results[item.index], models.ErrorTypeNotFound, "CI not found", nil)
+			failed = true
+		case current != nil && item.version != 0 && item.version != current.Version:
+			bulkFailure(&results[item.index], models.ErrorTypePreconditionFailed, preconditionFailedMessage,
+				map[string]interface{}{"current_version": current.Version})
+			failed = true
+		case item.op == bulkOpDelete:
+			deletes = append(deletes, &bulkWrite{index: item.index, ci: current, before: current})
+		case current == nil:
+			ci := item.ci
+			if ci.ID == uuid.Nil {
+				ci.ID = uuid.New()
+			}
+			ci.CreatedAt = now
+			ci.UpdatedAt = now
+			creates = append(creates, &bulkWrite{index: item.index, ci: ci})
+		default:
+			before := *current
+			ci := *current
+			ci.Name = item.ci.Name
+			ci.Type = item.ci.Type
+			ci.Attributes = item.ci.Attributes
+			ci.Tags = item.ci.Tags
+			ci.UpdatedAt = now
+			updates = append(updates, &bulkWrite{index: item.index, ci: &ci, before: &before})
+		}
+	}
+
+	// Write the CIs. Those missing from a batch result were taken or changed concurrently.
+	created, err := tx.CIs.CreateBatch(ctx, bulkWriteCIs(creates))
+	if err != nil {
+		return false, err
+	}
+	updated, err := tx.CIs.UpdateBatch(ctx, bulkWriteCIs(updates))
+	if err != nil {
+		return false, err
+	}
+	deleted, err := tx.CIs.DeleteBatch(ctx, bulkWriteCIs(deletes))
+	if err != nil {
+		return false, err
+	}
+
+	// Record the outcome of each write and create its audit log
+	var auditLogs []*models.AuditLog
+	for _, batch := range []struct {
+		writes  []*bulkWrite
+		written []uuid.UUID
+		action  string
+	}{
+		{creates, created, history.ActionCreate},
+		{updates, updated, history.ActionUpdate},
+		{deletes, deleted, history.ActionDelete},
+	} {
+		written := make(map[uuid.UUID]bool, len(batch.written))
+		for _, id := range batch.written {
+			written[id] = true
+		}
+
+		for _, write := range batch.writes {
+			result := &results[write.index]
+			if !written[write.ci.ID] {
+				if batch.action == history.ActionCreate {
+					bulkFailure(result, models.ErrorTypeConflict, "CI ID is already in use", nil)
+				} else {
+					bulkFailure(result, models.ErrorTypePreconditionFailed, preconditionFailedMessage, nil)
+				}
+				failed = true
+				continue
+			}
+
+			after := write.ci
+			result.Status = http.StatusOK
+			switch batch.action {
+			case history.ActionCreate:
+				result.Status = http.StatusCreated
+			case history.ActionDelete:
+				after = nil
+			}
+			result.ID = &write.ci.ID
+			result.Version = write.ci.Version
+
+			auditLogs = append(auditLogs, &models.AuditLog{
+				ID:         uuid.New(),
+				EntityType: "configuration_item",
+				EntityID:   write.ci.ID,
+				Action:     batch.action,
+				ChangedBy:  username,
+				ChangedAt:  now,
+				Details:    ciAuditDetails(write.ci, history.Diff(write.before, after)),
+			})
+		}
+	}
+
+	// The relationships of the deleted CIs went to the trash with them
+	if len(deleted) > 0 {
+		trashed, err := tx.Relationships.ListDeletedWithCIs(ctx, deleted)
+		if err != nil {
+			return false, err
+		}
+		auditLogs = append(auditLogs, relationshipAuditLogs(trashed, history.ActionDelete, username, now)...)
+	}
+
+	if err := tx.AuditLogs.CreateBatch(ctx, auditLogs); err != nil {
+		return false, err
+	}
+
+	return failed, nil
+}
+
+// bulkWriteCIs returns the CIs of the writes
+func bulkWriteCIs(writes []*bulkWrite) []*models.CI {
+	cis := make([]*models.CI, len(writes))
+	for i, write := range writes {
+		cis[i] = write.ci
+	}
+	return cis
+}
+
+// bulkFailure records a failed operation in its result
+func bulkFailure(result *models.BulkCIResult, errorType models.ErrorType, message string, details interface{}) {
+	result.Status = models.GetHTTPStatusForError(errorType)
+	result.Error = models.NewErrorResponse(errorType, message, details)
+}
+
+// bulkValidationError returns the validation error for an invalid field of an operation
+func bulkValidationError(field, message string) *models.ErrorResponse {
+	return models.NewErrorResponse(models.ErrorTypeValidation, "Validation failed", map[string]interface{}{
+		field: []string{message},
+	})
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/cmdb-lite/backend/internal/history"
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// PutCIByExternalID handles creating or updating the CI identified by an external ID
+// @Summary Upsert a CI by external ID
+// @Description Create or update the configuration item identified by an external ID, such as a hostname or a cloud
+// @Description instance ID, so that importers need not know CI IDs. When no CI has the external ID, the CI with the
+// @Description id given in the body, if any, gets it; otherwise a new CI is created with it. A request that changes
+// @Description nothing writes nothing, so repeating it is idempotent. If-Match is optional; when set it must carry
+// @Description the ETag of the current version. An external ID that belongs to a CI in the trash is a conflict that
+// @Description names the CI; restore it to update it.
+// @Tags cis
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param source path string true "Source of the external ID, e.g. hostname or aws"
+// @Param external_id path string true "External ID"
+// @Param If-Match header string false "ETag of the CI version being updated"
+// @Param ci body models.CI true "CI object"
+// @Success 200 {object} models.CI
+// @Success 201 {object} models.CI
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cis/by-external-id/{source}/{external_id} [put]
+func (h *CIHandler) PutCIByExternalID(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	externalID, ok := h.parseExternalID(w, r)
+	if !ok {
+		return
+	}
+
+	// Decode the request body
+	var ci models.CI
+	if err := json.NewDecoder(r.Body).Decode(&ci); err != nil {
+		middleware.RespondWithValidationError(w, "Invalid request body", nil)
+		return
+	}
+	if ci.Attributes == nil {
+		ci.Attributes = models.JSONBMap{}
+	}
+	if ci.Tags == nil {
+		ci.Tags = []string{}
+	}
+
+	// Find the CI with the external ID, or else the CI the body names
+	existingCI, err := h.ciRepo.GetByExternalID(r.Context(), externalID.Source, externalID.ExternalID)
+	if err != nil && !errors.Is(err, repositories.ErrCINotFound) {
+		middleware.RespondWithInternalError(w, "Failed to get CI", nil)
+		return
+	}
+	linked := err == nil
+	if !linked {
+		// GetByExternalID leaves out the CIs in the trash, which keep their external IDs
+		owners, err := h.ciRepo.FindExternalIDs(r.Context(), externalID.Source, []string{externalID.ExternalID})
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to get external IDs", nil)
+			return
+		}
+		if len(owners) > 0 {
+			middleware.RespondWithConflictError(w, "External ID belongs to a CI in the trash", map[string]interface{}{
+				"ci_id": owners[0].CIID,
+			})
+			return
+		}
+	}
+	switch {
+	case linked && ci.ID != uuid.Nil && ci.ID != existingCI.ID:
+		middleware.RespondWithConflictError(w, "External ID belongs to another CI", map[string]interface{}{
+			"ci_id": existingCI.ID,
+		})
+		return
+	case !linked && ci.ID != uuid.Nil:
+		if existingCI, err = h.ciRepo.GetByID(r.Context(), ci.ID); err != nil {
+			middleware.RespondWithNotFoundError(w, "CI not found", nil)
+			return
+		}
+	case !linked:
+		h.saveCICreate(w, r, username, &ci, externalID)
+		return
+	}
+
+	// Check the version only if the client names one
+	if r.Header.Get("If-Match") != "" && !checkIfMatch(w, r, existingCI.Version) {
+		return
+	}
+
+	// Leave the CI untouched if it already has the external ID and the requested state
+	if linked && len(history.Diff(existingCI, &ci)) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		setETag(w, existingCI.Version)
+		json.NewEncoder(w).Encode(existingCI)
+		return
+	}
+
+	if linked {
+		externalID = nil
+	}
+	h.saveCIUpdate(w, r, username, existingCI, &ci, externalID)
+}
+
+// GetCIByExternalID handles retrieving the CI identified by an external ID
+// @Summary Get a CI by external ID
+// @Description Get the configuration item identified by an external ID. The ETag header carries the CI's version.
+// @Tags cis
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param source path string true "Source of the external ID"
+// @Param external_id path string true "External ID"
+// @Success 200 {object} models.CI
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cis/by-external-id/{source}/{external_id} [get]
+func (h *CIHandler) GetCIByExternalID(w http.ResponseWriter, r *http.Request) {
+	externalID, ok := h.parseExternalID(w, r)
+	if !ok {
+		return
+	}
+
+	ci, err := h.ciRepo.GetByExternalID(r.Context(), externalID.Source, externalID.ExternalID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrCINotFound) {
+			middleware.RespondWithNotFoundError(w, "CI not found", nil)
+			return
+		}
+		middleware.RespondWithInternalError(w, "Failed to get CI", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, ci.Version)
+	json.NewEncoder(w).Encode(ci)
+}
+
+// GetCIExternalIDs handles listing the external IDs of a CI
+// @Summary Get the external IDs of a CI
+// @Description Get the external IDs of a configuration item, ordered by source and external ID
+// @Tags cis
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "CI ID"
+// @Success 200 {array} models.CIExternalID
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cis/{id}/external-ids [get]
+func (h *CIHandler) GetCIExternalIDs(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL parameters
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		middleware.RespondWithValidationError(w, "ID parameter is required", nil)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid ID format", nil)
+		return
+	}
+
+	// Check if the CI exists
+	if _, err := h.ciRepo.GetByID(r.Context(), id); err != nil {
+		middleware.RespondWithNotFoundError(w, "CI not found", nil)
+		return
+	}
+
+	externalIDs, err := h.ciRepo.ListExternalIDs(r.Context(), id)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get external IDs", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(externalIDs)
+}
+
+// parseExternalID reads and validates the source and external ID from the URL parameters. It
+// writes a 400 response and returns false if they are invalid.
+func (h *CIHandler) parseExternalID(w http.ResponseWriter, r *http.Request) (*models.CIExternalID, bool) {
+	vars := mux.Vars(r)
+	externalID := &models.CIExternalID{
+		Source:     vars["source"],
+		ExternalID: vars["external_id"],
+	}
+
+	if validationError := h.validator.Validate(*externalID); validationError != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(models.GetHTTPStatusForError(models.ErrorTypeValidation))
+		json.NewEncoder(w).Encode(validationError)
+		return nil, false
+	}
+
+	return externalID, true
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/repositories"
+)
+
+// attributeParamPrefix marks query parameters that filter on attribute values, e.g. attributes.env=prod
+const attributeParamPrefix = "attributes."
+
+// relationshipAttributeParamPrefix marks graph query parameters that restrict the relationships
+// followed by their attribute values, e.g. rel_attributes.protocol=tcp
+const relationshipAttributeParamPrefix = "rel_attributes."
+
+// parseCIFilter builds a CIFilter from the list query parameters. Invalid parameters are
+// reported per parameter name in the same shape the validator uses.
+func parseCIFilter(query url.Values) (repositories.CIFilter, map[string]interface{}) {
+	filter := repositories.CIFilter{
+		Type:         query.Get("type"),
+		NamePrefix:   query.Get("name_prefix"),
+		NameContains: query.Get("name_contains"),
+		TagsMatch:    repositories.TagsMatchAny,
+	}
+	errors := make(map[string]interface{})
+
+	filter.Tags = splitList(query.Get("tags"))
+
+	switch match := query.Get("tags_match"); match {
+	case "", repositories.TagsMatchAny:
+	case repositories.TagsMatchAll:
+		filter.TagsMatch = repositories.TagsMatchAll
+	default:
+		errors["tags_match"] = []string{"tags_match must be one of: any all"}
+	}
+
+	timeParams := map[string]**time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+		"updated_after":  &filter.UpdatedAfter,
+		"updated_before": &filter.UpdatedBefore,
+	}
+	for param, target := range timeParams {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			errors[param] = []string{fmt.Sprintf("%s must be an RFC3339 timestamp", param)}
+			continue
+		}
+		*target = &parsed
+	}
+
+	filter.Attributes = parseAttributeParams(query, attributeParamPrefix, errors)
+	filter.AsOf = parseAsOf(query, errors)
+
+	if sort := query.Get("sort"); sort != "" {
+		field, direction, _ := strings.Cut(sort, ":")
+		if _, ok := repositories.CISortFields[field]; !ok {
+			errors["sort"] = []string{"sort field must be one of: name type created_at updated_at"}
+		}
+		switch direction {
+		case "", "asc":
+		case "desc":
+			filter.SortDesc = true
+		default:
+			errors["sort"] = []string{"sort direction must be one of: asc desc"}
+		}
+		filter.SortField = field
+	}
+
+	if len(errors) > 0 {
+		return filter, errors
+	}
+	return filter, nil
+}
+
+// parseAttributeParams collects the attribute filters given as prefix-named query parameters,
+// mapping each dotted attribute path to its value. It returns nil when there are none.
+func parseAttributeParams(query url.Values, prefix string, errors map[string]interface{}) map[string]string {
+	var attributes map[string]string
+	for param, values := range query {
+		if !strings.HasPrefix(param, prefix) || len(values) == 0 {
+			continue
+		}
+		path := strings.TrimPrefix(param, prefix)
+		if path == "" {
+			errors[param] = []string{"attribute filters must name an attribute"}
+			continue
+		}
+		if attributes == nil {
+			attributes = make(map[string]string)
+		}
+		attributes[path] = values[0]
+	}
+	return attributes
+}
+
+// parseAsOf reads the as_of point in time, reporting an invalid value in errors. It returns
+// nil when the parameter is absent, meaning the current state.
+func parseAsOf(query url.Values, errors map[string]interface{}) *time.Time {
+	value := query.Get("as_of")
+	if value == "" {
+		return nil
+	}
+	asOf, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		errors["as_of"] = []string{"as_of must be an RFC3339 timestamp"}
+		return nil
+	}
+	return &asOf
+}
+
+// splitList splits a comma-separated query parameter, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
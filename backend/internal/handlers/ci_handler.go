@@ -1,13 +1,21 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
-	"strconv"
 	"time"
 
+	"github.com/cmdb-lite/backend/internal/history"
 	"github.com/cmdb-lite/backend/internal/middleware"
 	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/pagination"
+	"github.com/cmdb-lite/backend/internal/patch"
+	"github.com/cmdb-lite/backend/internal/query"
 	"github.com/cmdb-lite/backend/internal/repositories"
 	"github.com/cmdb-lite/backend/internal/validation"
 	"github.com/google/uuid"
@@ -16,23 +24,26 @@ import (
 
 // CIHandler handles HTTP requests for CIs
 type CIHandler struct {
-	ciRepo    repositories.CIRepository
-	relRepo   repositories.RelationshipRepository
-	auditRepo repositories.AuditLogRepository
-	validator *validation.Validator
+	ciRepo     repositories.CIRepository
+	relRepo    repositories.RelationshipRepository
+	ciTypeRepo repositories.CITypeRepository
+	uow        repositories.UnitOfWork
+	validator  *validation.Validator
 }
 
 // NewCIHandler creates a new CIHandler
 func NewCIHandler(
 	ciRepo repositories.CIRepository,
 	relRepo repositories.RelationshipRepository,
-	auditRepo repositories.AuditLogRepository,
+	ciTypeRepo repositories.CITypeRepository,
+	uow repositories.UnitOfWork,
 ) *CIHandler {
 	return &CIHandler{
-		ciRepo:    ciRepo,
-		relRepo:   relRepo,
-		auditRepo: auditRepo,
-		validator: validation.NewValidator(),
+		ciRepo:     ciRepo,
+		relRepo:    relRepo,
+		ciTypeRepo: ciTypeRepo,
+		uow:        uow,
+		validator:  validation.NewValidator(),
 	}
 }
 
@@ -64,8 +75,22 @@ func (h *CIHandler) CreateCI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.saveCICreate(w, r, username, &ci, nil)
+}
+
+// saveCICreate validates the CI and creates it, then writes the audit log and the response.
+// A non-nil externalID is added to the CI in the same transaction.
+func (h *CIHandler) saveCICreate(w http.ResponseWriter, r *http.Request, username string, ci *models.CI, externalID *models.CIExternalID) {
 	// Validate CI data using the validator
-	if validationError := h.validator.Validate(ci); validationError != nil {
+	if validationError := h.validator.Validate(*ci); validationError != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(models.GetHTTPStatusForError(models.ErrorTypeValidation))
+		json.NewEncoder(w).Encode(validationError)
+		return
+	}
+
+	// Validate the CI type and attributes against the CI type registry
+	if validationError := h.validateCIType(r.Context(), ci); validationError != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(models.GetHTTPStatusForError(models.ErrorTypeValidation))
 		json.NewEncoder(w).Encode(validationError)
@@ -80,40 +105,56 @@ func (h *CIHandler) CreateCI(w http.ResponseWriter, r *http.Request) {
 	ci.CreatedAt = now
 	ci.UpdatedAt = now
 
-	// Create the CI
-	if err := h.ciRepo.Create(r.Context(), &ci); err != nil {
+	// Create the CI and its audit log in one transaction
+	err := h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		if err := tx.CIs.Create(r.Context(), ci); err != nil {
+			return err
+		}
+
+		details := ciAuditDetails(ci, history.Diff(nil, ci))
+		if externalID != nil {
+			externalID.CIID = ci.ID
+			externalID.CreatedAt = now
+			if err := tx.CIs.AddExternalID(r.Context(), externalID); err != nil {
+				return err
+			}
+			details["external_id"] = externalID
+		}
+
+		return tx.AuditLogs.Create(r.Context(), &models.AuditLog{
+			ID:         uuid.New(),
+			EntityType: "configuration_item",
+			EntityID:   ci.ID,
+			Action:     "create",
+			ChangedBy:  username,
+			ChangedAt:  time.Now(),
+			Details:    details,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrExternalIDTaken) {
+			middleware.RespondWithConflictError(w, "External ID belongs to another CI", nil)
+			return
+		}
 		middleware.RespondWithInternalError(w, "Failed to create CI", nil)
 		return
 	}
 
-	// Create audit log
-	auditLog := &models.AuditLog{
-		ID:         uuid.New(),
-		EntityType: "configuration_item",
-		EntityID:   ci.ID,
-		Action:     "create",
-		ChangedBy:  username,
-		ChangedAt:  time.Now(),
-		Details:    models.JSONBMap{"name": ci.Name, "type": ci.Type},
-	}
-	if err := h.auditRepo.Create(r.Context(), auditLog); err != nil {
-		// Log the error but don't fail the request
-		// In a real application, you would use a proper logger
-	}
-
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, ci.Version)
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(ci)
 }
 
 // GetCI handles retrieving a CI by ID
 // @Summary Get a CI by ID
-// @Description Get a configuration item by its ID
+// @Description Get a configuration item by its ID. The ETag header carries the CI's version for use in If-Match.
 // @Tags cis
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "CI ID"
+// @Param as_of query string false "Return the CI as it was at this RFC3339 time"
 // @Success 200 {object} models.CI
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
@@ -135,102 +176,224 @@ func (h *CIHandler) GetCI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	errors := make(map[string]interface{})
+	asOf := parseAsOf(r.URL.Query(), errors)
+	if len(errors) > 0 {
+		middleware.RespondWithValidationError(w, "Invalid query parameters", errors)
+		return
+	}
+
 	// Get the CI
-	ci, err := h.ciRepo.GetByID(r.Context(), id)
+	ci, err := h.getCI(r.Context(), id, asOf)
 	if err != nil {
 		middleware.RespondWithNotFoundError(w, "CI not found", nil)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, ci.Version)
 	json.NewEncoder(w).Encode(ci)
 }
 
-// GetAllCIs handles retrieving all CIs with pagination
+// GetAllCIs handles retrieving all CIs with filtering, sorting and pagination
 // @Summary Get all CIs
-// @Description Get all configuration items with pagination
+// @Description Get configuration items filtered, sorted and paginated in the database. Attribute
+// @Description values can be matched with attributes.<path>=value parameters, e.g. attributes.env=prod. With Accept
+// @Description set to text/csv, application/x-ndjson or application/yaml, all matching CIs are streamed in that
+// @Description format instead and pagination parameters are ignored.
 // @Tags cis
 // @Accept json
-// @Produce json
+// @Produce json,text/csv,application/x-ndjson,application/yaml
 // @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Number of items per page" default(10)
+// @Param cursor query string false "Opaque next_cursor or prev_cursor from a previous page; overrides page"
+// @Param type query string false "Filter by CI type"
+// @Param tags query string false "Comma-separated tags to filter by"
+// @Param tags_match query string false "Whether CIs must have any or all of the tags" default(any)
+// @Param name_prefix query string false "Filter by name prefix (case-insensitive)"
+// @Param name_contains query string false "Filter by name substring (case-insensitive)"
+// @Param created_after query string false "Only CIs created at or after this RFC3339 time"
+// @Param created_before query string false "Only CIs created before this RFC3339 time"
+// @Param updated_after query string false "Only CIs updated at or after this RFC3339 time"
+// @Param updated_before query string false "Only CIs updated before this RFC3339 time"
+// @Param sort query string false "Sort as field:asc or field:desc" default(created_at:desc)
+// @Param as_of query string false "List the CIs as they were at this RFC3339 time"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /cis [get]
 func (h *CIHandler) GetAllCIs(w http.ResponseWriter, r *http.Request) {
 	// Get pagination parameters from query string
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
+	pageReq, err := parsePageRequest(r.URL.Query())
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid cursor", nil)
+		return
+	}
 
-	// Set default values
-	page := 1
-	limit := 10
+	// Parse filtering and sorting parameters
+	filter, filterErrors := parseCIFilter(r.URL.Query())
+	if filterErrors != nil {
+		middleware.RespondWithValidationError(w, "Invalid query parameters", filterErrors)
+		return
+	}
 
-	// Parse page parameter
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
+	h.respondWithCIPage(w, r, filter, pageReq)
+}
+
+// SearchCIs handles searching CIs with the query language
+// @Summary Search CIs
+// @Description Search configuration items with a query such as type = "server" AND attributes.cpu >= 8 AND tags CONTAINS "pci".
+// @Description The list filter, sort and pagination parameters may be combined with the query.
+// @Tags cis
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Search query"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(10)
+// @Param cursor query string false "Opaque next_cursor or prev_cursor from a previous page; overrides page"
+// @Param sort query string false "Sort as field:asc or field:desc" default(created_at:desc)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cis/search [get]
+func (h *CIHandler) SearchCIs(w http.ResponseWriter, r *http.Request) {
+	// Parse the search query
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+			"q": []string{"q is required"},
+		})
+		return
 	}
 
-	// Parse limit parameter
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
+	expr, err := query.Parse(q)
+	if err != nil {
+		var syntaxErr *query.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			middleware.RespondWithValidationError(w, "Invalid search query", map[string]interface{}{
+				"q":      []string{syntaxErr.Error()},
+				"column": syntaxErr.Column,
+			})
+			return
 		}
+		middleware.RespondWithValidationError(w, "Invalid search query", map[string]interface{}{
+			"q": []string{err.Error()},
+		})
+		return
 	}
 
-	// Get all CIs
-	cis, err := h.ciRepo.GetAll(r.Context())
+	// Get pagination parameters from query string
+	pageReq, err := parsePageRequest(r.URL.Query())
 	if err != nil {
-		middleware.RespondWithInternalError(w, "Failed to get CIs", nil)
+		middleware.RespondWithValidationError(w, "Invalid cursor", nil)
+		return
+	}
+
+	// Parse filtering and sorting parameters
+	filter, filterErrors := parseCIFilter(r.URL.Query())
+	if filterErrors != nil {
+		middleware.RespondWithValidationError(w, "Invalid query parameters", filterErrors)
 		return
 	}
+	filter.Query = expr
 
-	// Calculate pagination
-	total := len(cis)
-	start := (page - 1) * limit
-	end := start + limit
+	h.respondWithCIPage(w, r, filter, pageReq)
+}
 
-	if start > total {
-		start = total
+// respondWithCIPage writes one page of the CIs matching the filter, or all of them if the
+// client asks for an export format
+func (h *CIHandler) respondWithCIPage(w http.ResponseWriter, r *http.Request, filter repositories.CIFilter, pageReq pageRequest) {
+	if mediaType := exportFormat(r); mediaType != "" {
+		h.exportCIs(w, r, filter, mediaType)
+		return
 	}
-	if end > total {
-		end = total
+
+	// Cursors are keyed on (created_at, id), so they only work with the default ordering
+	keyset := filter.SortField == "" || (filter.SortField == "created_at" && filter.SortDesc)
+	if pageReq.Cursor != nil && !keyset {
+		middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+			"sort": []string{"sort cannot be combined with cursor"},
+		})
+		return
+	}
+
+	// Count all matching CIs
+	total, err := h.ciRepo.Count(r.Context(), filter)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get CIs", nil)
+		return
+	}
+
+	// Get the requested page of CIs
+	filter.Limit = pageReq.FetchLimit()
+	if pageReq.Cursor != nil {
+		filter.Cursor = pageReq.Cursor
+	} else {
+		filter.Offset = pageReq.Offset()
+	}
+	cis, err := h.ciRepo.List(r.Context(), filter)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get CIs", nil)
+		return
 	}
 
-	paginatedCIs := cis[start:end]
+	cis, paginationInfo := paginate(cis, pageReq, total, keyset, func(ci *models.CI) pagination.Cursor {
+		return pagination.Cursor{Timestamp: ci.CreatedAt, ID: ci.ID}
+	})
 
 	// Create response
 	response := map[string]interface{}{
-		"data": paginatedCIs,
-		"pagination": map[string]interface{}{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-		},
+		"data":       cis,
+		"pagination": paginationInfo,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// ciExportFields are the CI fields of a CSV export, before the attribute columns
+var ciExportFields = []string{"id", "name", "type", "tags", "version", "created_at", "updated_at"}
+
+// exportCIs streams the CIs matching the filter, ignoring pagination, in an export format
+func (h *CIHandler) exportCIs(w http.ResponseWriter, r *http.Request, filter repositories.CIFilter, mediaType string) {
+	table := exportTable{fields: ciExportFields, jsonField: "attributes"}
+	if mediaType == csvMediaType {
+		paths, err := h.ciRepo.AttributePaths(r.Context(), filter)
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to export CIs", nil)
+			return
+		}
+		table.paths = paths
+	}
+
+	exporter := newExporter(w, mediaType, table)
+	err := h.ciRepo.Stream(r.Context(), filter, func(ci *models.CI) error {
+		return exporter.write(ci)
+	})
+	exporter.finish(err, "Failed to export CIs")
+}
+
 // UpdateCI handles updating an existing CI
 // @Summary Update a CI
-// @Description Update an existing configuration item
+// @Description Update an existing configuration item. If-Match must carry the ETag of the version being updated.
 // @Tags cis
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "CI ID"
+// @Param If-Match header string true "ETag of the CI version being updated"
 // @Param ci body models.CI true "Updated CI object"
 // @Success 200 {object} models.CI
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /cis/{id} [put]
 func (h *CIHandler) UpdateCI(w http.ResponseWriter, r *http.Request) {
@@ -262,6 +425,11 @@ func (h *CIHandler) UpdateCI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check that the client updates the current version
+	if !checkIfMatch(w, r, existingCI.Version) {
+		return
+	}
+
 	// Decode the request body
 	var updatedCI models.CI
 	if err := json.NewDecoder(r.Body).Decode(&updatedCI); err != nil {
@@ -269,57 +437,244 @@ func (h *CIHandler) UpdateCI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.saveCIUpdate(w, r, username, existingCI, &updatedCI, nil)
+}
+
+// PatchCI handles partially updating an existing CI
+// @Summary Patch a CI
+// @Description Partially update a configuration item with a JSON Merge Patch (RFC 7386, Content-Type
+// @Description application/merge-patch+json) or a JSON Patch (RFC 6902, Content-Type application/json-patch+json).
+// @Description The patch is applied to the stored CI, which is then validated as on update. Changes to id, version,
+// @Description created_at and updated_at are ignored. If-Match must carry the ETag of the version being patched.
+// @Tags cis
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "CI ID"
+// @Param If-Match header string true "ETag of the CI version being patched"
+// @Param patch body object true "JSON Merge Patch object or JSON Patch operation array"
+// @Success 200 {object} models.CI
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 428 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cis/{id} [patch]
+func (h *CIHandler) PatchCI(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	// Extract ID from URL parameters
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		middleware.RespondWithValidationError(w, "ID parameter is required", nil)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid ID format", nil)
+		return
+	}
+
+	// Check the patch format
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != patch.MergePatchMediaType && mediaType != patch.JSONPatchMediaType) {
+		w.Header().Set("Accept-Patch", patch.MergePatchMediaType+", "+patch.JSONPatchMediaType)
+		middleware.RespondWithUnsupportedMediaTypeError(w, "Unsupported patch format", map[string]interface{}{
+			"content_type": []string{"Content-Type must be " + patch.MergePatchMediaType + " or " + patch.JSONPatchMediaType},
+		})
+		return
+	}
+
+	// Get the existing CI
+	existingCI, err := h.ciRepo.GetByID(r.Context(), id)
+	if err != nil {
+		middleware.RespondWithNotFoundError(w, "CI not found", nil)
+		return
+	}
+
+	// Check that the client patches the current version
+	if !checkIfMatch(w, r, existingCI.Version) {
+		return
+	}
+
+	// Read the patch
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid request body", nil)
+		return
+	}
+
+	// Apply the patch to the stored CI as a JSON document
+	encoded, err := json.Marshal(existingCI)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to patch CI", nil)
+		return
+	}
+	var document interface{}
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		middleware.RespondWithInternalError(w, "Failed to patch CI", nil)
+		return
+	}
+
+	if mediaType == patch.MergePatchMediaType {
+		var mergePatch interface{}
+		if err := json.Unmarshal(body, &mergePatch); err != nil {
+			middleware.RespondWithValidationError(w, "Invalid request body", nil)
+			return
+		}
+		document = patch.MergePatch(document, mergePatch)
+	} else {
+		operations, err := patch.ParseJSONPatch(body)
+		if err == nil {
+			document, err = patch.ApplyJSONPatch(document, operations)
+		}
+		if err != nil {
+			respondWithPatchError(w, err)
+			return
+		}
+	}
+
+	// Read the patched document back into a CI
+	var patchedCI models.CI
+	encoded, err = json.Marshal(document)
+	if err == nil {
+		err = json.Unmarshal(encoded, &patchedCI)
+	}
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Patched document is not a valid CI", map[string]interface{}{
+			"patch": []string{err.Error()},
+		})
+		return
+	}
+	if patchedCI.Attributes == nil {
+		patchedCI.Attributes = models.JSONBMap{}
+	}
+
+	h.saveCIUpdate(w, r, username, existingCI, &patchedCI, nil)
+}
+
+// saveCIUpdate validates the updated CI, copies its name, type, attributes and tags to the
+// existing CI and saves it, then writes the audit log and the response. A non-nil externalID
+// is added to the CI in the same transaction.
+func (h *CIHandler) saveCIUpdate(w http.ResponseWriter, r *http.Request, username string, existingCI, updatedCI *models.CI, externalID *models.CIExternalID) {
 	// Validate CI data using the validator
-	if validationError := h.validator.Validate(updatedCI); validationError != nil {
+	if validationError := h.validator.Validate(*updatedCI); validationError != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(models.GetHTTPStatusForError(models.ErrorTypeValidation))
+		json.NewEncoder(w).Encode(validationError)
+		return
+	}
+
+	// Validate the CI type and attributes against the CI type registry
+	if validationError := h.validateCIType(r.Context(), updatedCI); validationError != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(models.GetHTTPStatusForError(models.ErrorTypeValidation))
 		json.NewEncoder(w).Encode(validationError)
 		return
 	}
 
-	// Update the CI
+	// Update the CI, keeping the previous state for the audit log
+	before := *existingCI
 	existingCI.Name = updatedCI.Name
 	existingCI.Type = updatedCI.Type
 	existingCI.Attributes = updatedCI.Attributes
 	existingCI.Tags = updatedCI.Tags
 	existingCI.UpdatedAt = time.Now()
 
-	if err := h.ciRepo.Update(r.Context(), existingCI); err != nil {
+	// Save the CI and its audit log in one transaction
+	err := h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		if err := tx.CIs.Update(r.Context(), existingCI); err != nil {
+			return err
+		}
+
+		details := ciAuditDetails(existingCI, history.Diff(&before, existingCI))
+		if externalID != nil {
+			externalID.CIID = existingCI.ID
+			externalID.CreatedAt = existingCI.UpdatedAt
+			if err := tx.CIs.AddExternalID(r.Context(), externalID); err != nil {
+				return err
+			}
+			details["external_id"] = externalID
+		}
+
+		return tx.AuditLogs.Create(r.Context(), &models.AuditLog{
+			ID:         uuid.New(),
+			EntityType: "configuration_item",
+			EntityID:   existingCI.ID,
+			Action:     "update",
+			ChangedBy:  username,
+			ChangedAt:  time.Now(),
+			Details:    details,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			middleware.RespondWithPreconditionFailedError(w, preconditionFailedMessage, nil)
+			return
+		}
+		if errors.Is(err, repositories.ErrExternalIDTaken) {
+			middleware.RespondWithConflictError(w, "External ID belongs to another CI", nil)
+			return
+		}
 		middleware.RespondWithInternalError(w, "Failed to update CI", nil)
 		return
 	}
 
-	// Create audit log
-	auditLog := &models.AuditLog{
-		ID:         uuid.New(),
-		EntityType: "configuration_item",
-		EntityID:   existingCI.ID,
-		Action:     "update",
-		ChangedBy:  username,
-		ChangedAt:  time.Now(),
-		Details:    models.JSONBMap{"name": existingCI.Name, "type": existingCI.Type},
-	}
-	if err := h.auditRepo.Create(r.Context(), auditLog); err != nil {
-		// Log the error but don't fail the request
-	}
-
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, existingCI.Version)
 	json.NewEncoder(w).Encode(existingCI)
 }
 
+// respondWithPatchError writes the response for a JSON Patch that cannot be applied. A failed
+// test operation means the CI is not in the state the client expected, which is a conflict.
+func respondWithPatchError(w http.ResponseWriter, err error) {
+	patchErr, ok := err.(*patch.Error)
+	if !ok {
+		middleware.RespondWithValidationError(w, "Invalid JSON Patch", map[string]interface{}{
+			"patch": []string{err.Error()},
+		})
+		return
+	}
+
+	details := map[string]interface{}{
+		"patch":     []string{patchErr.Message},
+		"operation": patchErr.Index,
+	}
+	if patchErr.TestFailed {
+		middleware.RespondWithConflictError(w, "JSON Patch test failed", details)
+		return
+	}
+	middleware.RespondWithValidationError(w, "Invalid JSON Patch", details)
+}
+
 // DeleteCI handles deleting a CI
 // @Summary Delete a CI
-// @Description Delete a configuration item
+// @Description Move a configuration item and its relationships to the trash, from which they can be restored until
+// @Description the retention period ends. If-Match must carry the ETag of the current version.
 // @Tags cis
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "CI ID"
+// @Param If-Match header string true "ETag of the current CI version"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /cis/{id} [delete]
 func (h *CIHandler) DeleteCI(w http.ResponseWriter, r *http.Request) {
@@ -351,24 +706,46 @@ func (h *CIHandler) DeleteCI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete the CI
-	if err := h.ciRepo.Delete(r.Context(), id); err != nil {
-		middleware.RespondWithInternalError(w, "Failed to delete CI", nil)
+	// Check that the client deletes the current version
+	if !checkIfMatch(w, r, ci.Version) {
 		return
 	}
 
-	// Create audit log
-	auditLog := &models.AuditLog{
-		ID:         uuid.New(),
-		EntityType: "configuration_item",
-		EntityID:   ci.ID,
-		Action:     "delete",
-		ChangedBy:  username,
-		ChangedAt:  time.Now(),
-		Details:    models.JSONBMap{"name": ci.Name, "type": ci.Type},
-	}
-	if err := h.auditRepo.Create(r.Context(), auditLog); err != nil {
-		// Log the error but don't fail the request
+	// Move the CI and its relationships to the trash and record them in one transaction
+	err = h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		if err := tx.CIs.Delete(r.Context(), id, ci.Version); err != nil {
+			return err
+		}
+
+		trashed, err := tx.Relationships.ListDeletedWithCIs(r.Context(), []uuid.UUID{id})
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		auditLogs := []*models.AuditLog{{
+			ID:         uuid.New(),
+			EntityType: "configuration_item",
+			EntityID:   ci.ID,
+			Action:     "delete",
+			ChangedBy:  username,
+			ChangedAt:  now,
+			Details:    ciAuditDetails(ci, history.Diff(ci, nil)),
+		}}
+		auditLogs = append(auditLogs, relationshipAuditLogs(trashed, history.ActionDelete, username, now)...)
+		return tx.AuditLogs.CreateBatch(r.Context(), auditLogs)
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			middleware.RespondWithPreconditionFailedError(w, preconditionFailedMessage, nil)
+			return
+		}
+		if errors.Is(err, repositories.ErrCINotFound) {
+			middleware.RespondWithNotFoundError(w, "CI not found", nil)
+			return
+		}
+		middleware.RespondWithInternalError(w, "Failed to delete CI", nil)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -377,13 +754,18 @@ func (h *CIHandler) DeleteCI(w http.ResponseWriter, r *http.Request) {
 
 // GetCIGraph handles retrieving related CIs (graph traversal)
 // @Summary Get CI graph
-// @Description Get related configuration items (graph traversal)
+// @Description Get the configuration items reachable from a CI within depth hops and the relationships between them
 // @Tags cis
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "CI ID"
-// @Success 200 {object} map[string]interface{}
+// @Param depth query int false "Number of hops to follow (1-10)" default(1)
+// @Param direction query string false "Follow outgoing (out), incoming (in) or all (both) relationships" default(both)
+// @Param rel_types query string false "Comma-separated relationship types to follow"
+// @Param ci_types query string false "Comma-separated CI types to include"
+// @Param as_of query string false "Walk the graph as it was at this RFC3339 time"
+// @Success 200 {object} models.CIGraph
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 404 {object} map[string]string
@@ -404,36 +786,72 @@ func (h *CIHandler) GetCIGraph(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the CI
-	ci, err := h.ciRepo.GetByID(r.Context(), id)
-	if err != nil {
+	// Parse traversal parameters
+	filter, filterErrors := parseGraphFilter(r.URL.Query())
+	if filterErrors != nil {
+		middleware.RespondWithValidationError(w, "Invalid query parameters", filterErrors)
+		return
+	}
+
+	// Check if the CI exists, or existed at the requested time
+	if _, err := h.getCI(r.Context(), id, filter.AsOf); err != nil {
 		middleware.RespondWithNotFoundError(w, "CI not found", nil)
 		return
 	}
 
-	// Get relationships where this CI is the source
-	sourceRels, err := h.relRepo.GetBySourceCI(r.Context(), id)
+	// Walk the graph from the CI
+	graph, err := h.relRepo.Traverse(r.Context(), id, filter)
 	if err != nil {
-		middleware.RespondWithInternalError(w, "Failed to get relationships", nil)
+		middleware.RespondWithInternalError(w, "Failed to get CI graph", nil)
 		return
 	}
 
-	// Get relationships where this CI is the target
-	targetRels, err := h.relRepo.GetByTargetCI(r.Context(), id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(graph)
+}
+
+// validateCIType checks that the CI has a registered type and that its attributes
+// conform to that type's JSON Schema
+func (h *CIHandler) validateCIType(ctx context.Context, ci *models.CI) *models.ErrorResponse {
+	ciType, err := h.ciTypeRepo.GetByName(ctx, ci.Type)
 	if err != nil {
-		middleware.RespondWithInternalError(w, "Failed to get relationships", nil)
-		return
+		return unregisteredCITypeError(ci.Type)
 	}
 
-	// Create response
-	response := map[string]interface{}{
-		"node": ci,
-		"relationships": map[string]interface{}{
-			"outgoing": sourceRels,
-			"incoming": targetRels,
-		},
+	return h.validator.ValidateAttributes(ciType.Schema, ci.Attributes)
+}
+
+// unregisteredCITypeError returns the validation error for a CI whose type is not registered
+func unregisteredCITypeError(ciType string) *models.ErrorResponse {
+	return models.NewErrorResponse(
+		models.ErrorTypeValidation,
+		"Validation failed",
+		map[string]interface{}{"type": []string{fmt.Sprintf("type '%s' is not a registered CI type", ciType)}},
+	)
+}
+
+// getCI retrieves a CI by ID, as it was at asOf when that is set
+func (h *CIHandler) getCI(ctx context.Context, id uuid.UUID, asOf *time.Time) (*models.CI, error) {
+	if asOf == nil {
+		return h.ciRepo.GetByID(ctx, id)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	cis, err := h.ciRepo.List(ctx, repositories.CIFilter{IDs: []uuid.UUID{id}, AsOf: asOf})
+	if err != nil {
+		return nil, err
+	}
+	if len(cis) == 0 {
+		return nil, errors.New("CI not found")
+	}
+	return cis[0], nil
+}
+
+// ciAuditDetails returns the audit log details for a CI change: the CI's name and type and
+// the field changes that history uses to rebuild earlier versions
+func ciAuditDetails(ci *models.CI, changes []history.Change) models.JSONBMap {
+	return models.JSONBMap{
+		"name":    ci.Name,
+		"type":    ci.Type,
+		"changes": changes,
+	}
 }
@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/csvimport"
+	"github.com/cmdb-lite/backend/internal/history"
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/patch"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// maxImportSize is the largest CSV import request in bytes, file and mapping included
+const maxImportSize = 32 << 20
+
+// maxImportRows is the largest number of data rows in a CSV import. The rows are written in
+// batches of maxBulkOperations.
+const maxImportRows = 10000
+
+// Import row statuses
+const (
+	importCreated  = "created"
+	importUpdated  = "updated"
+	importSkipped  = "skipped"
+	importRejected = "rejected"
+)
+
+// importRowResult is the outcome of a data row of a CSV import. ID is the CI the row updates
+// or, once committed, creates; Changes are the changes an update makes.
+type importRowResult struct {
+	Line       int                   `json:"line"`
+	Status     string                `json:"status"`
+	ID         *uuid.UUID            `json:"id,omitempty"`
+	ExternalID string                `json:"external_id,omitempty"`
+	Changes    []history.Change      `json:"changes,omitempty"`
+	Error      *models.ErrorResponse `json:"error,omitempty"`
+}
+
+// ImportCIs handles importing CIs from a CSV file
+// @Summary Import CIs from CSV
+// @Description Create and update configuration items from the rows of a CSV file, using a mapping from columns to
+// @Description name, type, tags and attributes.*. With an external ID column, rows update the CIs that have the
+// @Description external ID and create the others with it; without one, every row creates a CI. Updates keep the
+// @Description attributes that have no column. By default the import is a dry run that only reports what each row
+// @Description would do; with dry_run=false the rows that are not rejected are written in one transaction.
+// @Tags import
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV file whose first line is the header"
+// @Param mapping formData string true "Mapping of columns to CI fields, as JSON"
+// @Param dry_run query bool false "Report without writing" default(true)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /import/cis [post]
+func (h *CIHandler) ImportCIs(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	dryRun := true
+	if dryRunStr := r.URL.Query().Get("dry_run"); dryRunStr != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(dryRunStr); err != nil {
+			middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+				"dry_run": []string{"dry_run must be true or false"},
+			})
+			return
+		}
+	}
+
+	// Read the mapping and the file from the form
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid request body", map[string]interface{}{
+			"file": []string{"a CSV file of at most 32 MB is required"},
+		})
+		return
+	}
+	defer file.Close()
+
+	var mapping csvimport.Mapping
+	if err := json.Unmarshal([]byte(r.FormValue("mapping")), &mapping); err != nil {
+		middleware.RespondWithValidationError(w, "Invalid request body", map[string]interface{}{
+			"mapping": []string{"mapping must be a JSON object"},
+		})
+		return
+	}
+
+	rows, err := csvimport.Read(file, mapping)
+	var mappingError *csvimport.MappingError
+	if errors.As(err, &mappingError) {
+		middleware.RespondWithValidationError(w, "Invalid mapping", mappingError.Details)
+		return
+	}
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid CSV file", map[string]interface{}{
+			"file": []string{err.Error()},
+		})
+		return
+	}
+	if len(rows) == 0 || len(rows) > maxImportRows {
+		middleware.RespondWithValidationError(w, "Invalid CSV file", map[string]interface{}{
+			"file": []string{fmt.Sprintf("between 1 and %d rows are required", maxImportRows)},
+		})
+		return
+	}
+
+	// Work out what each row does, and write the rows in one transaction unless this is a
+	// dry run
+	results := make([]importRowResult, len(rows))
+	if dryRun {
+		_, err = h.planImport(r.Context(), h.ciRepo, mapping, rows, results)
+	} else {
+		err = h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+			items, err := h.planImport(r.Context(), tx.CIs, mapping, rows, results)
+			if err != nil {
+				return err
+			}
+			return h.commitImport(r.Context(), tx, username, mapping, rows, items, results)
+		})
+	}
+	if err != nil {
+		if errors.Is(err, repositories.ErrExternalIDTaken) {
+			middleware.RespondWithConflictError(w, "An external ID was taken by another CI during the import", nil)
+			return
+		}
+		middleware.RespondWithInternalError(w, "Failed to import CIs", nil)
+		return
+	}
+
+	summary := map[string]int{importCreated: 0, importUpdated: 0, importSkipped: 0, importRejected: 0}
+	for _, result := range results {
+		summary[result.Status]++
+	}
+
+	// Create response
+	response := map[string]interface{}{
+		"dry_run": dryRun,
+		"summary": summary,
+		"rows":    results,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// planImport works out what each row does, records it in results and returns the writes for
+// the rows that are not rejected or skipped. Rows with an external ID that a CI has update it;
+// the others create a CI. CI types are looked up once per import.
+func (h *CIHandler) planImport(ctx context.Context, ciRepo repositories.CIRepository, mapping csvimport.Mapping, rows []csvimport.Row, results []importRowResult) ([]bulkItem, error) {
+	owners, current, err := h.findImportedCIs(ctx, ciRepo, mapping, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	tagsMapped := false
+	for _, target := range mapping.Columns {
+		tagsMapped = tagsMapped || target == csvimport.TargetTags
+	}
+
+	ciTypes := make(map[string]*models.CIType)
+	lines := make(map[string]int)
+	items := make([]bulkItem, 0, len(rows))
+
+	for i, row := range rows {
+		results[i] = importRowResult{Line: row.Line, ExternalID: row.ExternalID}
+		reject := func(rowError *models.ErrorResponse) {
+			results[i].Status = importRejected
+			results[i].Error = rowError
+		}
+
+		if mapping.ExternalID != nil {
+			externalID := models.CIExternalID{Source: mapping.ExternalID.Source, ExternalID: row.ExternalID}
+			if validationError := h.validator.Validate(externalID); validationError != nil {
+				reject(validationError)
+				continue
+			}
+			if line, ok := lines[row.ExternalID]; ok {
+				reject(bulkValidationError("external_id", fmt.Sprintf("external ID is also on line %d", line)))
+				continue
+			}
+			lines[row.ExternalID] = row.Line
+		}
+
+		if validationError := h.validator.Validate(*row.CI); validationError != nil {
+			reject(validationError)
+			continue
+		}
+		ciType := h.getCachedCIType(ctx, row.CI.Type, ciTypes)
+		if ciType == nil {
+			reject(unregisteredCITypeError(row.CI.Type))
+			continue
+		}
+
+		// Cells are strings; give the attributes the types of the CI type's schema
+		csvimport.Coerce(row.CI.Attributes, ciType.Schema)
+
+		ownerID, owned := owners[row.ExternalID]
+		existingCI := current[ownerID]
+		if owned && existingCI == nil {
+			reject(models.NewErrorResponse(models.ErrorTypeConflict, "External ID belongs to a CI in the trash",
+				map[string]interface{}{"ci_id": ownerID}))
+			continue
+		}
+
+		// A new CI is the row's CI
+		if existingCI == nil {
+			if validationError := h.validator.ValidateAttributes(ciType.Schema, row.CI.Attributes); validationError != nil {
+				reject(validationError)
+				continue
+			}
+			results[i].Status = importCreated
+			items = append(items, bulkItem{index: i, op: bulkOpUpsert, ci: row.CI})
+			continue
+		}
+
+		// An existing CI gets the row's name, type and attributes and, if mapped, its tags
+		updatedCI := *existingCI
+		updatedCI.Name = row.CI.Name
+		updatedCI.Type = row.CI.Type
+		if tagsMapped {
+			updatedCI.Tags = row.CI.Tags
+		}
+		if updatedCI.Attributes, err = mergeImportedAttributes(existingCI.Attributes, row.CI.Attributes); err != nil {
+			return nil, err
+		}
+		if validationError := h.validator.ValidateAttributes(ciType.Schema, updatedCI.Attributes); validationError != nil {
+			reject(validationError)
+			continue
+		}
+
+		results[i].ID = &existingCI.ID
+		results[i].Changes = history.Diff(existingCI, &updatedCI)
+		if len(results[i].Changes) == 0 {
+			results[i].Status = importSkipped
+			results[i].Changes = nil
+			continue
+		}
+		results[i].Status = importUpdated
+		items = append(items, bulkItem{
+			index:   i,
+			op:      bulkOpUpsert,
+			id:      existingCI.ID,
+			version: existingCI.Version,
+			ci:      &updatedCI,
+		})
+	}
+
+	return items, nil
+}
+
+// findImportedCIs looks up the CIs that have the rows' external IDs. It returns the owner of
+// each external ID found and the live CIs among the owners; owners missing from them are in
+// the trash.
+func (h *CIHandler) findImportedCIs(ctx context.Context, ciRepo repositories.CIRepository, mapping csvimport.Mapping, rows []csvimport.Row) (map[string]uuid.UUID, map[uuid.UUID]*models.CI, error) {
+	owners := make(map[string]uuid.UUID)
+	current := make(map[uuid.UUID]*models.CI)
+	if mapping.ExternalID == nil {
+		return owners, current, nil
+	}
+
+	externalIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.ExternalID != "" {
+			externalIDs = append(externalIDs, row.ExternalID)
+		}
+	}
+
+	found, err := ciRepo.FindExternalIDs(ctx, mapping.ExternalID.Source, externalIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(found) == 0 {
+		return owners, current, nil
+	}
+
+	ids := make([]uuid.UUID, len(found))
+	for i, externalID := range found {
+		owners[externalID.ExternalID] = externalID.CIID
+		ids[i] = externalID.CIID
+	}
+
+	cis, err := ciRepo.List(ctx, repositories.CIFilter{IDs: ids})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, ci := range cis {
+		current[ci.ID] = ci
+	}
+
+	return owners, current, nil
+}
+
+// commitImport writes the planned rows in batches and gives the new CIs their external IDs.
+// Rows whose CI changed since it was planned are rejected.
+func (h *CIHandler) commitImport(ctx context.Context, tx repositories.Repos, username string, mapping csvimport.Mapping, rows []csvimport.Row, items []bulkItem, results []importRowResult) error {
+	bulkResults := make([]models.BulkCIResult, len(rows))
+	for start := 0; start < len(items); start += maxBulkOperations {
+		end := start + maxBulkOperations
+		if end > len(items) {
+			end = len(items)
+		}
+		if _, err := h.applyBulkOperations(ctx, tx, username, items[start:end], bulkResults); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	var externalIDs []*models.CIExternalID
+	for _, item := range items {
+		result := &results[item.index]
+		bulkResult := bulkResults[item.index]
+		if bulkResult.Error != nil {
+			result.Status = importRejected
+			result.Changes = nil
+			result.Error = bulkResult.Error
+			continue
+		}
+
+		result.ID = bulkResult.ID
+		if result.Status == importCreated && mapping.ExternalID != nil {
+			externalIDs = append(externalIDs, &models.CIExternalID{
+				CIID:       *bulkResult.ID,
+				Source:     mapping.ExternalID.Source,
+				ExternalID: rows[item.index].ExternalID,
+				CreatedAt:  now,
+			})
+		}
+	}
+
+	return tx.CIs.AddExternalIDs(ctx, externalIDs)
+}
+
+// mergeImportedAttributes returns a copy of the CI's attributes with the imported attributes
+// merged in, so that attributes without a column keep their values
+func mergeImportedAttributes(attributes, imported models.JSONBMap) (models.JSONBMap, error) {
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := map[string]interface{}{}
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	if merged == nil {
+		merged = map[string]interface{}{}
+	}
+
+	return patch.MergePatch(merged, map[string]interface{}(imported)).(map[string]interface{}), nil
+}
@@ -0,0 +1,405 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/cmdb-lite/backend/internal/validation"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// CITypeHandler handles HTTP requests for the CI type registry
+type CITypeHandler struct {
+	ciTypeRepo repositories.CITypeRepository
+	ciRepo     repositories.CIRepository
+	uow        repositories.UnitOfWork
+	validator  *validation.Validator
+}
+
+// NewCITypeHandler creates a new CITypeHandler
+func NewCITypeHandler(
+	ciTypeRepo repositories.CITypeRepository,
+	ciRepo repositories.CIRepository,
+	uow repositories.UnitOfWork,
+) *CITypeHandler {
+	return &CITypeHandler{
+		ciTypeRepo: ciTypeRepo,
+		ciRepo:     ciRepo,
+		uow:        uow,
+		validator:  validation.NewValidator(),
+	}
+}
+
+// CreateCIType handles the creation of a new CI type
+// @Summary Create a new CI type
+// @Description Register a new CI type with a JSON Schema for its attributes
+// @Tags ci-types
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param ciType body models.CIType true "CI type object"
+// @Success 201 {object} models.CIType
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /ci-types [post]
+func (h *CITypeHandler) CreateCIType(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	var ciType models.CIType
+	if err := json.NewDecoder(r.Body).Decode(&ciType); err != nil {
+		middleware.RespondWithValidationError(w, "Invalid request body", nil)
+		return
+	}
+
+	// Validate CI type data using the validator
+	if validationError := h.validateCIType(ciType); validationError != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(models.GetHTTPStatusForError(models.ErrorTypeValidation))
+		json.NewEncoder(w).Encode(validationError)
+		return
+	}
+
+	// Make sure the name is not already registered
+	taken, err := h.nameTaken(r, ciType.Name, uuid.Nil)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to check CI type name", nil)
+		return
+	}
+	if taken {
+		middleware.RespondWithConflictError(w, "CI type already exists", nil)
+		return
+	}
+
+	// Set default values
+	if ciType.ID == uuid.Nil {
+		ciType.ID = uuid.New()
+	}
+	if ciType.Schema == nil {
+		ciType.Schema = models.JSONBMap{"type": "object"}
+	}
+	now := time.Now()
+	ciType.CreatedAt = now
+	ciType.UpdatedAt = now
+
+	// Create the CI type and its audit log in one transaction
+	err = h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		if err := tx.CITypes.Create(r.Context(), &ciType); err != nil {
+			return err
+		}
+
+		return tx.AuditLogs.Create(r.Context(), &models.AuditLog{
+			ID:         uuid.New(),
+			EntityType: "ci_type",
+			EntityID:   ciType.ID,
+			Action:     "create",
+			ChangedBy:  username,
+			ChangedAt:  time.Now(),
+			Details:    models.JSONBMap{"name": ciType.Name, "schema": ciType.Schema},
+		})
+	})
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to create CI type", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ciType)
+}
+
+// GetCIType handles retrieving a CI type by ID
+// @Summary Get a CI type by ID
+// @Description Get a registered CI type by its ID
+// @Tags ci-types
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "CI type ID"
+// @Success 200 {object} models.CIType
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /ci-types/{id} [get]
+func (h *CITypeHandler) GetCIType(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL parameters
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		middleware.RespondWithValidationError(w, "ID parameter is required", nil)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid ID format", nil)
+		return
+	}
+
+	// Get the CI type
+	ciType, err := h.ciTypeRepo.GetByID(r.Context(), id)
+	if err != nil {
+		middleware.RespondWithError(w, models.ErrorTypeCITypeNotFound, "CI type not found", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ciType)
+}
+
+// GetAllCITypes handles retrieving all CI types
+// @Summary Get all CI types
+// @Description Get all registered CI types
+// @Tags ci-types
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} []models.CIType
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /ci-types [get]
+func (h *CITypeHandler) GetAllCITypes(w http.ResponseWriter, r *http.Request) {
+	// Get all CI types
+	ciTypes, err := h.ciTypeRepo.GetAll(r.Context())
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get CI types", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ciTypes)
+}
+
+// UpdateCIType handles updating an existing CI type
+// @Summary Update a CI type
+// @Description Update the description or attribute schema of a CI type
+// @Tags ci-types
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "CI type ID"
+// @Param ciType body models.CIType true "Updated CI type object"
+// @Success 200 {object} models.CIType
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /ci-types/{id} [put]
+func (h *CITypeHandler) UpdateCIType(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	// Extract ID from URL parameters
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		middleware.RespondWithValidationError(w, "ID parameter is required", nil)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid ID format", nil)
+		return
+	}
+
+	// Get the existing CI type
+	existingType, err := h.ciTypeRepo.GetByID(r.Context(), id)
+	if err != nil {
+		middleware.RespondWithError(w, models.ErrorTypeCITypeNotFound, "CI type not found", nil)
+		return
+	}
+
+	// Decode the request body
+	var updatedType models.CIType
+	if err := json.NewDecoder(r.Body).Decode(&updatedType); err != nil {
+		middleware.RespondWithValidationError(w, "Invalid request body", nil)
+		return
+	}
+
+	// Validate CI type data using the validator
+	if validationError := h.validateCIType(updatedType); validationError != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(models.GetHTTPStatusForError(models.ErrorTypeValidation))
+		json.NewEncoder(w).Encode(validationError)
+		return
+	}
+
+	// Renaming a type would orphan the CIs that reference it
+	if updatedType.Name != existingType.Name {
+		count, err := h.ciRepo.Count(r.Context(), repositories.CIFilter{Type: existingType.Name})
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to check CI type usage", nil)
+			return
+		}
+		if count > 0 {
+			middleware.RespondWithConflictError(w, "CI type is in use and cannot be renamed", map[string]interface{}{"ci_count": count})
+			return
+		}
+		taken, err := h.nameTaken(r, updatedType.Name, existingType.ID)
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to check CI type name", nil)
+			return
+		}
+		if taken {
+			middleware.RespondWithConflictError(w, "CI type already exists", nil)
+			return
+		}
+	}
+
+	// Update the CI type
+	existingType.Name = updatedType.Name
+	existingType.Description = updatedType.Description
+	if updatedType.Schema != nil {
+		existingType.Schema = updatedType.Schema
+	}
+	existingType.UpdatedAt = time.Now()
+
+	// Save the CI type and its audit log in one transaction
+	err = h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		if err := tx.CITypes.Update(r.Context(), existingType); err != nil {
+			return err
+		}
+
+		return tx.AuditLogs.Create(r.Context(), &models.AuditLog{
+			ID:         uuid.New(),
+			EntityType: "ci_type",
+			EntityID:   existingType.ID,
+			Action:     "update",
+			ChangedBy:  username,
+			ChangedAt:  time.Now(),
+			Details:    models.JSONBMap{"name": existingType.Name, "schema": existingType.Schema},
+		})
+	})
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to update CI type", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingType)
+}
+
+// DeleteCIType handles deleting a CI type
+// @Summary Delete a CI type
+// @Description Delete a CI type that is not used by any CI
+// @Tags ci-types
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "CI type ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /ci-types/{id} [delete]
+func (h *CITypeHandler) DeleteCIType(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	// Extract ID from URL parameters
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		middleware.RespondWithValidationError(w, "ID parameter is required", nil)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid ID format", nil)
+		return
+	}
+
+	// Get the CI type
+	ciType, err := h.ciTypeRepo.GetByID(r.Context(), id)
+	if err != nil {
+		middleware.RespondWithError(w, models.ErrorTypeCITypeNotFound, "CI type not found", nil)
+		return
+	}
+
+	// Refuse to delete a type that CIs still reference
+	count, err := h.ciRepo.Count(r.Context(), repositories.CIFilter{Type: ciType.Name})
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to check CI type usage", nil)
+		return
+	}
+	if count > 0 {
+		middleware.RespondWithConflictError(w, "CI type is in use and cannot be deleted", map[string]interface{}{"ci_count": count})
+		return
+	}
+
+	// Delete the CI type and record it in one transaction
+	err = h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		if err := tx.CITypes.Delete(r.Context(), id); err != nil {
+			return err
+		}
+
+		return tx.AuditLogs.Create(r.Context(), &models.AuditLog{
+			ID:         uuid.New(),
+			EntityType: "ci_type",
+			EntityID:   ciType.ID,
+			Action:     "delete",
+			ChangedBy:  username,
+			ChangedAt:  time.Now(),
+			Details:    models.JSONBMap{"name": ciType.Name},
+		})
+	})
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to delete CI type", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "CI type deleted successfully"})
+}
+
+// validateCIType validates the CI type fields and checks that its schema compiles
+func (h *CITypeHandler) validateCIType(ciType models.CIType) *models.ErrorResponse {
+	if validationError := h.validator.Validate(ciType); validationError != nil {
+		return validationError
+	}
+	return h.validator.ValidateSchema(ciType.Schema)
+}
+
+// nameTaken reports whether another CI type already uses the name, ignoring case
+func (h *CITypeHandler) nameTaken(r *http.Request, name string, exceptID uuid.UUID) (bool, error) {
+	ciTypes, err := h.ciTypeRepo.GetAll(r.Context())
+	if err != nil {
+		return false, err
+	}
+
+	for _, ciType := range ciTypes {
+		if ciType.ID != exceptID && strings.EqualFold(ciType.Name, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
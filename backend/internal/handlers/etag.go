@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cmdb-lite/backend/internal/middleware"
+)
+
+// preconditionFailedMessage is the message of 412 responses to requests made against an
+// outdated version
+const preconditionFailedMessage = "Precondition failed: the resource has been modified"
+
+// setETag sets the ETag header to the version of the returned CI or relationship
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", formatETag(version))
+}
+
+// formatETag returns the strong entity tag of a version, e.g. "3"
+func formatETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// checkIfMatch checks the If-Match header of a request that modifies an entity against the
+// entity's current version. It writes a 428 response if the header is missing and a 412
+// response if no listed tag matches, and returns whether the request may proceed. "*" matches
+// any version and weak tags never match, as If-Match requires strong comparison.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, version int) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		middleware.RespondWithPreconditionRequiredError(w, "If-Match header is required", nil)
+		return false
+	}
+
+	current := formatETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+
+	w.Header().Set("ETag", current)
+	middleware.RespondWithPreconditionFailedError(w, preconditionFailedMessage, map[string]interface{}{
+		"current_version": version,
+	})
+	return false
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/events"
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+)
+
+// keepAliveInterval is how often an idle stream sends a comment, so that proxies keep the
+// connection open
+const keepAliveInterval = 15 * time.Second
+
+// replayBatchSize is the number of missed events read at once when a client resumes
+const replayBatchSize = 500
+
+// streamEntityTypes are the entity types each role may stream
+var streamEntityTypes = map[string][]string{
+	"admin":  events.EntityTypes,
+	"viewer": {events.EntityTypeCI, events.EntityTypeRelationship},
+}
+
+// EventHandler handles HTTP requests for the change feed
+type EventHandler struct {
+	eventRepo repositories.EventRepository
+	hub       *events.Hub
+}
+
+// NewEventHandler creates a new EventHandler
+func NewEventHandler(eventRepo repositories.EventRepository, hub *events.Hub) *EventHandler {
+	return &EventHandler{
+		eventRepo: eventRepo,
+		hub:       hub,
+	}
+}
+
+// StreamEvents handles streaming the change feed
+// @Summary Stream changes
+// @Description Stream the changes to CIs, relationships and users as Server-Sent Events. Each event's id is its
+// @Description sequence number; a client that sends it back in Last-Event-ID, or in last_event_id, first receives
+// @Description the events it missed. CI types and tags only filter CI events. Viewers cannot stream user events.
+// @Description The stream ends with a token_expired event when the access token expires.
+// @Tags events
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param entity_types query string false "Comma-separated entity types: configuration_item, relationship, user"
+// @Param ci_types query string false "Comma-separated CI types"
+// @Param tags query string false "Comma-separated tags, any of which a CI must have"
+// @Param last_event_id query int false "Sequence number of the last event received"
+// @Param Last-Event-ID header int false "Sequence number of the last event received"
+// @Success 200 {string} string "Server-Sent Events"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /events/stream [get]
+func (h *EventHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	// Get the user from the context
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	// Restrict the stream to the entity types the user's role may read
+	allowed, ok := streamEntityTypes[claims.Role]
+	if !ok {
+		middleware.RespondWithForbiddenError(w, "Insufficient permissions", nil)
+		return
+	}
+	query := r.URL.Query()
+	filter := events.Filter{
+		EntityTypes: splitList(query.Get("entity_types")),
+		CITypes:     splitList(query.Get("ci_types")),
+		Tags:        splitList(query.Get("tags")),
+	}
+	for _, entityType := range filter.EntityTypes {
+		if !containsString(events.EntityTypes, entityType) {
+			middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+				"entity_types": []string{"entity_types must be some of: configuration_item relationship user"},
+			})
+			return
+		}
+		if !containsString(allowed, entityType) {
+			middleware.RespondWithForbiddenError(w, fmt.Sprintf("Role %s cannot stream %s events", claims.Role, entityType), nil)
+			return
+		}
+	}
+	if len(filter.EntityTypes) == 0 {
+		filter.EntityTypes = allowed
+	}
+
+	// Browsers send the last event's ID in the header when they reconnect
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("last_event_id")
+	}
+	var lastSeq int64
+	resume := lastEventID != ""
+	if resume {
+		seq, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || seq < 0 {
+			middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+				"last_event_id": []string{"last_event_id must be the id of an event"},
+			})
+			return
+		}
+		lastSeq = seq
+	}
+
+	// The stream outlives any write timeout of the server
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		middleware.RespondWithInternalError(w, "Failed to start stream", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	stream := &eventStream{w: w, rc: rc, filter: filter}
+
+	// Replay the missed events before subscribing, then once more to cover the events
+	// recorded in between; the events of the second replay may also arrive live
+	sent := map[int64]bool{}
+	if resume {
+		var err error
+		if lastSeq, err = h.replay(r.Context(), stream, lastSeq, nil); err != nil {
+			return
+		}
+	}
+	subscription := h.hub.Subscribe(filter)
+	defer h.hub.Unsubscribe(subscription)
+	if resume {
+		if _, err := h.replay(r.Context(), stream, lastSeq, sent); err != nil {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	// End the stream when the access token expires, so that a revoked role stops streaming
+	var expired <-chan time.Time
+	if claims.ExpiresAt != nil {
+		timer := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-subscription.Events():
+			// The subscription is closed when the client fell behind or the server is
+			// shutting down; the client reconnects and resumes
+			if !ok {
+				return
+			}
+			if sent[event.Seq] {
+				delete(sent, event.Seq)
+				continue
+			}
+			if err := stream.send(event); err != nil {
+				return
+			}
+
+		case <-keepAlive.C:
+			if err := stream.comment("keep-alive"); err != nil {
+				return
+			}
+
+		case <-expired:
+			stream.named("token_expired", map[string]string{"message": "Access token expired"})
+			return
+		}
+	}
+}
+
+// replay sends the events after a sequence number that match the stream's filter and returns
+// the sequence number of the last event read. The numbers of the events sent are added to
+// sent unless it is nil.
+func (h *EventHandler) replay(ctx context.Context, stream *eventStream, afterSeq int64, sent map[int64]bool) (int64, error) {
+	for {
+		batch, err := h.eventRepo.ListAfter(ctx, afterSeq, replayBatchSize)
+		if err != nil {
+			stream.named("error", map[string]string{"message": "Failed to replay events"})
+			return afterSeq, err
+		}
+
+		for _, event := range batch {
+			afterSeq = event.Seq
+			if !stream.filter.Matches(event) {
+				continue
+			}
+			if err := stream.send(event); err != nil {
+				return afterSeq, err
+			}
+			if sent != nil {
+				sent[event.Seq] = true
+			}
+		}
+
+		if len(batch) < replayBatchSize {
+			return afterSeq, nil
+		}
+	}
+}
+
+// eventStream writes Server-Sent Events to a client
+type eventStream struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	filter events.Filter
+}
+
+// send writes an event of the change feed with its sequence number as the event ID
+func (s *eventStream) send(event *models.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.write(fmt.Sprintf("id: %d\ndata: %s\n\n", event.Seq, data))
+}
+
+// named writes an event of the stream itself, with a name and no ID
+func (s *eventStream) named(name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.write(fmt.Sprintf("event: %s\ndata: %s\n\n", name, data))
+}
+
+// comment writes a comment, which clients ignore
+func (s *eventStream) comment(text string) error {
+	return s.write(": " + text + "\n\n")
+}
+
+// write writes a message and flushes it to the client
+func (s *eventStream) write(message string) error {
+	if _, err := s.w.Write([]byte(message)); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
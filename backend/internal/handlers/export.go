@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cmdb-lite/backend/internal/csvimport"
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"gopkg.in/yaml.v3"
+)
+
+// Media types of the export formats list endpoints serve when the Accept header asks for them
+const (
+	csvMediaType    = "text/csv"
+	ndjsonMediaType = "application/x-ndjson"
+	yamlMediaType   = "application/yaml"
+)
+
+// exportFormat returns the export media type the Accept header of a request prefers, or "" if
+// it prefers JSON or names no export format. Among equally preferred types the first wins.
+func exportFormat(r *http.Request) string {
+	best, bestQuality := "", 0.0
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case "application/json", "application/*", "*/*":
+			mediaType = ""
+		case csvMediaType, ndjsonMediaType, yamlMediaType:
+		default:
+			continue
+		}
+
+		if quality > bestQuality {
+			best, bestQuality = mediaType, quality
+		}
+	}
+	return best
+}
+
+// exportTable is the layout of a CSV export: the entity's JSON fields in order, followed by a
+// column for each dotted path in the JSON object of jsonField, e.g. attributes.os.name
+type exportTable struct {
+	fields    []string
+	jsonField string
+	paths     []string
+}
+
+// exporter writes entities to a response one at a time in an export format. Nothing is written
+// until the first entity or close, so a failure before then can still get an error response.
+type exporter struct {
+	w         http.ResponseWriter
+	mediaType string
+	table     exportTable
+	csv       *csv.Writer
+	started   bool
+	count     int
+}
+
+// newExporter creates an exporter writing to w in the media type. table is only used for CSV.
+func newExporter(w http.ResponseWriter, mediaType string, table exportTable) *exporter {
+	return &exporter{w: w, mediaType: mediaType, table: table}
+}
+
+// start sets the Content-Type header and writes the CSV header row
+func (e *exporter) start() error {
+	e.started = true
+	if e.mediaType != csvMediaType {
+		e.w.Header().Set("Content-Type", e.mediaType)
+		return nil
+	}
+
+	e.w.Header().Set("Content-Type", csvMediaType+"; charset=utf-8")
+	e.csv = csv.NewWriter(e.w)
+	header := append([]string{}, e.table.fields...)
+	for _, path := range e.table.paths {
+		header = append(header, e.table.jsonField+"."+path)
+	}
+	return e.csv.Write(header)
+}
+
+// write writes an entity
+func (e *exporter) write(entity interface{}) error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	e.count++
+
+	if e.mediaType == ndjsonMediaType {
+		return json.NewEncoder(e.w).Encode(entity)
+	}
+
+	value, err := toJSONObject(entity)
+	if err != nil {
+		return err
+	}
+
+	if e.mediaType == csvMediaType {
+		return e.csv.Write(e.csvRecord(value))
+	}
+	return e.writeYAMLItem(value)
+}
+
+// close finishes the export
+func (e *exporter) close() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	switch e.mediaType {
+	case csvMediaType:
+		e.csv.Flush()
+		return e.csv.Error()
+	case yamlMediaType:
+		if e.count == 0 {
+			_, err := e.w.Write([]byte("[]\n"))
+			return err
+		}
+	}
+	return nil
+}
+
+// finish closes the export, or responds with the error message if err is set or closing
+// fails. Once the response has started an error can only be logged, and the client is left
+// with a truncated body.
+func (e *exporter) finish(err error, message string) {
+	if err == nil {
+		err = e.close()
+	}
+	if err == nil {
+		return
+	}
+
+	if !e.started {
+		middleware.RespondWithInternalError(e.w, message, nil)
+		return
+	}
+	log.Printf("%s: %v", message, err)
+}
+
+// csvRecord returns the cells of an entity. Arrays in the entity's fields, like tags, are
+// joined with the CSV import's tag separator so that an export can be imported again; other
+// arrays and objects are written as JSON.
+func (e *exporter) csvRecord(value map[string]interface{}) []string {
+	record := make([]string, 0, len(e.table.fields)+len(e.table.paths))
+	for _, field := range e.table.fields {
+		if items, ok := value[field].([]interface{}); ok {
+			cells := make([]string, len(items))
+			for i, item := range items {
+				cells[i] = csvCell(item)
+			}
+			record = append(record, strings.Join(cells, csvimport.DefaultTagSeparator))
+			continue
+		}
+		record = append(record, csvCell(value[field]))
+	}
+
+	object, _ := value[e.table.jsonField].(map[string]interface{})
+	for _, path := range e.table.paths {
+		record = append(record, csvCell(lookupPath(object, path)))
+	}
+	return record
+}
+
+// writeYAMLItem writes an entity as an item of the top-level YAML sequence
+func (e *exporter) writeYAMLItem(value map[string]interface{}) error {
+	var data bytes.Buffer
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(yamlValue(value)); err != nil {
+		return err
+	}
+
+	// Indent the entity's mapping under the item's dash
+	var item bytes.Buffer
+	for i, line := range strings.Split(strings.TrimSuffix(data.String(), "\n"), "\n") {
+		switch {
+		case i == 0:
+			item.WriteString("- ")
+		case line != "":
+			item.WriteString("  ")
+		}
+		item.WriteString(line)
+		item.WriteString("\n")
+	}
+
+	_, err := e.w.Write(item.Bytes())
+	return err
+}
+
+// toJSONObject returns an entity as the object it is encoded to in JSON, with numbers kept as
+// json.Number so that they are written exactly
+func toJSONObject(entity interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value map[string]interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// csvCell formats a JSON value as a CSV cell. null is an empty cell.
+func csvCell(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
+
+// lookupPath returns the value at a dotted path in a JSON object, or nil if there is none
+func lookupPath(object map[string]interface{}, path string) interface{} {
+	var value interface{} = object
+	for _, key := range strings.Split(path, ".") {
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = child[key]
+	}
+	return value
+}
+
+// yamlValue converts the json.Number values in a JSON value to integers or floats, which YAML
+// writes as numbers rather than strings
+func yamlValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return n
+		}
+		if f, err := value.Float64(); err == nil {
+			return f
+		}
+		return value.String()
+	case map[string]interface{}:
+		for key, child := range value {
+			value[key] = yamlValue(child)
+		}
+		return value
+	case []interface{}:
+		for i, child := range value {
+			value[i] = yamlValue(child)
+		}
+		return value
+	default:
+		return value
+	}
+}
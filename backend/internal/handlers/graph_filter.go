@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/cmdb-lite/backend/internal/repositories"
+)
+
+// parseGraphFilter builds a GraphFilter from the graph query parameters. Invalid parameters
+// are reported per parameter name in the same shape the validator uses.
+func parseGraphFilter(query url.Values) (repositories.GraphFilter, map[string]interface{}) {
+	filter := repositories.GraphFilter{
+		Depth:             1,
+		Direction:         repositories.GraphDirectionBoth,
+		RelationshipTypes: splitList(query.Get("rel_types")),
+		CITypes:           splitList(query.Get("ci_types")),
+	}
+	errors := make(map[string]interface{})
+
+	if depthStr := query.Get("depth"); depthStr != "" {
+		depth, err := strconv.Atoi(depthStr)
+		if err != nil || depth < 1 || depth > repositories.MaxGraphDepth {
+			errors["depth"] = []string{fmt.Sprintf("depth must be an integer between 1 and %d", repositories.MaxGraphDepth)}
+		} else {
+			filter.Depth = depth
+		}
+	}
+
+	filter.RelationshipAttributes = parseAttributeParams(query, relationshipAttributeParamPrefix, errors)
+	filter.AsOf = parseAsOf(query, errors)
+
+	switch direction := query.Get("direction"); direction {
+	case "", repositories.GraphDirectionBoth:
+	case repositories.GraphDirectionIn, repositories.GraphDirectionOut:
+		filter.Direction = direction
+	default:
+		errors["direction"] = []string{"direction must be one of: in out both"}
+	}
+
+	if len(errors) > 0 {
+		return filter, errors
+	}
+	return filter, nil
+}
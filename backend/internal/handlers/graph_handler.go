@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/cmdb-lite/backend/internal/graph"
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// GraphHandler handles HTTP requests for queries over the relationship graph
+type GraphHandler struct {
+	ciRepo      repositories.CIRepository
+	relRepo     repositories.RelationshipRepository
+	relTypeRepo repositories.RelationshipTypeRepository
+}
+
+// NewGraphHandler creates a new GraphHandler
+func NewGraphHandler(
+	ciRepo repositories.CIRepository,
+	relRepo repositories.RelationshipRepository,
+	relTypeRepo repositories.RelationshipTypeRepository,
+) *GraphHandler {
+	return &GraphHandler{
+		ciRepo:      ciRepo,
+		relRepo:     relRepo,
+		relTypeRepo: relTypeRepo,
+	}
+}
+
+// cycleEntry is a cycle of one relationship type in the cycles response
+type cycleEntry struct {
+	Type string `json:"type"`
+	*graph.Cycle
+}
+
+// GetPaths handles finding the relationship chains between two CIs
+// @Summary Find paths between CIs
+// @Description Find the relationship chains between two CIs, either every shortest path or every path
+// @Description that visits no CI twice, up to max_depth relationships long
+// @Tags graph
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param from query string true "Source CI ID"
+// @Param to query string true "Target CI ID"
+// @Param max_depth query int false "Maximum path length (1-10)" default(6)
+// @Param mode query string false "shortest or all" default(shortest)
+// @Param direction query string false "Follow relationships from source to target (out), the reverse (in) or ignore direction (both)" default(both)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /graph/paths [get]
+func (h *GraphHandler) GetPaths(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	errors := make(map[string]interface{})
+
+	// Parse the endpoints
+	var from, to uuid.UUID
+	for param, target := range map[string]*uuid.UUID{"from": &from, "to": &to} {
+		value := query.Get(param)
+		if value == "" {
+			errors[param] = []string{param + " is required"}
+			continue
+		}
+		id, err := uuid.Parse(value)
+		if err != nil {
+			errors[param] = []string{param + " must be a valid UUID"}
+			continue
+		}
+		*target = id
+	}
+
+	// Parse search options
+	opts := graph.PathOptions{
+		Mode:      graph.ModeShortest,
+		Direction: graph.DirectionBoth,
+		MaxDepth:  graph.DefaultPathDepth,
+	}
+
+	if depthStr := query.Get("max_depth"); depthStr != "" {
+		depth, err := strconv.Atoi(depthStr)
+		if err != nil || depth < 1 || depth > graph.MaxPathDepth {
+			errors["max_depth"] = []string{fmt.Sprintf("max_depth must be an integer between 1 and %d", graph.MaxPathDepth)}
+		} else {
+			opts.MaxDepth = depth
+		}
+	}
+
+	switch mode := query.Get("mode"); mode {
+	case "", graph.ModeShortest:
+	case graph.ModeAll:
+		opts.Mode = mode
+	default:
+		errors["mode"] = []string{"mode must be one of: shortest all"}
+	}
+
+	switch direction := query.Get("direction"); direction {
+	case "", graph.DirectionBoth:
+	case graph.DirectionOut, graph.DirectionIn:
+		opts.Direction = direction
+	default:
+		errors["direction"] = []string{"direction must be one of: in out both"}
+	}
+
+	relAttributes := parseAttributeParams(query, relationshipAttributeParamPrefix, errors)
+
+	if len(errors) > 0 {
+		middleware.RespondWithValidationError(w, "Invalid query parameters", errors)
+		return
+	}
+
+	// Check if both CIs exist
+	if _, err := h.ciRepo.GetByID(r.Context(), from); err != nil {
+		middleware.RespondWithNotFoundError(w, "Source CI not found", nil)
+		return
+	}
+	if _, err := h.ciRepo.GetByID(r.Context(), to); err != nil {
+		middleware.RespondWithNotFoundError(w, "Target CI not found", nil)
+		return
+	}
+
+	// Search the graph
+	outgoing := graph.WithAttributes(h.relRepo.GetBySourceCI, relAttributes)
+	incoming := graph.WithAttributes(h.relRepo.GetByTargetCI, relAttributes)
+	result, err := graph.Paths(r.Context(), from, to, outgoing, incoming, opts)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to find paths", nil)
+		return
+	}
+
+	// Load every CI on the paths
+	seen := make(map[uuid.UUID]bool)
+	var ids []uuid.UUID
+	for _, path := range result.Paths {
+		for _, id := range path.CIs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	nodes := []*models.CI{}
+	if len(ids) > 0 {
+		nodes, err = h.ciRepo.List(r.Context(), repositories.CIFilter{IDs: ids})
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to find paths", nil)
+			return
+		}
+	}
+
+	// Create response
+	response := map[string]interface{}{
+		"from":      from,
+		"to":        to,
+		"mode":      opts.Mode,
+		"direction": opts.Direction,
+		"max_depth": opts.MaxDepth,
+		"nodes":     nodes,
+		"paths":     result.Paths,
+		"truncated": result.Truncated,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetCycles handles reporting the cycles in the relationship graph
+// @Summary Find relationship cycles
+// @Description Find the directed cycles formed by relationships of each type. CIs that share cycles are
+// @Description reported once as a group, with a shortest cycle through the group's first CI.
+// @Tags graph
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param rel_types query string false "Comma-separated relationship types to check (defaults to the acyclic types)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /graph/cycles [get]
+func (h *GraphHandler) GetCycles(w http.ResponseWriter, r *http.Request) {
+	// Check the requested types, or every acyclic type
+	relTypes := splitList(r.URL.Query().Get("rel_types"))
+	if len(relTypes) == 0 {
+		registered, err := h.relTypeRepo.GetAll(r.Context())
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to find cycles", nil)
+			return
+		}
+		relTypes = []string{}
+		for _, relType := range registered {
+			if relType.Acyclic {
+				relTypes = append(relTypes, relType.Name)
+			}
+		}
+	}
+
+	// Cycles are found within each type, since only same-typed cycles break an acyclic type
+	cycles := []*cycleEntry{}
+	seen := make(map[uuid.UUID]bool)
+	var ids []uuid.UUID
+	for _, relType := range relTypes {
+		relationships, err := h.relRepo.GetByType(r.Context(), relType)
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to find cycles", nil)
+			return
+		}
+		for _, cycle := range graph.FindCycles(relationships) {
+			cycles = append(cycles, &cycleEntry{Type: relType, Cycle: cycle})
+			for _, id := range cycle.CIs {
+				if !seen[id] {
+					seen[id] = true
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+
+	// Load every CI in a cycle
+	nodes := []*models.CI{}
+	if len(ids) > 0 {
+		var err error
+		nodes, err = h.ciRepo.List(r.Context(), repositories.CIFilter{IDs: ids})
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to find cycles", nil)
+			return
+		}
+	}
+
+	// Create response
+	response := map[string]interface{}{
+		"relationship_types": relTypes,
+		"total":              len(cycles),
+		"cycles":             cycles,
+		"nodes":              nodes,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/cmdb-lite/backend/internal/history"
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// HistoryHandler handles HTTP requests for the version history of CIs
+type HistoryHandler struct {
+	auditRepo repositories.AuditLogRepository
+}
+
+// NewHistoryHandler creates a new HistoryHandler
+func NewHistoryHandler(auditRepo repositories.AuditLogRepository) *HistoryHandler {
+	return &HistoryHandler{
+		auditRepo: auditRepo,
+	}
+}
+
+// GetCIHistory handles retrieving the versions of a CI
+// @Summary Get CI history
+// @Description Get every recorded version of a CI, oldest first, with the field changes of each version.
+// @Description The history outlives the CI, so deleted CIs can still be inspected.
+// @Tags cis
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "CI ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cis/{id}/history [get]
+func (h *HistoryHandler) GetCIHistory(w http.ResponseWriter, r *http.Request) {
+	id, versions, ok := h.loadVersions(w, r)
+	if !ok {
+		return
+	}
+
+	// Create response
+	response := map[string]interface{}{
+		"ci_id":    id,
+		"total":    len(versions),
+		"versions": versions,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetCIVersion handles rebuilding a CI as it was at a version
+// @Summary Get CI version
+// @Description Rebuild a CI as it was after version n by replaying its history. For a delete or purge version the
+// @Description CI is returned as it was when deleted, with deleted set.
+// @Tags cis
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "CI ID"
+// @Param n path int true "Version number, starting at 1"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cis/{id}/versions/{n} [get]
+func (h *HistoryHandler) GetCIVersion(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(mux.Vars(r)["n"])
+	if err != nil || n < 1 {
+		middleware.RespondWithValidationError(w, "Version must be a positive integer", nil)
+		return
+	}
+
+	id, versions, ok := h.loadVersions(w, r)
+	if !ok {
+		return
+	}
+	if n > len(versions) {
+		middleware.RespondWithNotFoundError(w, "Version not found", map[string]interface{}{"latest_version": len(versions)})
+		return
+	}
+	version := versions[n-1]
+
+	// Create response
+	response := map[string]interface{}{
+		"version":    version.Version,
+		"action":     version.Action,
+		"changed_by": version.ChangedBy,
+		"changed_at": version.ChangedAt,
+		"deleted":    version.Deleted(),
+		"ci":         history.Rebuild(id, versions, n),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// loadVersions reads the CI ID from the URL and loads its versions, writing an error
+// response and returning false if that fails or the CI has no history
+func (h *HistoryHandler) loadVersions(w http.ResponseWriter, r *http.Request) (uuid.UUID, []*history.Version, bool) {
+	// Extract ID from URL parameters
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		middleware.RespondWithValidationError(w, "ID parameter is required", nil)
+		return uuid.Nil, nil, false
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid ID format", nil)
+		return uuid.Nil, nil, false
+	}
+
+	// Get the audit logs of the CI
+	logs, err := h.auditRepo.List(r.Context(), repositories.AuditLogFilter{EntityType: "configuration_item", EntityID: id})
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get CI history", nil)
+		return uuid.Nil, nil, false
+	}
+	if len(logs) == 0 {
+		middleware.RespondWithNotFoundError(w, "CI history not found", nil)
+		return uuid.Nil, nil, false
+	}
+
+	return id, history.Versions(logs), true
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/cmdb-lite/backend/internal/graph"
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ImpactHandler handles HTTP requests for impact analysis
+type ImpactHandler struct {
+	ciRepo          repositories.CIRepository
+	relRepo         repositories.RelationshipRepository
+	dependencyTypes []string
+}
+
+// NewImpactHandler creates a new ImpactHandler. dependencyTypes are the relationship types
+// followed when no rel_types parameter is given; when empty, graph.DefaultDependencyTypes are
+// followed.
+func NewImpactHandler(
+	ciRepo repositories.CIRepository,
+	relRepo repositories.RelationshipRepository,
+	dependencyTypes []string,
+) *ImpactHandler {
+	if len(dependencyTypes) == 0 {
+		dependencyTypes = graph.DefaultDependencyTypes
+	}
+	return &ImpactHandler{
+		ciRepo:          ciRepo,
+		relRepo:         relRepo,
+		dependencyTypes: dependencyTypes,
+	}
+}
+
+// impactEntry is an affected CI in the impact response
+type impactEntry struct {
+	CI            *models.CI  `json:"ci"`
+	Distance      int         `json:"distance"`
+	Path          []uuid.UUID `json:"path"`
+	Relationships []uuid.UUID `json:"relationships"`
+}
+
+// GetCIImpact handles impact analysis for a CI
+// @Summary Get CI impact
+// @Description Find every CI that is transitively affected when this CI goes down, by following dependency
+// @Description relationships (source depends on target) in reverse. Affected CIs are grouped by CI type and
+// @Description carry the path from this CI and its hop distance.
+// @Tags cis
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "CI ID"
+// @Param rel_types query string false "Comma-separated dependency relationship types (defaults to the configured types)"
+// @Param depth query int false "Maximum number of hops (1-20)" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cis/{id}/impact [get]
+func (h *ImpactHandler) GetCIImpact(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL parameters
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		middleware.RespondWithValidationError(w, "ID parameter is required", nil)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid ID format", nil)
+		return
+	}
+
+	// Parse analysis parameters
+	opts := graph.ImpactOptions{DependencyTypes: h.dependencyTypes}
+	if relTypes := splitList(r.URL.Query().Get("rel_types")); len(relTypes) > 0 {
+		opts.DependencyTypes = relTypes
+	}
+	if depthStr := r.URL.Query().Get("depth"); depthStr != "" {
+		depth, err := strconv.Atoi(depthStr)
+		if err != nil || depth < 1 || depth > graph.MaxImpactDepth {
+			middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+				"depth": []string{fmt.Sprintf("depth must be an integer between 1 and %d", graph.MaxImpactDepth)},
+			})
+			return
+		}
+		opts.MaxDepth = depth
+	}
+
+	errors := make(map[string]interface{})
+	relAttributes := parseAttributeParams(r.URL.Query(), relationshipAttributeParamPrefix, errors)
+	if len(errors) > 0 {
+		middleware.RespondWithValidationError(w, "Invalid query parameters", errors)
+		return
+	}
+
+	// Check if the CI exists
+	if _, err := h.ciRepo.GetByID(r.Context(), id); err != nil {
+		middleware.RespondWithNotFoundError(w, "CI not found", nil)
+		return
+	}
+
+	// Walk the dependencies in reverse, one query per level
+	incoming := func(ctx context.Context, ciIDs []uuid.UUID) ([]*models.Relationship, error) {
+		return h.relRepo.GetByTargetCIs(ctx, ciIDs, opts.DependencyTypes, relAttributes)
+	}
+	result, err := graph.Impact(r.Context(), id, incoming, opts)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to analyse impact", nil)
+		return
+	}
+
+	// Load the affected CIs
+	cisByID := make(map[uuid.UUID]*models.CI)
+	if len(result.Impacted) > 0 {
+		ids := make([]uuid.UUID, len(result.Impacted))
+		for i, impacted := range result.Impacted {
+			ids[i] = impacted.ID
+		}
+		cis, err := h.ciRepo.List(r.Context(), repositories.CIFilter{IDs: ids})
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to analyse impact", nil)
+			return
+		}
+		for _, ci := range cis {
+			cisByID[ci.ID] = ci
+		}
+	}
+
+	// Group the affected CIs by type, nearest first
+	groups := make(map[string][]*impactEntry)
+	for _, impacted := range result.Impacted {
+		ci, ok := cisByID[impacted.ID]
+		if !ok {
+			// Deleted while the analysis was running
+			continue
+		}
+		groups[ci.Type] = append(groups[ci.Type], &impactEntry{
+			CI:            ci,
+			Distance:      impacted.Distance,
+			Path:          impacted.Path,
+			Relationships: impacted.Relationships,
+		})
+	}
+	total := 0
+	for _, entries := range groups {
+		sort.SliceStable(entries, func(i, j int) bool {
+			if entries[i].Distance != entries[j].Distance {
+				return entries[i].Distance < entries[j].Distance
+			}
+			return entries[i].CI.Name < entries[j].CI.Name
+		})
+		total += len(entries)
+	}
+
+	// Create response
+	response := map[string]interface{}{
+		"root_id":            id,
+		"relationship_types": opts.DependencyTypes,
+		"total":              total,
+		"truncated":          result.Truncated,
+		"groups":             groups,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
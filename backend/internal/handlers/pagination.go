@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/cmdb-lite/backend/internal/pagination"
+)
+
+// pageRequest holds the pagination parameters shared by the list endpoints. Either Page or
+// Cursor is used: a cursor takes precedence over the page number.
+type pageRequest struct {
+	Page   int
+	Limit  int
+	Cursor *pagination.Cursor
+}
+
+// parsePageRequest reads the page, limit and cursor query parameters
+func parsePageRequest(query url.Values) (pageRequest, error) {
+	// Set default values
+	req := pageRequest{Page: 1, Limit: 10}
+
+	// Parse page parameter
+	if pageStr := query.Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			req.Page = p
+		}
+	}
+
+	// Parse limit parameter
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			req.Limit = l
+		}
+	}
+
+	// Parse cursor parameter
+	if token := query.Get("cursor"); token != "" {
+		cursor, err := pagination.Decode(token)
+		if err != nil {
+			return req, err
+		}
+		req.Cursor = &cursor
+	}
+
+	return req, nil
+}
+
+// Offset returns the number of rows to skip in page-number mode
+func (p pageRequest) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// FetchLimit returns how many rows to request from the repository. Cursor mode fetches one
+// extra row to find out whether another page exists.
+func (p pageRequest) FetchLimit() int {
+	if p.Cursor != nil {
+		return p.Limit + 1
+	}
+	return p.Limit
+}
+
+// paginate trims the look-ahead row from a cursor page and builds the pagination object for
+// the response. Items must be ordered newest first; key returns an item's keyset position.
+// When keyset is false the items are not in keyset order, no cursors are returned and key
+// may be nil.
+func paginate[T any](items []T, req pageRequest, total int, keyset bool, key func(T) pagination.Cursor) ([]T, map[string]interface{}) {
+	var hasNewer, hasOlder bool
+
+	switch {
+	case req.Cursor == nil:
+		hasNewer = req.Page > 1
+		hasOlder = req.Offset()+len(items) < total
+	case req.Cursor.Backward:
+		hasOlder = true
+		if len(items) > req.Limit {
+			hasNewer = true
+			items = items[len(items)-req.Limit:]
+		}
+	default:
+		hasNewer = true
+		if len(items) > req.Limit {
+			hasOlder = true
+			items = items[:req.Limit]
+		}
+	}
+
+	if items == nil {
+		items = []T{}
+	}
+
+	info := map[string]interface{}{
+		"limit":       req.Limit,
+		"total":       total,
+		"next_cursor": nil,
+		"prev_cursor": nil,
+	}
+	if req.Cursor == nil {
+		info["page"] = req.Page
+	}
+
+	if keyset && len(items) > 0 {
+		if hasOlder {
+			info["next_cursor"] = pagination.Encode(key(items[len(items)-1]))
+		}
+		if hasNewer {
+			first := key(items[0])
+			first.Backward = true
+			info["prev_cursor"] = pagination.Encode(first)
+		}
+	}
+
+	return items, info
+}
@@ -1,40 +1,53 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/cmdb-lite/backend/internal/graph"
 	"github.com/cmdb-lite/backend/internal/middleware"
 	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/pagination"
 	"github.com/cmdb-lite/backend/internal/repositories"
 	"github.com/cmdb-lite/backend/internal/validation"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
+// errInvalidRelationship is returned inside a write transaction when the relationship breaks
+// the rules of its type
+var errInvalidRelationship = errors.New("relationship breaks the rules of its type")
+
 // RelationshipHandler handles HTTP requests for relationships
 type RelationshipHandler struct {
 	relRepo   repositories.RelationshipRepository
-	auditRepo repositories.AuditLogRepository
+	uow       repositories.UnitOfWork
 	validator *validation.Validator
 }
 
 // NewRelationshipHandler creates a new RelationshipHandler
 func NewRelationshipHandler(
 	relRepo repositories.RelationshipRepository,
-	auditRepo repositories.AuditLogRepository,
+	uow repositories.UnitOfWork,
 ) *RelationshipHandler {
 	return &RelationshipHandler{
 		relRepo:   relRepo,
-		auditRepo: auditRepo,
+		uow:       uow,
 		validator: validation.NewValidator(),
 	}
 }
 
 // CreateRelationship handles the creation of a new relationship
 // @Summary Create a new relationship
-// @Description Create a new relationship between CIs
+// @Description Create a new relationship between CIs. The type must be a registered relationship type or its
+// @Description inverse name, in which case the relationship is stored in the canonical direction. The type's
+// @Description allowed CI types, cardinality and acyclicity are enforced.
 // @Tags relationships
 // @Accept json
 // @Produce json
@@ -72,36 +85,60 @@ func (h *RelationshipHandler) CreateRelationship(w http.ResponseWriter, r *http.
 	if relationship.ID == uuid.Nil {
 		relationship.ID = uuid.New()
 	}
-	relationship.CreatedAt = time.Now()
-
-	// Create the relationship
-	if err := h.relRepo.Create(r.Context(), &relationship); err != nil {
+	if relationship.Attributes == nil {
+		relationship.Attributes = models.JSONBMap{}
+	}
+	now := time.Now()
+	relationship.CreatedAt = now
+	relationship.UpdatedAt = now
+
+	// Enforce the rules of the relationship type, then create the relationship and its audit
+	// log in one transaction
+	var validationError *models.ErrorResponse
+	err := h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		var err error
+		validationError, err = validateRelationshipType(r.Context(), tx, &relationship, uuid.Nil)
+		if err != nil {
+			return err
+		}
+		if validationError != nil {
+			return errInvalidRelationship
+		}
+
+		if err := tx.Relationships.Create(r.Context(), &relationship); err != nil {
+			return err
+		}
+
+		return tx.AuditLogs.Create(r.Context(), &models.AuditLog{
+			ID:         uuid.New(),
+			EntityType: "relationship",
+			EntityID:   relationship.ID,
+			Action:     "create",
+			ChangedBy:  username,
+			ChangedAt:  time.Now(),
+			Details:    relationshipAuditDetails(&relationship),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errInvalidRelationship) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(models.GetHTTPStatusForError(models.ErrorTypeValidation))
+			json.NewEncoder(w).Encode(validationError)
+			return
+		}
 		middleware.RespondWithInternalError(w, "Failed to create relationship", nil)
 		return
 	}
 
-	// Create audit log
-	auditLog := &models.AuditLog{
-		ID:         uuid.New(),
-		EntityType: "relationship",
-		EntityID:   relationship.ID,
-		Action:     "create",
-		ChangedBy:  username,
-		ChangedAt:  time.Now(),
-		Details:    models.JSONBMap{"source_id": relationship.SourceID, "target_id": relationship.TargetID, "type": relationship.Type},
-	}
-	if err := h.auditRepo.Create(r.Context(), auditLog); err != nil {
-		// Log the error but don't fail the request
-	}
-
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, relationship.Version)
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(relationship)
 }
 
 // GetRelationship handles retrieving a relationship by ID
 // @Summary Get a relationship by ID
-// @Description Get a relationship by its ID
+// @Description Get a relationship by its ID. The ETag header carries the relationship's version for use in If-Match.
 // @Tags relationships
 // @Accept json
 // @Produce json
@@ -136,46 +173,183 @@ func (h *RelationshipHandler) GetRelationship(w http.ResponseWriter, r *http.Req
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, relationship.Version)
 	json.NewEncoder(w).Encode(relationship)
 }
 
 // GetAllRelationships handles retrieving all relationships
 // @Summary Get all relationships
-// @Description Get all relationships
+// @Description Get all relationships. Without query parameters the full list is returned as an array;
+// @Description with any pagination or filter parameter a paginated object with data and pagination is returned.
+// @Description Attribute filters are given as attributes.<path>=value, e.g. attributes.protocol=tcp.
+// @Description With Accept set to text/csv, application/x-ndjson or application/yaml, all matching relationships
+// @Description are streamed in that format instead and pagination parameters are ignored.
 // @Tags relationships
 // @Accept json
-// @Produce json
+// @Produce json,text/csv,application/x-ndjson,application/yaml
 // @Security BearerAuth
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Param cursor query string false "Opaque next_cursor or prev_cursor from a previous page; overrides page"
+// @Param type query string false "Filter by relationship type"
+// @Param source_id query string false "Filter by source CI ID"
+// @Param target_id query string false "Filter by target CI ID"
 // @Success 200 {object} []models.Relationship
+// @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /relationships [get]
 func (h *RelationshipHandler) GetAllRelationships(w http.ResponseWriter, r *http.Request) {
-	// Get all relationships
-	relationships, err := h.relRepo.GetAll(r.Context())
+	query := r.URL.Query()
+
+	// Stream every matching relationship if the client asks for an export format
+	if mediaType := exportFormat(r); mediaType != "" {
+		filter, ok := parseRelationshipFilter(w, query)
+		if ok {
+			h.exportRelationships(w, r, filter, mediaType)
+		}
+		return
+	}
+
+	paginated := false
+	for param := range query {
+		if query.Get(param) == "" {
+			continue
+		}
+		switch param {
+		case "page", "limit", "cursor", "type", "source_id", "target_id":
+			paginated = true
+		}
+		if strings.HasPrefix(param, attributeParamPrefix) {
+			paginated = true
+		}
+	}
+
+	if !paginated {
+		// Get all relationships
+		relationships, err := h.relRepo.GetAll(r.Context())
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to get relationships", nil)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(relationships)
+		return
+	}
+
+	// Get pagination parameters from query string
+	pageReq, err := parsePageRequest(query)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid cursor", nil)
+		return
+	}
+
+	// Get filter parameters from query string
+	filter, ok := parseRelationshipFilter(w, query)
+	if !ok {
+		return
+	}
+
+	// Count all matching relationships
+	total, err := h.relRepo.Count(r.Context(), filter)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get relationships", nil)
+		return
+	}
+
+	// Get the requested page of relationships
+	filter.Limit = pageReq.FetchLimit()
+	if pageReq.Cursor != nil {
+		filter.Cursor = pageReq.Cursor
+	} else {
+		filter.Offset = pageReq.Offset()
+	}
+	relationships, err := h.relRepo.List(r.Context(), filter)
 	if err != nil {
 		middleware.RespondWithInternalError(w, "Failed to get relationships", nil)
 		return
 	}
 
+	relationships, paginationInfo := paginate(relationships, pageReq, total, true, func(rel *models.Relationship) pagination.Cursor {
+		return pagination.Cursor{Timestamp: rel.CreatedAt, ID: rel.ID}
+	})
+
+	// Create response
+	response := map[string]interface{}{
+		"data":       relationships,
+		"pagination": paginationInfo,
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(relationships)
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseRelationshipFilter reads the relationship filter parameters from the query string. It
+// writes a 400 response and returns false if they are invalid.
+func parseRelationshipFilter(w http.ResponseWriter, query url.Values) (repositories.RelationshipFilter, bool) {
+	filter := repositories.RelationshipFilter{Type: query.Get("type")}
+	errors := make(map[string]interface{})
+	filter.Attributes = parseAttributeParams(query, attributeParamPrefix, errors)
+	if len(errors) > 0 {
+		middleware.RespondWithValidationError(w, "Invalid query parameters", errors)
+		return filter, false
+	}
+	for param, target := range map[string]*uuid.UUID{"source_id": &filter.SourceID, "target_id": &filter.TargetID} {
+		if value := query.Get(param); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				middleware.RespondWithValidationError(w, "Invalid "+param+" format", nil)
+				return filter, false
+			}
+			*target = id
+		}
+	}
+	return filter, true
+}
+
+// relationshipExportFields are the relationship fields of a CSV export, before the attribute
+// columns
+var relationshipExportFields = []string{"id", "source_id", "target_id", "type", "version", "created_at", "updated_at"}
+
+// exportRelationships streams the relationships matching the filter, ignoring pagination, in
+// an export format
+func (h *RelationshipHandler) exportRelationships(w http.ResponseWriter, r *http.Request, filter repositories.RelationshipFilter, mediaType string) {
+	table := exportTable{fields: relationshipExportFields, jsonField: "attributes"}
+	if mediaType == csvMediaType {
+		paths, err := h.relRepo.AttributePaths(r.Context(), filter)
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to export relationships", nil)
+			return
+		}
+		table.paths = paths
+	}
+
+	exporter := newExporter(w, mediaType, table)
+	err := h.relRepo.Stream(r.Context(), filter, func(relationship *models.Relationship) error {
+		return exporter.write(relationship)
+	})
+	exporter.finish(err, "Failed to export relationships")
 }
 
 // UpdateRelationship handles updating an existing relationship
 // @Summary Update a relationship
-// @Description Update an existing relationship
+// @Description Update an existing relationship. The relationship type rules are enforced as on creation.
+// @Description If-Match must carry the ETag of the version being updated.
 // @Tags relationships
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Relationship ID"
+// @Param If-Match header string true "ETag of the relationship version being updated"
 // @Param relationship body models.Relationship true "Updated relationship object"
 // @Success 200 {object} models.Relationship
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /relationships/{id} [put]
 func (h *RelationshipHandler) UpdateRelationship(w http.ResponseWriter, r *http.Request) {
@@ -207,6 +381,11 @@ func (h *RelationshipHandler) UpdateRelationship(w http.ResponseWriter, r *http.
 		return
 	}
 
+	// Check that the client updates the current version
+	if !checkIfMatch(w, r, existingRel.Version) {
+		return
+	}
+
 	// Decode the request body
 	var updatedRel models.Relationship
 	if err := json.NewDecoder(r.Body).Decode(&updatedRel); err != nil {
@@ -222,47 +401,79 @@ func (h *RelationshipHandler) UpdateRelationship(w http.ResponseWriter, r *http.
 		return
 	}
 
-	// Update the relationship
-	existingRel.SourceID = updatedRel.SourceID
-	existingRel.TargetID = updatedRel.TargetID
-	existingRel.Type = updatedRel.Type
-
-	if err := h.relRepo.Update(r.Context(), existingRel); err != nil {
+	// Enforce the rules of the relationship type, then save the relationship and its audit log
+	// in one transaction
+	var validationError *models.ErrorResponse
+	err = h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		var err error
+		validationError, err = validateRelationshipType(r.Context(), tx, &updatedRel, existingRel.ID)
+		if err != nil {
+			return err
+		}
+		if validationError != nil {
+			return errInvalidRelationship
+		}
+
+		// Update the relationship
+		existingRel.SourceID = updatedRel.SourceID
+		existingRel.TargetID = updatedRel.TargetID
+		existingRel.Type = updatedRel.Type
+		existingRel.Attributes = updatedRel.Attributes
+		if existingRel.Attributes == nil {
+			existingRel.Attributes = models.JSONBMap{}
+		}
+		existingRel.UpdatedAt = time.Now()
+
+		if err := tx.Relationships.Update(r.Context(), existingRel); err != nil {
+			return err
+		}
+
+		return tx.AuditLogs.Create(r.Context(), &models.AuditLog{
+			ID:         uuid.New(),
+			EntityType: "relationship",
+			EntityID:   existingRel.ID,
+			Action:     "update",
+			ChangedBy:  username,
+			ChangedAt:  time.Now(),
+			Details:    relationshipAuditDetails(existingRel),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errInvalidRelationship) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(models.GetHTTPStatusForError(models.ErrorTypeValidation))
+			json.NewEncoder(w).Encode(validationError)
+			return
+		}
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			middleware.RespondWithPreconditionFailedError(w, preconditionFailedMessage, nil)
+			return
+		}
 		middleware.RespondWithInternalError(w, "Failed to update relationship", nil)
 		return
 	}
 
-	// Create audit log
-	auditLog := &models.AuditLog{
-		ID:         uuid.New(),
-		EntityType: "relationship",
-		EntityID:   existingRel.ID,
-		Action:     "update",
-		ChangedBy:  username,
-		ChangedAt:  time.Now(),
-		Details:    models.JSONBMap{"source_id": existingRel.SourceID, "target_id": existingRel.TargetID, "type": existingRel.Type},
-	}
-	if err := h.auditRepo.Create(r.Context(), auditLog); err != nil {
-		// Log the error but don't fail the request
-	}
-
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, existingRel.Version)
 	json.NewEncoder(w).Encode(existingRel)
 }
 
 // DeleteRelationship handles deleting a relationship
 // @Summary Delete a relationship
-// @Description Delete a relationship
+// @Description Delete a relationship. If-Match must carry the ETag of the current version.
 // @Tags relationships
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Relationship ID"
+// @Param If-Match header string true "ETag of the current relationship version"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /relationships/{id} [delete]
 func (h *RelationshipHandler) DeleteRelationship(w http.ResponseWriter, r *http.Request) {
@@ -294,26 +505,199 @@ func (h *RelationshipHandler) DeleteRelationship(w http.ResponseWriter, r *http.
 		return
 	}
 
-	// Delete the relationship
-	if err := h.relRepo.Delete(r.Context(), id); err != nil {
-		middleware.RespondWithInternalError(w, "Failed to delete relationship", nil)
+	// Check that the client deletes the current version
+	if !checkIfMatch(w, r, rel.Version) {
 		return
 	}
 
-	// Create audit log
-	auditLog := &models.AuditLog{
-		ID:         uuid.New(),
-		EntityType: "relationship",
-		EntityID:   rel.ID,
-		Action:     "delete",
-		ChangedBy:  username,
-		ChangedAt:  time.Now(),
-		Details:    models.JSONBMap{"source_id": rel.SourceID, "target_id": rel.TargetID, "type": rel.Type},
-	}
-	if err := h.auditRepo.Create(r.Context(), auditLog); err != nil {
-		// Log the error but don't fail the request
+	// Delete the relationship and record it in one transaction
+	err = h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		if err := tx.Relationships.Delete(r.Context(), id, rel.Version); err != nil {
+			return err
+		}
+
+		return tx.AuditLogs.Create(r.Context(), &models.AuditLog{
+			ID:         uuid.New(),
+			EntityType: "relationship",
+			EntityID:   rel.ID,
+			Action:     "delete",
+			ChangedBy:  username,
+			ChangedAt:  time.Now(),
+			Details:    relationshipAuditDetails(rel),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			middleware.RespondWithPreconditionFailedError(w, preconditionFailedMessage, nil)
+			return
+		}
+		if errors.Is(err, repositories.ErrRelationshipNotFound) {
+			middleware.RespondWithNotFoundError(w, "Relationship not found", nil)
+			return
+		}
+		middleware.RespondWithInternalError(w, "Failed to delete relationship", nil)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Relationship deleted successfully"})
-}
\ No newline at end of file
+}
+
+// validateRelationshipType checks the relationship against its registered type. A relationship
+// given by the type's inverse name is turned around into the canonical direction. Then the
+// endpoint CI types, the cardinality and, for acyclic types, the absence of cycles are checked;
+// a cycle is reported in the details as the CIs from the source around to the source again.
+// excludeID is the relationship being updated, which is ignored by the checks. The checks run
+// in the transaction that writes the relationship, with the endpoint CIs locked, so that
+// concurrent writes of relationships between the same CIs cannot both pass them. A cycle can
+// be closed through any CIs, so the writes of an acyclic type are serialized by locking the
+// type.
+func validateRelationshipType(ctx context.Context, tx repositories.Repos, rel *models.Relationship, excludeID uuid.UUID) (*models.ErrorResponse, error) {
+	relType, err := tx.RelationshipTypes.GetByName(ctx, rel.Type)
+	if err != nil {
+		return relationshipValidationError("type", fmt.Sprintf("type '%s' is not a registered relationship type", rel.Type)), nil
+	}
+	if !strings.EqualFold(rel.Type, relType.Name) {
+		rel.SourceID, rel.TargetID = rel.TargetID, rel.SourceID
+	}
+	rel.Type = relType.Name
+
+	if relType.Acyclic {
+		if err := tx.Relationships.LockType(ctx, relType.Name); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.CIs.Lock(ctx, rel.SourceID, rel.TargetID); err != nil {
+		return nil, err
+	}
+
+	// Check the CI types at both ends
+	details := make(map[string]interface{})
+	for _, end := range []struct {
+		field   string
+		id      uuid.UUID
+		allowed []string
+	}{
+		{"source_id", rel.SourceID, relType.AllowedSourceTypes},
+		{"target_id", rel.TargetID, relType.AllowedTargetTypes},
+	} {
+		ci, err := tx.CIs.GetByID(ctx, end.id)
+		if err != nil {
+			details[end.field] = []string{"CI not found"}
+			continue
+		}
+		if len(end.allowed) > 0 && !containsString(end.allowed, ci.Type) {
+			details[end.field] = []string{fmt.Sprintf(
+				"type '%s' does not allow CIs of type '%s' here; allowed: %s",
+				relType.Name, ci.Type, strings.Join(end.allowed, ", "),
+			)}
+		}
+	}
+	if len(details) > 0 {
+		return models.NewErrorResponse(models.ErrorTypeValidation, "Validation failed", details), nil
+	}
+
+	// Check the cardinality; with 1:N a target has a single source, with 1:1 a source also
+	// has a single target
+	switch relType.Cardinality {
+	case models.CardinalityOneToOne:
+		existing, err := otherRelationships(ctx, tx, repositories.RelationshipFilter{SourceID: rel.SourceID, Type: rel.Type}, excludeID)
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 {
+			return relationshipValidationError("source_id", fmt.Sprintf("source CI already has a '%s' relationship (cardinality %s)", rel.Type, relType.Cardinality)), nil
+		}
+		fallthrough
+	case models.CardinalityOneToMany:
+		existing, err := otherRelationships(ctx, tx, repositories.RelationshipFilter{TargetID: rel.TargetID, Type: rel.Type}, excludeID)
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 {
+			return relationshipValidationError("target_id", fmt.Sprintf("target CI already has a '%s' relationship (cardinality %s)", rel.Type, relType.Cardinality)), nil
+		}
+	}
+
+	// Check that the relationship closes no cycle of this type
+	if relType.Acyclic {
+		outgoing := func(ctx context.Context, ciID uuid.UUID) ([]*models.Relationship, error) {
+			return otherRelationships(ctx, tx, repositories.RelationshipFilter{SourceID: ciID, Type: rel.Type}, excludeID)
+		}
+		cycle, err := graph.ClosesCycle(ctx, rel.SourceID, rel.TargetID, outgoing)
+		if err != nil {
+			return nil, err
+		}
+		if cycle != nil {
+			return models.NewErrorResponse(models.ErrorTypeValidation, "Validation failed", map[string]interface{}{
+				"target_id": []string{fmt.Sprintf("relationship would create a '%s' cycle", rel.Type)},
+				"cycle":     cycle,
+			}), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// otherRelationships lists the relationships matching the filter other than excludeID
+func otherRelationships(ctx context.Context, tx repositories.Repos, filter repositories.RelationshipFilter, excludeID uuid.UUID) ([]*models.Relationship, error) {
+	relationships, err := tx.Relationships.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := relationships[:0]
+	for _, rel := range relationships {
+		if rel.ID != excludeID {
+			result = append(result, rel)
+		}
+	}
+	return result, nil
+}
+
+// relationshipValidationError returns a validation error with a single message for the field
+func relationshipValidationError(field, message string) *models.ErrorResponse {
+	return models.NewErrorResponse(
+		models.ErrorTypeValidation,
+		"Validation failed",
+		map[string]interface{}{field: []string{message}},
+	)
+}
+
+// containsString reports whether the list contains the value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// relationshipAuditLogs returns an audit log for each of the relationships, e.g. those that
+// went to the trash or came back with their CI
+func relationshipAuditLogs(relationships []*models.Relationship, action, username string, changedAt time.Time) []*models.AuditLog {
+	auditLogs := make([]*models.AuditLog, len(relationships))
+	for i, rel := range relationships {
+		auditLogs[i] = &models.AuditLog{
+			ID:         uuid.New(),
+			EntityType: "relationship",
+			EntityID:   rel.ID,
+			Action:     action,
+			ChangedBy:  username,
+			ChangedAt:  changedAt,
+			Details:    relationshipAuditDetails(rel),
+		}
+	}
+	return auditLogs
+}
+
+// relationshipAuditDetails returns the audit log details for a relationship
+func relationshipAuditDetails(rel *models.Relationship) models.JSONBMap {
+	return models.JSONBMap{
+		"source_id":  rel.SourceID,
+		"target_id":  rel.TargetID,
+		"type":       rel.Type,
+		"attributes": rel.Attributes,
+	}
+}
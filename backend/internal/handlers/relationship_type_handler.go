@@ -0,0 +1,466 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/cmdb-lite/backend/internal/validation"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// RelationshipTypeHandler handles HTTP requests for the relationship type registry
+type RelationshipTypeHandler struct {
+	relTypeRepo repositories.RelationshipTypeRepository
+	relRepo     repositories.RelationshipRepository
+	ciTypeRepo  repositories.CITypeRepository
+	uow         repositories.UnitOfWork
+	validator   *validation.Validator
+}
+
+// NewRelationshipTypeHandler creates a new RelationshipTypeHandler
+func NewRelationshipTypeHandler(
+	relTypeRepo repositories.RelationshipTypeRepository,
+	relRepo repositories.RelationshipRepository,
+	ciTypeRepo repositories.CITypeRepository,
+	uow repositories.UnitOfWork,
+) *RelationshipTypeHandler {
+	return &RelationshipTypeHandler{
+		relTypeRepo: relTypeRepo,
+		relRepo:     relRepo,
+		ciTypeRepo:  ciTypeRepo,
+		uow:         uow,
+		validator:   validation.NewValidator(),
+	}
+}
+
+// CreateRelationshipType handles the creation of a new relationship type
+// @Summary Create a new relationship type
+// @Description Register a new relationship type with its inverse name, allowed endpoints, cardinality and acyclicity
+// @Tags relationship-types
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param relationshipType body models.RelationshipType true "Relationship type object"
+// @Success 201 {object} models.RelationshipType
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /relationship-types [post]
+func (h *RelationshipTypeHandler) CreateRelationshipType(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	var relType models.RelationshipType
+	if err := json.NewDecoder(r.Body).Decode(&relType); err != nil {
+		middleware.RespondWithValidationError(w, "Invalid request body", nil)
+		return
+	}
+
+	// Validate relationship type data using the validator
+	if validationError := h.validateRelationshipType(r.Context(), &relType); validationError != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(models.GetHTTPStatusForError(models.ErrorTypeValidation))
+		json.NewEncoder(w).Encode(validationError)
+		return
+	}
+
+	// Make sure neither name is already registered
+	taken, err := h.namesTaken(r.Context(), &relType, uuid.Nil)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to check relationship type name", nil)
+		return
+	}
+	if taken {
+		middleware.RespondWithConflictError(w, "Relationship type already exists", nil)
+		return
+	}
+
+	// Set default values
+	if relType.ID == uuid.Nil {
+		relType.ID = uuid.New()
+	}
+	now := time.Now()
+	relType.CreatedAt = now
+	relType.UpdatedAt = now
+
+	// Create the relationship type and its audit log in one transaction
+	err = h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		if err := tx.RelationshipTypes.Create(r.Context(), &relType); err != nil {
+			return err
+		}
+
+		return tx.AuditLogs.Create(r.Context(), &models.AuditLog{
+			ID:         uuid.New(),
+			EntityType: "relationship_type",
+			EntityID:   relType.ID,
+			Action:     "create",
+			ChangedBy:  username,
+			ChangedAt:  time.Now(),
+			Details:    relationshipTypeAuditDetails(&relType),
+		})
+	})
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to create relationship type", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(relType)
+}
+
+// GetRelationshipType handles retrieving a relationship type by ID
+// @Summary Get a relationship type by ID
+// @Description Get a registered relationship type by its ID
+// @Tags relationship-types
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Relationship type ID"
+// @Success 200 {object} models.RelationshipType
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /relationship-types/{id} [get]
+func (h *RelationshipTypeHandler) GetRelationshipType(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL parameters
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		middleware.RespondWithValidationError(w, "ID parameter is required", nil)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid ID format", nil)
+		return
+	}
+
+	// Get the relationship type
+	relType, err := h.relTypeRepo.GetByID(r.Context(), id)
+	if err != nil {
+		middleware.RespondWithError(w, models.ErrorTypeRelationshipTypeNotFound, "Relationship type not found", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(relType)
+}
+
+// GetAllRelationshipTypes handles retrieving all relationship types
+// @Summary Get all relationship types
+// @Description Get all registered relationship types
+// @Tags relationship-types
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} []models.RelationshipType
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /relationship-types [get]
+func (h *RelationshipTypeHandler) GetAllRelationshipTypes(w http.ResponseWriter, r *http.Request) {
+	// Get all relationship types
+	relTypes, err := h.relTypeRepo.GetAll(r.Context())
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get relationship types", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(relTypes)
+}
+
+// UpdateRelationshipType handles updating an existing relationship type
+// @Summary Update a relationship type
+// @Description Update a relationship type. The rules apply to relationships created or updated afterwards.
+// @Tags relationship-types
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Relationship type ID"
+// @Param relationshipType body models.RelationshipType true "Updated relationship type object"
+// @Success 200 {object} models.RelationshipType
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /relationship-types/{id} [put]
+func (h *RelationshipTypeHandler) UpdateRelationshipType(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	// Extract ID from URL parameters
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		middleware.RespondWithValidationError(w, "ID parameter is required", nil)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid ID format", nil)
+		return
+	}
+
+	// Get the existing relationship type
+	existingType, err := h.relTypeRepo.GetByID(r.Context(), id)
+	if err != nil {
+		middleware.RespondWithError(w, models.ErrorTypeRelationshipTypeNotFound, "Relationship type not found", nil)
+		return
+	}
+
+	// Decode the request body
+	var updatedType models.RelationshipType
+	if err := json.NewDecoder(r.Body).Decode(&updatedType); err != nil {
+		middleware.RespondWithValidationError(w, "Invalid request body", nil)
+		return
+	}
+
+	// Validate relationship type data using the validator
+	if validationError := h.validateRelationshipType(r.Context(), &updatedType); validationError != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(models.GetHTTPStatusForError(models.ErrorTypeValidation))
+		json.NewEncoder(w).Encode(validationError)
+		return
+	}
+
+	// Renaming a type would orphan the relationships that reference it
+	if updatedType.Name != existingType.Name {
+		relationships, err := h.relRepo.GetByType(r.Context(), existingType.Name)
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to check relationship type usage", nil)
+			return
+		}
+		if len(relationships) > 0 {
+			middleware.RespondWithConflictError(w, "Relationship type is in use and cannot be renamed", map[string]interface{}{"relationship_count": len(relationships)})
+			return
+		}
+	}
+
+	taken, err := h.namesTaken(r.Context(), &updatedType, existingType.ID)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to check relationship type name", nil)
+		return
+	}
+	if taken {
+		middleware.RespondWithConflictError(w, "Relationship type already exists", nil)
+		return
+	}
+
+	// Update the relationship type
+	existingType.Name = updatedType.Name
+	existingType.InverseName = updatedType.InverseName
+	existingType.Description = updatedType.Description
+	existingType.AllowedSourceTypes = updatedType.AllowedSourceTypes
+	existingType.AllowedTargetTypes = updatedType.AllowedTargetTypes
+	existingType.Cardinality = updatedType.Cardinality
+	existingType.Acyclic = updatedType.Acyclic
+	existingType.UpdatedAt = time.Now()
+
+	// Save the relationship type and its audit log in one transaction
+	err = h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		if err := tx.RelationshipTypes.Update(r.Context(), existingType); err != nil {
+			return err
+		}
+
+		return tx.AuditLogs.Create(r.Context(), &models.AuditLog{
+			ID:         uuid.New(),
+			EntityType: "relationship_type",
+			EntityID:   existingType.ID,
+			Action:     "update",
+			ChangedBy:  username,
+			ChangedAt:  time.Now(),
+			Details:    relationshipTypeAuditDetails(existingType),
+		})
+	})
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to update relationship type", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingType)
+}
+
+// DeleteRelationshipType handles deleting a relationship type
+// @Summary Delete a relationship type
+// @Description Delete a relationship type that is not used by any relationship
+// @Tags relationship-types
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Relationship type ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /relationship-types/{id} [delete]
+func (h *RelationshipTypeHandler) DeleteRelationshipType(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	// Extract ID from URL parameters
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		middleware.RespondWithValidationError(w, "ID parameter is required", nil)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid ID format", nil)
+		return
+	}
+
+	// Get the relationship type
+	relType, err := h.relTypeRepo.GetByID(r.Context(), id)
+	if err != nil {
+		middleware.RespondWithError(w, models.ErrorTypeRelationshipTypeNotFound, "Relationship type not found", nil)
+		return
+	}
+
+	// Refuse to delete a type that relationships still reference
+	relationships, err := h.relRepo.GetByType(r.Context(), relType.Name)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to check relationship type usage", nil)
+		return
+	}
+	if len(relationships) > 0 {
+		middleware.RespondWithConflictError(w, "Relationship type is in use and cannot be deleted", map[string]interface{}{"relationship_count": len(relationships)})
+		return
+	}
+
+	// Delete the relationship type and record it in one transaction
+	err = h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		if err := tx.RelationshipTypes.Delete(r.Context(), id); err != nil {
+			return err
+		}
+
+		return tx.AuditLogs.Create(r.Context(), &models.AuditLog{
+			ID:         uuid.New(),
+			EntityType: "relationship_type",
+			EntityID:   relType.ID,
+			Action:     "delete",
+			ChangedBy:  username,
+			ChangedAt:  time.Now(),
+			Details:    models.JSONBMap{"name": relType.Name},
+		})
+	})
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to delete relationship type", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Relationship type deleted successfully"})
+}
+
+// validateRelationshipType validates the relationship type fields, fills in defaults and checks
+// that the allowed endpoint types are registered CI types
+func (h *RelationshipTypeHandler) validateRelationshipType(ctx context.Context, relType *models.RelationshipType) *models.ErrorResponse {
+	if validationError := h.validator.Validate(*relType); validationError != nil {
+		return validationError
+	}
+
+	if relType.Cardinality == "" {
+		relType.Cardinality = models.CardinalityManyToMany
+	}
+	if relType.AllowedSourceTypes == nil {
+		relType.AllowedSourceTypes = pq.StringArray{}
+	}
+	if relType.AllowedTargetTypes == nil {
+		relType.AllowedTargetTypes = pq.StringArray{}
+	}
+
+	details := make(map[string]interface{})
+	if strings.EqualFold(relType.Name, relType.InverseName) {
+		details["inverse_name"] = []string{"inverse_name must differ from name"}
+	}
+	for field, ciTypes := range map[string]pq.StringArray{
+		"allowed_source_types": relType.AllowedSourceTypes,
+		"allowed_target_types": relType.AllowedTargetTypes,
+	} {
+		var messages []string
+		for _, ciType := range ciTypes {
+			if _, err := h.ciTypeRepo.GetByName(ctx, ciType); err != nil {
+				messages = append(messages, fmt.Sprintf("'%s' is not a registered CI type", ciType))
+			}
+		}
+		if len(messages) > 0 {
+			details[field] = messages
+		}
+	}
+
+	if len(details) > 0 {
+		return models.NewErrorResponse(models.ErrorTypeValidation, "Validation failed", details)
+	}
+	return nil
+}
+
+// namesTaken reports whether the name or inverse name of the relationship type is already used,
+// ignoring case, as the name or inverse name of another type
+func (h *RelationshipTypeHandler) namesTaken(ctx context.Context, relType *models.RelationshipType, exceptID uuid.UUID) (bool, error) {
+	relTypes, err := h.relTypeRepo.GetAll(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, other := range relTypes {
+		if other.ID == exceptID {
+			continue
+		}
+		for _, name := range []string{relType.Name, relType.InverseName} {
+			if name == "" {
+				continue
+			}
+			if strings.EqualFold(other.Name, name) || strings.EqualFold(other.InverseName, name) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// relationshipTypeAuditDetails returns the audit log details for a relationship type
+func relationshipTypeAuditDetails(relType *models.RelationshipType) models.JSONBMap {
+	return models.JSONBMap{
+		"name":                 relType.Name,
+		"inverse_name":         relType.InverseName,
+		"allowed_source_types": []string(relType.AllowedSourceTypes),
+		"allowed_target_types": []string(relType.AllowedTargetTypes),
+		"cardinality":          relType.Cardinality,
+		"acyclic":              relType.Acyclic,
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+)
+
+// maxSearchTextLength bounds the free text accepted by the search endpoint
+const maxSearchTextLength = 256
+
+// SearchHandler handles HTTP requests for full-text search
+type SearchHandler struct {
+	ciRepo repositories.CIRepository
+}
+
+// NewSearchHandler creates a new SearchHandler
+func NewSearchHandler(ciRepo repositories.CIRepository) *SearchHandler {
+	return &SearchHandler{
+		ciRepo: ciRepo,
+	}
+}
+
+// Search handles full-text search over CIs
+// @Summary Full-text search
+// @Description Search CI names, types, tags and string attribute values. Every word must match, as a prefix,
+// @Description in any field. Results are ranked by relevance and include highlighted snippets of the matching fields.
+// @Tags search
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param text query string true "Words to search for"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /search [get]
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	// Validate the search text
+	text := strings.TrimSpace(r.URL.Query().Get("text"))
+	switch {
+	case text == "":
+		middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+			"text": []string{"text is required"},
+		})
+		return
+	case len(text) > maxSearchTextLength:
+		middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+			"text": []string{"text must be at most 256 characters"},
+		})
+		return
+	}
+
+	// Get pagination parameters from query string. Results are ordered by rank, so only
+	// page numbers are supported.
+	pageReq, err := parsePageRequest(r.URL.Query())
+	if err != nil || pageReq.Cursor != nil {
+		middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+			"cursor": []string{"cursor is not supported by search"},
+		})
+		return
+	}
+
+	// Count all matching CIs
+	total, err := h.ciRepo.CountSearch(r.Context(), text)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to search CIs", nil)
+		return
+	}
+
+	// Get the requested page of results
+	results, err := h.ciRepo.Search(r.Context(), text, pageReq.Limit, pageReq.Offset())
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to search CIs", nil)
+		return
+	}
+
+	results, paginationInfo := paginate[*models.CISearchResult](results, pageReq, total, false, nil)
+
+	// Create response
+	response := map[string]interface{}{
+		"data":       results,
+		"pagination": paginationInfo,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
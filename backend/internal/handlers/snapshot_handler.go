@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/cmdb-lite/backend/internal/snapshot"
+	"github.com/google/uuid"
+)
+
+// Audit log entity type and actions of snapshots
+const (
+	snapshotEntityType   = "snapshot"
+	snapshotActionExport = "export"
+	snapshotActionImport = "import"
+)
+
+// restoreBatchSize is the number of rows restored with one statement
+const restoreBatchSize = 1000
+
+// errDatabaseNotEmpty is returned inside the import transaction when the database has CIs or
+// relationships outside the trash
+var errDatabaseNotEmpty = errors.New("database is not empty")
+
+// restoredTable is the outcome of restoring a table of a snapshot
+type restoredTable struct {
+	Table    string `json:"table"`
+	Records  int    `json:"records"`
+	Restored int64  `json:"restored"`
+}
+
+// SnapshotHandler handles HTTP requests for snapshots of the whole CMDB
+type SnapshotHandler struct {
+	snapshotRepo repositories.SnapshotRepository
+	auditRepo    repositories.AuditLogRepository
+	uow          repositories.UnitOfWork
+}
+
+// NewSnapshotHandler creates a new SnapshotHandler
+func NewSnapshotHandler(
+	snapshotRepo repositories.SnapshotRepository,
+	auditRepo repositories.AuditLogRepository,
+	uow repositories.UnitOfWork,
+) *SnapshotHandler {
+	return &SnapshotHandler{
+		snapshotRepo: snapshotRepo,
+		auditRepo:    auditRepo,
+		uow:          uow,
+	}
+}
+
+// ExportSnapshot handles exporting a snapshot of the CMDB
+// @Summary Export a snapshot
+// @Description Export a consistent snapshot of the CMDB as a tar.gz archive: a manifest.json with the schema version
+// @Description and the SHA-256 checksum of each file, followed by an NDJSON file per table with CI and relationship
+// @Description types, users, CIs (in the trash too), external IDs, relationships, their history and audit logs.
+// @Description Password hashes are left out unless asked for. The export is recorded in the audit log.
+// @Tags admin
+// @Produce application/gzip
+// @Security BearerAuth
+// @Param include_password_hashes query bool false "Include the users' password hashes" default(false)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/export [get]
+func (h *SnapshotHandler) ExportSnapshot(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	passwordHashes := false
+	if value := r.URL.Query().Get("include_password_hashes"); value != "" {
+		var err error
+		if passwordHashes, err = strconv.ParseBool(value); err != nil {
+			middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+				"include_password_hashes": []string{"include_password_hashes must be true or false"},
+			})
+			return
+		}
+	}
+
+	// Read every table from the same snapshot of the database into the archive's files
+	archive := snapshot.NewWriter()
+	defer archive.Close()
+
+	manifest := snapshot.Manifest{
+		ID:             uuid.New(),
+		CreatedBy:      username,
+		PasswordHashes: passwordHashes,
+	}
+	err := h.snapshotRepo.InSnapshot(r.Context(), func(s repositories.SnapshotRepository) error {
+		var err error
+		if manifest.SchemaVersion, err = s.SchemaVersion(r.Context()); err != nil {
+			return err
+		}
+		manifest.CreatedAt = time.Now().UTC()
+
+		for _, table := range repositories.SnapshotTables {
+			columns, err := s.Columns(r.Context(), table, passwordHashes)
+			if err != nil {
+				return err
+			}
+			file, err := archive.Create(table, columns)
+			if err != nil {
+				return err
+			}
+			if err := s.StreamRows(r.Context(), table, columns, file.Write); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to export snapshot", nil)
+		return
+	}
+
+	// Record who took the snapshot before handing it out
+	if err := h.auditRepo.Create(r.Context(), &models.AuditLog{
+		ID:         uuid.New(),
+		EntityType: snapshotEntityType,
+		EntityID:   manifest.ID,
+		Action:     snapshotActionExport,
+		ChangedBy:  username,
+		ChangedAt:  time.Now(),
+		Details: models.JSONBMap{
+			"schema_version":  manifest.SchemaVersion,
+			"password_hashes": passwordHashes,
+		},
+	}); err != nil {
+		middleware.RespondWithInternalError(w, "Failed to create audit log", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", snapshot.MediaType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="cmdb-snapshot-%s.tar.gz"`,
+		manifest.CreatedAt.Format("20060102T150405Z")))
+	if err := archive.WriteTo(w, manifest); err != nil {
+		log.Printf("Failed to write snapshot: %v", err)
+	}
+}
+
+// ImportSnapshot handles restoring a snapshot of the CMDB
+// @Summary Import a snapshot
+// @Description Restore a snapshot archive made by the export into a database without CIs or relationships outside
+// @Description the trash, keeping the IDs of all rows. The archive must come from the same schema version, and every
+// @Description file must match the checksum and row count in its manifest. The trash is emptied, and the CI and
+// @Description relationship types replace the existing ones. Users whose ID or username is taken are skipped, and
+// @Description users exported without password hashes cannot log in; existing audit logs are kept. Everything is
+// @Description restored in one transaction, so a failed import changes nothing. The import is recorded in the audit
+// @Description log.
+// @Tags admin
+// @Accept application/gzip
+// @Produce json
+// @Security BearerAuth
+// @Param archive body string true "Snapshot archive"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/import [post]
+func (h *SnapshotHandler) ImportSnapshot(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	// Read the manifest and check that it describes the tables this version snapshots
+	reader, err := snapshot.NewReader(r.Body)
+	if err != nil {
+		respondWithInvalidArchive(w, err)
+		return
+	}
+	defer reader.Close()
+
+	manifest := reader.Manifest()
+	if err := checkSnapshotFiles(manifest.Files); err != nil {
+		respondWithInvalidArchive(w, err)
+		return
+	}
+
+	schemaVersion, err := h.snapshotRepo.SchemaVersion(r.Context())
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get schema version", nil)
+		return
+	}
+	if manifest.SchemaVersion != schemaVersion {
+		middleware.RespondWithConflictError(w, "Archive is from another schema version", map[string]interface{}{
+			"archive_schema_version":  manifest.SchemaVersion,
+			"database_schema_version": schemaVersion,
+		})
+		return
+	}
+
+	// Restore the tables in one transaction, as they are read from the archive
+	var nonEmpty []string
+	tables := make([]restoredTable, 0, len(manifest.Files))
+	err = h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		var err error
+		if nonEmpty, err = tx.Snapshots.NonEmptyTables(r.Context()); err != nil {
+			return err
+		}
+		if len(nonEmpty) > 0 {
+			return errDatabaseNotEmpty
+		}
+
+		for {
+			file, err := reader.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			restored, err := restoreSnapshotFile(r.Context(), tx.Snapshots, file)
+			if err != nil {
+				return err
+			}
+			tables = append(tables, restoredTable{Table: file.File.Table, Records: file.File.Records, Restored: restored})
+		}
+
+		return tx.AuditLogs.Create(r.Context(), &models.AuditLog{
+			ID:         uuid.New(),
+			EntityType: snapshotEntityType,
+			EntityID:   manifest.ID,
+			Action:     snapshotActionImport,
+			ChangedBy:  username,
+			ChangedAt:  time.Now(),
+			Details: models.JSONBMap{
+				"schema_version": manifest.SchemaVersion,
+				"created_at":     manifest.CreatedAt,
+				"created_by":     manifest.CreatedBy,
+			},
+		})
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errDatabaseNotEmpty):
+			middleware.RespondWithConflictError(w, "Database is not empty", map[string]interface{}{
+				"tables": nonEmpty,
+			})
+		case errors.Is(err, snapshot.ErrInvalidArchive), errors.Is(err, repositories.ErrSnapshotRowsRejected):
+			respondWithInvalidArchive(w, err)
+		default:
+			middleware.RespondWithInternalError(w, "Failed to import snapshot", nil)
+		}
+		return
+	}
+
+	// Create response
+	response := map[string]interface{}{
+		"id":             manifest.ID,
+		"schema_version": manifest.SchemaVersion,
+		"created_at":     manifest.CreatedAt,
+		"created_by":     manifest.CreatedBy,
+		"tables":         tables,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// restoreSnapshotFile restores the rows of a file of a snapshot in batches and returns the
+// number of rows inserted. The file's columns must be columns of its table.
+func restoreSnapshotFile(ctx context.Context, snapshotRepo repositories.SnapshotRepository, file *snapshot.FileReader) (int64, error) {
+	table := file.File.Table
+	columns, err := snapshotRepo.Columns(ctx, table, true)
+	if err != nil {
+		return 0, err
+	}
+	for _, column := range file.File.Columns {
+		if !containsString(columns, column) {
+			return 0, fmt.Errorf("%w: %s has column %s, which table %s does not have",
+				snapshot.ErrInvalidArchive, file.File.Name, column, table)
+		}
+	}
+
+	if err := snapshotRepo.PrepareRestore(ctx, table); err != nil {
+		return 0, err
+	}
+
+	var restored int64
+	batch := make([]json.RawMessage, 0, restoreBatchSize)
+	flush := func() error {
+		n, err := snapshotRepo.RestoreRows(ctx, table, file.File.Columns, batch)
+		restored += n
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		row, err := file.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		batch = append(batch, row)
+		if len(batch) == restoreBatchSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	return restored, nil
+}
+
+// checkSnapshotFiles checks that an archive holds a file for each snapshot table, in the
+// order they are restored
+func checkSnapshotFiles(files []snapshot.File) error {
+	if len(files) != len(repositories.SnapshotTables) {
+		return fmt.Errorf("%w: the manifest lists %d files, expected %d",
+			snapshot.ErrInvalidArchive, len(files), len(repositories.SnapshotTables))
+	}
+
+	for i, table := range repositories.SnapshotTables {
+		if files[i].Table != table || files[i].Name != snapshot.FileName(table) {
+			return fmt.Errorf("%w: file %d of the manifest is %s, expected %s",
+				snapshot.ErrInvalidArchive, i+1, files[i].Name, snapshot.FileName(table))
+		}
+	}
+	return nil
+}
+
+// respondWithInvalidArchive responds with a 400 describing why an archive was rejected
+func respondWithInvalidArchive(w http.ResponseWriter, err error) {
+	middleware.RespondWithValidationError(w, "Invalid archive", map[string]interface{}{
+		"archive": []string{err.Error()},
+	})
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/history"
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// errCITypeNotRegistered is returned inside the restore transaction when the CI's type is no
+// longer registered
+var errCITypeNotRegistered = errors.New("CI type is not registered")
+
+// unrestoredRelationship is a relationship that a restore left in the trash because it breaks
+// the rules of its type
+type unrestoredRelationship struct {
+	Relationship *models.Relationship  `json:"relationship"`
+	Error        *models.ErrorResponse `json:"error"`
+}
+
+// TrashHandler handles HTTP requests for deleted CIs
+type TrashHandler struct {
+	ciRepo repositories.CIRepository
+	uow    repositories.UnitOfWork
+}
+
+// NewTrashHandler creates a new TrashHandler
+func NewTrashHandler(ciRepo repositories.CIRepository, uow repositories.UnitOfWork) *TrashHandler {
+	return &TrashHandler{
+		ciRepo: ciRepo,
+		uow:    uow,
+	}
+}
+
+// GetTrash handles listing the CIs in the trash
+// @Summary Get the trash
+// @Description Get the deleted configuration items, most recently deleted first, with the number of their
+// @Description relationships that were removed with them. Deleted CIs are purged after the retention period.
+// @Tags trash
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /trash [get]
+func (h *TrashHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	// Get pagination parameters from query string. The trash is ordered by deletion time,
+	// so only page numbers are supported.
+	pageReq, err := parsePageRequest(r.URL.Query())
+	if err != nil || pageReq.Cursor != nil {
+		middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+			"cursor": []string{"cursor is not supported by the trash"},
+		})
+		return
+	}
+
+	// Count all deleted CIs
+	total, err := h.ciRepo.CountDeleted(r.Context())
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get trash", nil)
+		return
+	}
+
+	// Get the requested page of deleted CIs
+	cis, err := h.ciRepo.ListDeleted(r.Context(), pageReq.Limit, pageReq.Offset())
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get trash", nil)
+		return
+	}
+
+	cis, paginationInfo := paginate[*models.DeletedCI](cis, pageReq, total, false, nil)
+
+	// Create response
+	response := map[string]interface{}{
+		"data":       cis,
+		"pagination": paginationInfo,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RestoreCI handles taking a CI out of the trash
+// @Summary Restore a CI
+// @Description Restore a deleted configuration item together with the relationships that were removed with it.
+// @Description Relationships to CIs that are still in the trash, or that have been created again since, stay deleted.
+// @Description Relationships that break the rules of their type, e.g. a cardinality or a cycle, because of relationships
+// @Description written while the CI was in the trash also stay deleted and are listed in relationships_not_restored.
+// @Description A CI whose type was deleted or renamed while it was in the trash cannot be restored until the type is registered again.
+// @Tags trash
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "CI ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cis/{id}/restore [post]
+func (h *TrashHandler) RestoreCI(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	// Extract ID from URL parameters
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		middleware.RespondWithValidationError(w, "ID parameter is required", nil)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid ID format", nil)
+		return
+	}
+
+	// Restore the CI and its relationships and record them in one transaction
+	var ci *models.CI
+	var restored []*models.Relationship
+	var notRestored []*unrestoredRelationship
+	err = h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		restored, notRestored = []*models.Relationship{}, []*unrestoredRelationship{}
+
+		relationships, err := tx.Relationships.ListRestorable(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if err := lockRestorableRelationships(r.Context(), tx, id, relationships); err != nil {
+			return err
+		}
+
+		if err := tx.CIs.Restore(r.Context(), id); err != nil {
+			return err
+		}
+
+		if ci, err = tx.CIs.GetByID(r.Context(), id); err != nil {
+			return err
+		}
+
+		// The CI's type may have been deleted or renamed while the CI was in the trash
+		if _, err := tx.CITypes.GetByName(r.Context(), ci.Type); err != nil {
+			return errCITypeNotRegistered
+		}
+
+		// Relationships written while the CI was in the trash may leave no room for its own,
+		// e.g. by taking the single source of a 1:N target or by making one close a cycle.
+		// Those that break the rules of their types stay in the trash.
+		for _, rel := range relationships {
+			checked := *rel
+			validationError, err := validateRelationshipType(r.Context(), tx, &checked, rel.ID)
+			if err != nil {
+				return err
+			}
+			if validationError != nil {
+				notRestored = append(notRestored, &unrestoredRelationship{Relationship: rel, Error: validationError})
+				continue
+			}
+
+			if err := tx.Relationships.Restore(r.Context(), rel.ID); err != nil {
+				return err
+			}
+			restored = append(restored, rel)
+		}
+
+		// A restore brings the CI back unchanged, so it records no changes
+		now := time.Now()
+		details := ciAuditDetails(ci, []history.Change{})
+		details["relationships_restored"] = len(restored)
+		auditLogs := []*models.AuditLog{{
+			ID:         uuid.New(),
+			EntityType: "configuration_item",
+			EntityID:   ci.ID,
+			Action:     history.ActionRestore,
+			ChangedBy:  username,
+			ChangedAt:  now,
+			Details:    details,
+		}}
+		auditLogs = append(auditLogs, relationshipAuditLogs(restored, history.ActionRestore, username, now)...)
+		return tx.AuditLogs.CreateBatch(r.Context(), auditLogs)
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotInTrash) {
+			middleware.RespondWithNotFoundError(w, "CI not found in trash", nil)
+			return
+		}
+		if errors.Is(err, errCITypeNotRegistered) {
+			middleware.RespondWithConflictError(w, "CI type is no longer registered", map[string]interface{}{
+				"type": []string{fmt.Sprintf("type '%s' is not a registered CI type; register it again to restore the CI", ci.Type)},
+			})
+			return
+		}
+		middleware.RespondWithInternalError(w, "Failed to restore CI", nil)
+		return
+	}
+
+	// Create response
+	response := map[string]interface{}{
+		"ci":                         ci,
+		"relationships_restored":     len(restored),
+		"relationships_not_restored": notRestored,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, ci.Version)
+	json.NewEncoder(w).Encode(response)
+}
+
+// lockRestorableRelationships takes the locks that validateRelationshipType takes for the
+// relationships, in the same order: the acyclic relationship types, by name, then the CIs at
+// both ends, including the CI being restored. Taking them all before the CI comes back keeps
+// the restore from deadlocking with concurrent writes of relationships.
+func lockRestorableRelationships(ctx context.Context, tx repositories.Repos, ciID uuid.UUID, relationships []*models.Relationship) error {
+	typeNames := make(map[string]bool)
+	ciIDs := []uuid.UUID{ciID}
+	for _, rel := range relationships {
+		typeNames[rel.Type] = true
+		ciIDs = append(ciIDs, rel.SourceID, rel.TargetID)
+	}
+
+	names := make([]string, 0, len(typeNames))
+	for name := range typeNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		relType, err := tx.RelationshipTypes.GetByName(ctx, name)
+		if err != nil {
+			// A relationship of a type that is no longer registered stays in the trash
+			continue
+		}
+		if relType.Acyclic {
+			if err := tx.Relationships.LockType(ctx, relType.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.CIs.Lock(ctx, ciIDs...)
+}
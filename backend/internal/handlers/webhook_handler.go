@@ -0,0 +1,557 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/cmdb-lite/backend/internal/validation"
+	"github.com/cmdb-lite/backend/internal/webhook"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// webhookEntityType is the audit log entity type of webhook subscriptions
+const webhookEntityType = "webhook"
+
+// webhookRequest is the body of a request creating or updating a webhook subscription. An
+// empty secret is generated on creation and left unchanged on update; a missing active flag
+// means active on creation and unchanged on update.
+type webhookRequest struct {
+	Name       string   `json:"name"`
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+	CITypes    []string `json:"ci_types"`
+	Active     *bool    `json:"active"`
+}
+
+// WebhookHandler handles HTTP requests for webhook subscriptions and their deliveries
+type WebhookHandler struct {
+	webhookRepo repositories.WebhookRepository
+	ciTypeRepo  repositories.CITypeRepository
+	uow         repositories.UnitOfWork
+	validator   *validation.Validator
+}
+
+// NewWebhookHandler creates a new WebhookHandler
+func NewWebhookHandler(
+	webhookRepo repositories.WebhookRepository,
+	ciTypeRepo repositories.CITypeRepository,
+	uow repositories.UnitOfWork,
+) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepo: webhookRepo,
+		ciTypeRepo:  ciTypeRepo,
+		uow:         uow,
+		validator:   validation.NewValidator(),
+	}
+}
+
+// CreateWebhook handles the creation of a new webhook subscription
+// @Summary Create a webhook subscription
+// @Description Subscribe a URL to CMDB events. Event types are "<entity type>.<action>" or "<entity type>.*", and
+// @Description CI types restrict CI events to CIs of those types; empty lists match every event. Deliveries are
+// @Description signed with the secret, which is generated when left empty and only returned in this response.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook body webhookRequest true "Webhook subscription"
+// @Success 201 {object} models.WebhookSubscription
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.RespondWithValidationError(w, "Invalid request body", nil)
+		return
+	}
+
+	// Set default values
+	now := time.Now()
+	subscription := models.WebhookSubscription{
+		ID:         uuid.New(),
+		Name:       req.Name,
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: pq.StringArray(nonNilStrings(req.EventTypes)),
+		CITypes:    pq.StringArray(nonNilStrings(req.CITypes)),
+		Active:     req.Active == nil || *req.Active,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if subscription.Secret == "" {
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to generate webhook secret", nil)
+			return
+		}
+		subscription.Secret = secret
+	}
+
+	if !h.validateWebhook(w, r, subscription) {
+		return
+	}
+
+	// Create the subscription and its audit log in one transaction
+	err := h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		if err := tx.Webhooks.CreateSubscription(r.Context(), &subscription); err != nil {
+			return err
+		}
+
+		return tx.AuditLogs.Create(r.Context(), webhookAuditLog(&subscription, "create", username))
+	})
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to create webhook", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// GetWebhook handles retrieving a webhook subscription by ID
+// @Summary Get a webhook subscription by ID
+// @Description Get a webhook subscription by its ID. The secret is not returned.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} models.WebhookSubscription
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := h.getSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	subscription.Secret = ""
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// GetAllWebhooks handles retrieving all webhook subscriptions
+// @Summary Get all webhook subscriptions
+// @Description Get all webhook subscriptions, ordered by name. Secrets are not returned.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} []models.WebhookSubscription
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks [get]
+func (h *WebhookHandler) GetAllWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.webhookRepo.ListSubscriptions(r.Context())
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get webhooks", nil)
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		subscription.Secret = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscriptions)
+}
+
+// UpdateWebhook handles updating an existing webhook subscription
+// @Summary Update a webhook subscription
+// @Description Replace the name, URL, event types and CI types of a webhook subscription. The secret and active flag
+// @Description are only changed when given. Pending deliveries are sent with the updated URL and secret.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Param webhook body webhookRequest true "Updated webhook subscription"
+// @Success 200 {object} models.WebhookSubscription
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	// Get the existing subscription
+	subscription, ok := h.getSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.RespondWithValidationError(w, "Invalid request body", nil)
+		return
+	}
+
+	// Update the subscription
+	subscription.Name = req.Name
+	subscription.URL = req.URL
+	subscription.EventTypes = pq.StringArray(nonNilStrings(req.EventTypes))
+	subscription.CITypes = pq.StringArray(nonNilStrings(req.CITypes))
+	if req.Secret != "" {
+		subscription.Secret = req.Secret
+	}
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+	subscription.UpdatedAt = time.Now()
+
+	if !h.validateWebhook(w, r, *subscription) {
+		return
+	}
+
+	// Save the subscription and its audit log in one transaction
+	err := h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		if err := tx.Webhooks.UpdateSubscription(r.Context(), subscription); err != nil {
+			return err
+		}
+
+		return tx.AuditLogs.Create(r.Context(), webhookAuditLog(subscription, "update", username))
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrWebhookNotFound) {
+			middleware.RespondWithNotFoundError(w, "Webhook not found", nil)
+			return
+		}
+		middleware.RespondWithInternalError(w, "Failed to update webhook", nil)
+		return
+	}
+
+	subscription.Secret = ""
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// DeleteWebhook handles deleting a webhook subscription
+// @Summary Delete a webhook subscription
+// @Description Delete a webhook subscription together with its deliveries, including the pending ones
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	// Get the username from the context
+	username, ok := middleware.GetUsernameFromContext(r.Context())
+	if !ok {
+		middleware.RespondWithUnauthorizedError(w, "User not authenticated", nil)
+		return
+	}
+
+	// Get the subscription
+	subscription, ok := h.getSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	// Delete the subscription and record it in one transaction
+	err := h.uow.WithTx(r.Context(), func(tx repositories.Repos) error {
+		if err := tx.Webhooks.DeleteSubscription(r.Context(), subscription.ID); err != nil {
+			return err
+		}
+
+		return tx.AuditLogs.Create(r.Context(), webhookAuditLog(subscription, "delete", username))
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrWebhookNotFound) {
+			middleware.RespondWithNotFoundError(w, "Webhook not found", nil)
+			return
+		}
+		middleware.RespondWithInternalError(w, "Failed to delete webhook", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries handles listing the deliveries of a webhook subscription
+// @Summary Get the deliveries of a webhook subscription
+// @Description Get the delivery log of a webhook subscription, newest first: the payload, status, number of attempts,
+// @Description and the response status and error of the last attempt of each delivery
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Param status query string false "Filter by status" Enums(pending, succeeded, failed)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := h.getSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	// Get pagination parameters from query string. Deliveries are ordered by creation time,
+	// so only page numbers are supported.
+	pageReq, err := parsePageRequest(r.URL.Query())
+	if err != nil || pageReq.Cursor != nil {
+		middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+			"cursor": []string{"cursor is not supported by webhook deliveries"},
+		})
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed:
+	default:
+		middleware.RespondWithValidationError(w, "Invalid query parameters", map[string]interface{}{
+			"status": []string{"status must be one of: pending succeeded failed"},
+		})
+		return
+	}
+
+	// Count all matching deliveries
+	total, err := h.webhookRepo.CountDeliveries(r.Context(), subscription.ID, status)
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get webhook deliveries", nil)
+		return
+	}
+
+	// Get the requested page of deliveries
+	deliveries, err := h.webhookRepo.ListDeliveries(r.Context(), subscription.ID, status, pageReq.Limit, pageReq.Offset())
+	if err != nil {
+		middleware.RespondWithInternalError(w, "Failed to get webhook deliveries", nil)
+		return
+	}
+
+	deliveries, paginationInfo := paginate[*models.WebhookDelivery](deliveries, pageReq, total, false, nil)
+
+	// Create response
+	response := map[string]interface{}{
+		"data":       deliveries,
+		"pagination": paginationInfo,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RedeliverWebhook handles sending an event to a webhook subscription again
+// @Summary Redeliver a webhook event
+// @Description Queue a new delivery of the event and payload of a delivery, whatever its status. The new delivery
+// @Description shares the event ID and is attempted as soon as the dispatcher runs, with a fresh number of attempts.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Success 202 {object} models.WebhookDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (h *WebhookHandler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := h.getSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := uuid.Parse(mux.Vars(r)["delivery_id"])
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid delivery ID format", nil)
+		return
+	}
+
+	// The delivery must belong to the subscription in the path
+	delivery, err := h.webhookRepo.GetDelivery(r.Context(), deliveryID)
+	if err != nil && !errors.Is(err, repositories.ErrWebhookNotFound) {
+		middleware.RespondWithInternalError(w, "Failed to get webhook delivery", nil)
+		return
+	}
+	if delivery == nil || delivery.SubscriptionID != subscription.ID {
+		middleware.RespondWithNotFoundError(w, "Webhook delivery not found", nil)
+		return
+	}
+
+	redelivery, err := h.webhookRepo.Redeliver(r.Context(), delivery.ID, time.Now())
+	if err != nil {
+		if errors.Is(err, repositories.ErrWebhookNotFound) {
+			middleware.RespondWithNotFoundError(w, "Webhook delivery not found", nil)
+			return
+		}
+		middleware.RespondWithInternalError(w, "Failed to redeliver webhook", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(redelivery)
+}
+
+// getSubscription gets the webhook subscription whose ID is in the path, responding with an
+// error if there is none
+func (h *WebhookHandler) getSubscription(w http.ResponseWriter, r *http.Request) (*models.WebhookSubscription, bool) {
+	// Extract ID from URL parameters
+	vars := mux.Vars(r)
+	idStr, ok := vars["id"]
+	if !ok {
+		middleware.RespondWithValidationError(w, "ID parameter is required", nil)
+		return nil, false
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		middleware.RespondWithValidationError(w, "Invalid ID format", nil)
+		return nil, false
+	}
+
+	subscription, err := h.webhookRepo.GetSubscription(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repositories.ErrWebhookNotFound) {
+			middleware.RespondWithNotFoundError(w, "Webhook not found", nil)
+			return nil, false
+		}
+		middleware.RespondWithInternalError(w, "Failed to get webhook", nil)
+		return nil, false
+	}
+
+	return subscription, true
+}
+
+// validateWebhook validates a subscription's fields, checks that its URL is an HTTP(S) URL and
+// that it only lists known event types and registered CI types, and responds with the errors
+// if any
+func (h *WebhookHandler) validateWebhook(w http.ResponseWriter, r *http.Request, subscription models.WebhookSubscription) bool {
+	if validationError := h.validator.Validate(subscription); validationError != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(models.GetHTTPStatusForError(models.ErrorTypeValidation))
+		json.NewEncoder(w).Encode(validationError)
+		return false
+	}
+
+	details := map[string]interface{}{}
+	if u, err := url.Parse(subscription.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		details["url"] = []string{"url must be an http or https URL"}
+	}
+
+	var unknownEvents []string
+	for _, eventType := range subscription.EventTypes {
+		if !webhook.ValidEventType(eventType) {
+			unknownEvents = append(unknownEvents, fmt.Sprintf("%s is not a known event type", eventType))
+		}
+	}
+	if len(unknownEvents) > 0 {
+		details["event_types"] = unknownEvents
+	}
+
+	if len(subscription.CITypes) > 0 {
+		ciTypes, err := h.ciTypeRepo.GetAll(r.Context())
+		if err != nil {
+			middleware.RespondWithInternalError(w, "Failed to check CI types", nil)
+			return false
+		}
+
+		var unknownTypes []string
+		for _, name := range subscription.CITypes {
+			if !ciTypeRegistered(ciTypes, name) {
+				unknownTypes = append(unknownTypes, fmt.Sprintf("%s is not a registered CI type", name))
+			}
+		}
+		if len(unknownTypes) > 0 {
+			details["ci_types"] = unknownTypes
+		}
+	}
+
+	if len(details) > 0 {
+		middleware.RespondWithValidationError(w, "Invalid webhook", details)
+		return false
+	}
+	return true
+}
+
+// ciTypeRegistered reports whether a CI type with the name is registered, ignoring case
+func ciTypeRegistered(ciTypes []*models.CIType, name string) bool {
+	for _, ciType := range ciTypes {
+		if strings.EqualFold(ciType.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookAuditLog returns the audit log of an action on a webhook subscription. The secret is
+// never recorded.
+func webhookAuditLog(subscription *models.WebhookSubscription, action, username string) *models.AuditLog {
+	return &models.AuditLog{
+		ID:         uuid.New(),
+		EntityType: webhookEntityType,
+		EntityID:   subscription.ID,
+		Action:     action,
+		ChangedBy:  username,
+		ChangedAt:  time.Now(),
+		Details: models.JSONBMap{
+			"name":        subscription.Name,
+			"url":         subscription.URL,
+			"event_types": []string(subscription.EventTypes),
+			"ci_types":    []string(subscription.CITypes),
+			"active":      subscription.Active,
+		},
+	}
+}
+
+// nonNilStrings returns values, or an empty slice if it is nil
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
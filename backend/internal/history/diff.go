@@ -0,0 +1,208 @@
+// Package history records how configuration items change over time as structured diffs and
+// rebuilds earlier versions of a CI by replaying them.
+package history
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/cmdb-lite/backend/internal/models"
+)
+
+// Change operations
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+)
+
+// attributesPrefix starts the path of every attribute change, e.g. "attributes.network.vlan"
+const attributesPrefix = "attributes."
+
+// Change is a single field change of a CI. Path is "name", "type", "tags" or "attributes."
+// followed by the dotted attribute path. Old is null for additions and New for removals.
+type Change struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// Diff returns the changes that turn before into after, sorted by path. A nil before
+// describes a creation and a nil after a deletion. Nested attribute objects are compared
+// leaf by leaf; arrays, scalars and empty objects are compared as whole values.
+func Diff(before, after *models.CI) []Change {
+	oldFields, newFields := fields(before), fields(after)
+
+	changes := []Change{}
+	for path, oldValue := range oldFields {
+		newValue, ok := newFields[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: path, Op: OpRemove, Old: oldValue})
+		case !reflect.DeepEqual(normalize(oldValue), normalize(newValue)):
+			changes = append(changes, Change{Path: path, Op: OpReplace, Old: oldValue, New: newValue})
+		}
+	}
+	for path, newValue := range newFields {
+		if _, ok := oldFields[path]; !ok {
+			changes = append(changes, Change{Path: path, Op: OpAdd, New: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// Apply applies the changes to the CI. Removals are applied first so a change of an attribute
+// between an object and a scalar, recorded as a removal and an addition, replays correctly.
+func Apply(ci *models.CI, changes []Change) {
+	if ci.Attributes == nil {
+		ci.Attributes = models.JSONBMap{}
+	}
+
+	for _, change := range changes {
+		if change.Op == OpRemove {
+			applyChange(ci, change.Path, nil, true)
+		}
+	}
+	for _, change := range changes {
+		if change.Op != OpRemove {
+			applyChange(ci, change.Path, change.New, false)
+		}
+	}
+}
+
+// ChangesFromDetails reads the changes stored in audit log details under "changes". It
+// returns false for audit logs written before changes were recorded.
+func ChangesFromDetails(details models.JSONBMap) ([]Change, bool) {
+	raw, ok := details["changes"]
+	if !ok {
+		return nil, false
+	}
+
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, false
+	}
+	var changes []Change
+	if err := json.Unmarshal(encoded, &changes); err != nil {
+		return nil, false
+	}
+	return changes, true
+}
+
+// fields flattens a CI into its diffable fields keyed by path
+func fields(ci *models.CI) map[string]interface{} {
+	result := make(map[string]interface{})
+	if ci == nil {
+		return result
+	}
+
+	result["name"] = ci.Name
+	result["type"] = ci.Type
+	tags := ci.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	result["tags"] = tags
+	flatten(ci.Attributes, "attributes", result)
+	return result
+}
+
+// flatten adds the leaf values of a nested attribute object to result
+func flatten(attributes map[string]interface{}, prefix string, result map[string]interface{}) {
+	for key, value := range attributes {
+		path := prefix + "." + key
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flatten(nested, path, result)
+			continue
+		}
+		result[path] = value
+	}
+}
+
+// normalize round-trips a value through JSON so that values read back from the database
+// compare equal to the values they were written from
+func normalize(value interface{}) interface{} {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return value
+	}
+	return decoded
+}
+
+// applyChange sets or removes the value at the path
+func applyChange(ci *models.CI, path string, value interface{}, remove bool) {
+	switch path {
+	case "name":
+		ci.Name, _ = value.(string)
+		return
+	case "type":
+		ci.Type, _ = value.(string)
+		return
+	case "tags":
+		ci.Tags = toStrings(value)
+		return
+	}
+
+	if !strings.HasPrefix(path, attributesPrefix) {
+		return
+	}
+	keys := strings.Split(strings.TrimPrefix(path, attributesPrefix), ".")
+	if remove {
+		removeAttribute(ci.Attributes, keys)
+		return
+	}
+
+	current := map[string]interface{}(ci.Attributes)
+	for _, key := range keys[:len(keys)-1] {
+		nested, ok := current[key].(map[string]interface{})
+		if !ok {
+			nested = make(map[string]interface{})
+			current[key] = nested
+		}
+		current = nested
+	}
+	current[keys[len(keys)-1]] = value
+}
+
+// removeAttribute deletes the value at the path and any objects left empty by the deletion
+func removeAttribute(attributes map[string]interface{}, keys []string) {
+	if len(keys) == 1 {
+		delete(attributes, keys[0])
+		return
+	}
+
+	nested, ok := attributes[keys[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+	removeAttribute(nested, keys[1:])
+	if len(nested) == 0 {
+		delete(attributes, keys[0])
+	}
+}
+
+// toStrings converts a tag list as stored in a change to a string slice
+func toStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		tags := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				tags = append(tags, s)
+			}
+		}
+		return tags
+	}
+	return []string{}
+}
@@ -0,0 +1,141 @@
+package history
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	before := &models.CI{
+		Name: "web-01",
+		Type: "server",
+		Tags: []string{"prod"},
+		Attributes: models.JSONBMap{
+			"os":      "linux",
+			"cpu":     4,
+			"network": map[string]interface{}{"vlan": 10, "ip": "10.0.0.1"},
+			"owner":   "ops",
+		},
+	}
+	after := &models.CI{
+		Name: "web-01",
+		Type: "server",
+		Tags: []string{"prod", "web"},
+		Attributes: models.JSONBMap{
+			"os":      "linux",
+			"cpu":     8,
+			"network": map[string]interface{}{"vlan": 10},
+			"owner":   map[string]interface{}{"team": "ops"},
+			"rack":    "r1",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		before   *models.CI
+		after    *models.CI
+		expected []Change
+	}{
+		{
+			name:   "Update",
+			before: before,
+			after:  after,
+			expected: []Change{
+				{Path: "attributes.cpu", Op: OpReplace, Old: 4, New: 8},
+				{Path: "attributes.network.ip", Op: OpRemove, Old: "10.0.0.1"},
+				{Path: "attributes.owner", Op: OpRemove, Old: "ops"},
+				{Path: "attributes.owner.team", Op: OpAdd, New: "ops"},
+				{Path: "attributes.rack", Op: OpAdd, New: "r1"},
+				{Path: "tags", Op: OpReplace, Old: []string{"prod"}, New: []string{"prod", "web"}},
+			},
+		},
+		{
+			name:   "Create",
+			before: nil,
+			after:  &models.CI{Name: "db", Type: "database", Attributes: models.JSONBMap{"engine": "postgres"}},
+			expected: []Change{
+				{Path: "attributes.engine", Op: OpAdd, New: "postgres"},
+				{Path: "name", Op: OpAdd, New: "db"},
+				{Path: "tags", Op: OpAdd, New: []string{}},
+				{Path: "type", Op: OpAdd, New: "database"},
+			},
+		},
+		{
+			name:   "Delete",
+			before: &models.CI{Name: "db", Type: "database", Tags: []string{"prod"}},
+			after:  nil,
+			expected: []Change{
+				{Path: "name", Op: OpRemove, Old: "db"},
+				{Path: "tags", Op: OpRemove, Old: []string{"prod"}},
+				{Path: "type", Op: OpRemove, Old: "database"},
+			},
+		},
+		{
+			name:     "No change",
+			before:   before,
+			after:    before,
+			expected: []Change{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Diff(tt.before, tt.after))
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	before := &models.CI{
+		Name: "web-01",
+		Type: "server",
+		Tags: []string{"prod"},
+		Attributes: models.JSONBMap{
+			"cpu":     float64(4),
+			"network": map[string]interface{}{"vlan": float64(10), "ip": "10.0.0.1"},
+			"owner":   "ops",
+			"legacy":  map[string]interface{}{"id": "x"},
+		},
+	}
+	after := &models.CI{
+		Name: "web-02",
+		Type: "server",
+		Tags: []string{"web"},
+		Attributes: models.JSONBMap{
+			"cpu":     float64(8),
+			"network": map[string]interface{}{"vlan": float64(10)},
+			"owner":   map[string]interface{}{"team": "ops"},
+			"empty":   map[string]interface{}{},
+		},
+	}
+
+	// Store the changes as audit log details and read them back, as the history does
+	encoded, err := json.Marshal(models.JSONBMap{"changes": Diff(before, after)})
+	require.NoError(t, err)
+	var details models.JSONBMap
+	require.NoError(t, json.Unmarshal(encoded, &details))
+	changes, ok := ChangesFromDetails(details)
+	require.True(t, ok)
+
+	ci := &models.CI{
+		Name:       before.Name,
+		Type:       before.Type,
+		Tags:       before.Tags,
+		Attributes: normalize(before.Attributes).(map[string]interface{}),
+	}
+	Apply(ci, changes)
+
+	assert.Equal(t, after.Name, ci.Name)
+	assert.Equal(t, after.Type, ci.Type)
+	assert.Equal(t, after.Tags, ci.Tags)
+	assert.Equal(t, after.Attributes, ci.Attributes)
+}
+
+func TestChangesFromDetails(t *testing.T) {
+	_, ok := ChangesFromDetails(models.JSONBMap{"name": "web-01", "type": "server"})
+	assert.False(t, ok)
+}
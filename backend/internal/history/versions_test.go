@@ -0,0 +1,66 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebuild(t *testing.T) {
+	id := uuid.New()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The first version predates recorded changes and only carries the name and type
+	v1 := &models.CI{Name: "web-01", Type: "server", Tags: []string{}, Attributes: models.JSONBMap{}, Version: 1}
+	v2 := &models.CI{Name: "web-01", Type: "server", Tags: []string{"prod"}, Attributes: models.JSONBMap{"cpu": float64(4)}, Version: 2}
+	v3 := &models.CI{Name: "web-02", Type: "server", Tags: []string{"prod", "web"}, Attributes: models.JSONBMap{"cpu": float64(8)}, Version: 3}
+	v4 := &models.CI{Name: "web-02", Type: "server", Tags: []string{"prod", "web"}, Attributes: models.JSONBMap{"cpu": float64(16)}, Version: 4}
+
+	// Audit logs arrive newest first
+	logs := []*models.AuditLog{
+		{ID: uuid.New(), EntityID: id, Action: ActionUpdate, ChangedAt: start.Add(5 * time.Hour), Details: models.JSONBMap{"changes": Diff(v3, v4)}},
+		{ID: uuid.New(), EntityID: id, Action: ActionRestore, ChangedAt: start.Add(4 * time.Hour), Details: models.JSONBMap{"changes": []Change{}}},
+		{ID: uuid.New(), EntityID: id, Action: ActionDelete, ChangedAt: start.Add(3 * time.Hour), Details: models.JSONBMap{"changes": Diff(v3, nil)}},
+		{ID: uuid.New(), EntityID: id, Action: ActionUpdate, ChangedAt: start.Add(2 * time.Hour), Details: models.JSONBMap{"changes": Diff(v2, v3)}},
+		{ID: uuid.New(), EntityID: id, Action: ActionUpdate, ChangedAt: start.Add(time.Hour), Details: models.JSONBMap{"changes": Diff(v1, v2)}},
+		{ID: uuid.New(), EntityID: id, Action: ActionCreate, ChangedAt: start, Details: models.JSONBMap{"name": "web-01", "type": "server"}},
+	}
+
+	versions := Versions(logs)
+	require.Len(t, versions, 6)
+	for i, version := range versions {
+		assert.Equal(t, i+1, version.Version)
+	}
+	assert.Equal(t, []Change{{Path: "name", Op: OpReplace, New: "web-01"}, {Path: "type", Op: OpReplace, New: "server"}}, versions[0].Changes)
+
+	tests := []struct {
+		name     string
+		version  int
+		expected *models.CI
+	}{
+		{name: "Legacy version", version: 1, expected: v1},
+		{name: "Update", version: 2, expected: v2},
+		{name: "Latest update", version: 3, expected: v3},
+		{name: "Deletion keeps the last state", version: 4, expected: v3},
+		{name: "Restore brings back the last state", version: 5, expected: v3},
+		{name: "Update after restore", version: 6, expected: v4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ci := Rebuild(id, versions, tt.version)
+
+			assert.Equal(t, id, ci.ID)
+			assert.Equal(t, tt.expected.Name, ci.Name)
+			assert.Equal(t, tt.expected.Type, ci.Type)
+			assert.Equal(t, tt.expected.Tags, ci.Tags)
+			assert.Equal(t, tt.expected.Attributes, ci.Attributes)
+			assert.Equal(t, tt.expected.Version, ci.Version)
+			assert.Equal(t, start, ci.CreatedAt)
+		})
+	}
+}
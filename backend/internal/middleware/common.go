@@ -33,6 +33,11 @@ func CORS(cfg *config.Config) func(http.Handler) http.Handler {
 			headers := strings.Join(cfg.CORSAllowedHeaders, ", ")
 			w.Header().Set("Access-Control-Allow-Headers", headers)
 
+			// Set the response headers scripts may read, e.g. the ETag sent back in If-Match
+			if len(cfg.CORSExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(cfg.CORSExposedHeaders, ", "))
+			}
+
 			// Set credentials policy
 			if cfg.CORSAllowCredentials {
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
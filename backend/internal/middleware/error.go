@@ -142,4 +142,24 @@ func RespondWithNotFoundError(w http.ResponseWriter, message string, details int
 // RespondWithInternalError is a helper function for internal server errors
 func RespondWithInternalError(w http.ResponseWriter, message string, details interface{}) {
 	RespondWithError(w, models.ErrorTypeInternal, message, details)
-}
\ No newline at end of file
+}
+
+// RespondWithConflictError is a helper function for conflict errors
+func RespondWithConflictError(w http.ResponseWriter, message string, details interface{}) {
+	RespondWithError(w, models.ErrorTypeConflict, message, details)
+}
+
+// RespondWithPreconditionFailedError is a helper function for failed If-Match preconditions
+func RespondWithPreconditionFailedError(w http.ResponseWriter, message string, details interface{}) {
+	RespondWithError(w, models.ErrorTypePreconditionFailed, message, details)
+}
+
+// RespondWithPreconditionRequiredError is a helper function for missing If-Match preconditions
+func RespondWithPreconditionRequiredError(w http.ResponseWriter, message string, details interface{}) {
+	RespondWithError(w, models.ErrorTypePreconditionRequired, message, details)
+}
+
+// RespondWithUnsupportedMediaTypeError is a helper function for request bodies of an unsupported content type
+func RespondWithUnsupportedMediaTypeError(w http.ResponseWriter, message string, details interface{}) {
+	RespondWithError(w, models.ErrorTypeUnsupportedMediaType, message, details)
+}
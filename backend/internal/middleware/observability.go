@@ -139,6 +139,11 @@ func (rw *observabilityResponseWriter) Flush() {
 	}
 }
 
+// Unwrap returns the wrapped http.ResponseWriter, for http.ResponseController
+func (rw *observabilityResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // DatabaseQueryMiddleware creates middleware for database query logging and metrics
 func DatabaseQueryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // User represents a user in the system
@@ -69,17 +70,117 @@ type CI struct {
 	Type       string    `json:"type" db:"type" validate:"required,min=1,max=50"`
 	Attributes JSONBMap  `json:"attributes" db:"attributes"`
 	Tags       []string  `json:"tags" db:"tags"`
+	Version    int       `json:"version" db:"version"`
 	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// CISearchResult is a CI matched by full-text search. Highlights maps each field that matched
+// (name, type, tags or attributes) to a snippet with the matching words wrapped in <mark> tags.
+type CISearchResult struct {
+	CI
+	Rank       float64           `json:"rank"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// DeletedCI is a CI in the trash. Relationships is the number of its relationships that are
+// in the trash too; restoring the CI brings back those whose other CI is not in the trash.
+type DeletedCI struct {
+	CI
+	DeletedAt     time.Time `json:"deleted_at" db:"deleted_at"`
+	Relationships int       `json:"relationships" db:"relationships"`
+}
+
+// CIExternalID is an identifier a CI has in another system, such as a hostname or a cloud
+// instance ID. Each pair of source and external ID belongs to one CI.
+type CIExternalID struct {
+	CIID       uuid.UUID `json:"ci_id" db:"ci_id"`
+	Source     string    `json:"source" db:"source" validate:"required,min=1,max=100"`
+	ExternalID string    `json:"external_id" db:"external_id" validate:"required,min=1,max=255"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// BulkCIOperation is one operation of a bulk CI request. An upsert carries the CI, which
+// updates the CI with its ID if there is one and is created otherwise. A delete carries the ID
+// of the CI to move to the trash. A non-zero version must equal the stored version.
+type BulkCIOperation struct {
+	Op      string    `json:"op"`
+	CI      *CI       `json:"ci,omitempty"`
+	ID      uuid.UUID `json:"id,omitempty"`
+	Version int       `json:"version,omitempty"`
+}
+
+// BulkCIResult is the outcome of a bulk operation. Status is the HTTP status the operation
+// would have had on its own; ID and Version identify the CI written, and Error is set when the
+// operation failed.
+type BulkCIResult struct {
+	Index   int            `json:"index"`
+	Op      string         `json:"op"`
+	Status  int            `json:"status"`
+	ID      *uuid.UUID     `json:"id,omitempty"`
+	Version int            `json:"version,omitempty"`
+	Error   *ErrorResponse `json:"error,omitempty"`
+}
+
+// CIType represents a registered CI type and the JSON Schema its attributes must conform to
+type CIType struct {
+	ID          uuid.UUID `json:"id" db:"id" validate:"uuid"`
+	Name        string    `json:"name" db:"name" validate:"required,min=1,max=50"`
+	Description string    `json:"description" db:"description" validate:"max=255"`
+	Schema      JSONBMap  `json:"schema" db:"schema"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Relationship type cardinalities, read in the source to target direction. 1:N allows a source
+// many targets but each target only one source; 1:1 allows one of each.
+const (
+	CardinalityOneToOne   = "1:1"
+	CardinalityOneToMany  = "1:N"
+	CardinalityManyToMany = "N:M"
+)
+
+// RelationshipType represents a registered relationship type. Relationships are stored under
+// Name; one created with InverseName is stored as Name with source and target swapped. Empty
+// allowed type lists accept CIs of any type.
+type RelationshipType struct {
+	ID                 uuid.UUID      `json:"id" db:"id" validate:"uuid"`
+	Name               string         `json:"name" db:"name" validate:"required,min=1,max=50"`
+	InverseName        string         `json:"inverse_name" db:"inverse_name" validate:"max=50"`
+	Description        string         `json:"description" db:"description" validate:"max=255"`
+	AllowedSourceTypes pq.StringArray `json:"allowed_source_types" db:"allowed_source_types"`
+	AllowedTargetTypes pq.StringArray `json:"allowed_target_types" db:"allowed_target_types"`
+	Cardinality        string         `json:"cardinality" db:"cardinality" validate:"omitempty,oneof=1:1 1:N N:M"`
+	Acyclic            bool           `json:"acyclic" db:"acyclic"`
+	CreatedAt          time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at" db:"updated_at"`
+}
+
 // Relationship represents a relationship between CIs
 type Relationship struct {
-	ID        uuid.UUID `json:"id" db:"id" validate:"uuid"`
-	SourceID  uuid.UUID `json:"source_id" db:"source_id" validate:"required,uuid"`
-	TargetID  uuid.UUID `json:"target_id" db:"target_id" validate:"required,uuid"`
-	Type      string    `json:"type" db:"type" validate:"required,min=1,max=50"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID         uuid.UUID `json:"id" db:"id" validate:"uuid"`
+	SourceID   uuid.UUID `json:"source_id" db:"source_id" validate:"required,uuid"`
+	TargetID   uuid.UUID `json:"target_id" db:"target_id" validate:"required,uuid"`
+	Type       string    `json:"type" db:"type" validate:"required,min=1,max=50"`
+	Attributes JSONBMap  `json:"attributes" db:"attributes"`
+	Version    int       `json:"version" db:"version"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// GraphNode is a CI reached by a graph traversal. Depth is the number of hops from the root.
+type GraphNode struct {
+	CI
+	Depth int `json:"depth" db:"depth"`
+}
+
+// CIGraph is the neighbourhood of a CI: the CIs reached from the root and the relationships
+// between them. Truncated is set when the traversal stopped at the node limit.
+type CIGraph struct {
+	RootID    uuid.UUID       `json:"root_id"`
+	Nodes     []*GraphNode    `json:"nodes"`
+	Edges     []*Relationship `json:"edges"`
+	Truncated bool            `json:"truncated"`
 }
 
 // AuditLog represents an audit log entry
@@ -87,12 +188,86 @@ type AuditLog struct {
 	ID         uuid.UUID `json:"id" db:"id" validate:"uuid"`
 	EntityType string    `json:"entity_type" db:"entity_type" validate:"required,min=1,max=50"`
 	EntityID   uuid.UUID `json:"entity_id" db:"entity_id" validate:"required,uuid"`
-	Action     string    `json:"action" db:"action" validate:"required,min=1,max=20,oneof=create update delete"`
+	Action     string    `json:"action" db:"action" validate:"required,min=1,max=20,oneof=create update delete restore purge export import"`
 	ChangedBy  string    `json:"changed_by" db:"changed_by" validate:"required,min=1,max=50"`
 	ChangedAt  time.Time `json:"changed_at" db:"changed_at"`
 	Details    JSONBMap  `json:"details" db:"details"`
 }
 
+// Event is a change in the change feed: the audit log of a change to a CI, relationship or
+// user, numbered by Seq in the order the changes were committed. Tags are the current tags
+// of the CI of a CI event, used to filter the feed.
+type Event struct {
+	Seq        int64          `json:"seq" db:"seq"`
+	ID         uuid.UUID      `json:"id" db:"id"`
+	Type       string         `json:"type" db:"type"`
+	EntityType string         `json:"entity_type" db:"entity_type"`
+	EntityID   uuid.UUID      `json:"entity_id" db:"entity_id"`
+	Action     string         `json:"action" db:"action"`
+	ChangedBy  string         `json:"changed_by" db:"changed_by"`
+	ChangedAt  time.Time      `json:"changed_at" db:"changed_at"`
+	Details    JSONBMap       `json:"details" db:"details"`
+	Tags       pq.StringArray `json:"-" db:"tags"`
+}
+
+// OutboxEvent is a domain event of a change to a CI or relationship, written to the outbox in
+// the transaction of the change and published by the relay. ID is the ID of the change's audit
+// log; Seq orders the events as they were recorded.
+type OutboxEvent struct {
+	ID            uuid.UUID       `json:"id" db:"id"`
+	Seq           int64           `json:"seq" db:"seq"`
+	AggregateType string          `json:"aggregate_type" db:"aggregate_type"`
+	AggregateID   uuid.UUID       `json:"aggregate_id" db:"aggregate_id"`
+	EventType     string          `json:"event_type" db:"event_type"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	AvailableAt   time.Time       `json:"available_at" db:"available_at"`
+	PublishedAt   *time.Time      `json:"published_at" db:"published_at"`
+	LastError     string          `json:"last_error" db:"last_error"`
+}
+
+// WebhookSubscription is a URL that events are posted to. Empty EventTypes and CITypes match
+// every event; CITypes only restricts events of CIs. Secret signs the payloads and is only
+// returned when the subscription is created.
+type WebhookSubscription struct {
+	ID         uuid.UUID      `json:"id" db:"id" validate:"uuid"`
+	Name       string         `json:"name" db:"name" validate:"required,min=1,max=100"`
+	URL        string         `json:"url" db:"url" validate:"required,url,max=2048"`
+	Secret     string         `json:"secret,omitempty" db:"secret" validate:"omitempty,min=16,max=255"`
+	EventTypes pq.StringArray `json:"event_types" db:"event_types"`
+	CITypes    pq.StringArray `json:"ci_types" db:"ci_types"`
+	Active     bool           `json:"active" db:"active"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// Webhook delivery statuses. A pending delivery is attempted until it succeeds or fails for
+// good after the last attempt.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is an event posted to a webhook subscription. EventID is the ID of the
+// event's audit log; redeliveries of the event share it. ResponseStatus and LastError describe
+// the last attempt.
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id" db:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id" db:"subscription_id"`
+	EventID        uuid.UUID       `json:"event_id" db:"event_id"`
+	EventType      string          `json:"event_type" db:"event_type"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         string          `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at" db:"last_attempt_at"`
+	ResponseStatus *int            `json:"response_status" db:"response_status"`
+	LastError      string          `json:"last_error" db:"last_error"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+}
+
 // JSONBMap is a custom type for handling JSONB data
 type JSONBMap map[string]interface{}
 
@@ -154,11 +329,26 @@ const (
 	ErrorTypeInsufficientPermissions ErrorType = "INSUFFICIENT_PERMISSIONS"
 
 	// Not found errors (404 Not Found)
-	ErrorTypeNotFound             ErrorType = "NOT_FOUND"
-	ErrorTypeUserNotFound         ErrorType = "USER_NOT_FOUND"
-	ErrorTypeCINotFound           ErrorType = "CI_NOT_FOUND"
-	ErrorTypeRelationshipNotFound ErrorType = "RELATIONSHIP_NOT_FOUND"
-	ErrorTypeAuditLogNotFound     ErrorType = "AUDIT_LOG_NOT_FOUND"
+	ErrorTypeNotFound                 ErrorType = "NOT_FOUND"
+	ErrorTypeUserNotFound             ErrorType = "USER_NOT_FOUND"
+	ErrorTypeCINotFound               ErrorType = "CI_NOT_FOUND"
+	ErrorTypeRelationshipNotFound     ErrorType = "RELATIONSHIP_NOT_FOUND"
+	ErrorTypeAuditLogNotFound         ErrorType = "AUDIT_LOG_NOT_FOUND"
+	ErrorTypeCITypeNotFound           ErrorType = "CI_TYPE_NOT_FOUND"
+	ErrorTypeRelationshipTypeNotFound ErrorType = "RELATIONSHIP_TYPE_NOT_FOUND"
+
+	// Conflict errors (409 Conflict)
+	ErrorTypeConflict ErrorType = "CONFLICT"
+
+	// Precondition errors (412 Precondition Failed, 428 Precondition Required)
+	ErrorTypePreconditionFailed   ErrorType = "PRECONDITION_FAILED"
+	ErrorTypePreconditionRequired ErrorType = "PRECONDITION_REQUIRED"
+
+	// Unsupported media type errors (415 Unsupported Media Type)
+	ErrorTypeUnsupportedMediaType ErrorType = "UNSUPPORTED_MEDIA_TYPE"
+
+	// Failed dependency errors (424 Failed Dependency)
+	ErrorTypeFailedDependency ErrorType = "FAILED_DEPENDENCY"
 
 	// Server errors (500 Internal Server Error)
 	ErrorTypeInternal ErrorType = "INTERNAL_ERROR"
@@ -186,8 +376,19 @@ func GetHTTPStatusForError(errorType ErrorType) int {
 	case ErrorTypeForbidden, ErrorTypeInsufficientPermissions:
 		return http.StatusForbidden
 	case ErrorTypeNotFound, ErrorTypeUserNotFound, ErrorTypeCINotFound,
-		ErrorTypeRelationshipNotFound, ErrorTypeAuditLogNotFound:
+		ErrorTypeRelationshipNotFound, ErrorTypeAuditLogNotFound, ErrorTypeCITypeNotFound,
+		ErrorTypeRelationshipTypeNotFound:
 		return http.StatusNotFound
+	case ErrorTypeConflict:
+		return http.StatusConflict
+	case ErrorTypePreconditionFailed:
+		return http.StatusPreconditionFailed
+	case ErrorTypePreconditionRequired:
+		return http.StatusPreconditionRequired
+	case ErrorTypeUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case ErrorTypeFailedDependency:
+		return http.StatusFailedDependency
 	default:
 		return http.StatusInternalServerError
 	}
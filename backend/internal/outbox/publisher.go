@@ -0,0 +1,75 @@
+// Package outbox relays the domain events written to the outbox to an EventPublisher. Events
+// are written in the transaction of the change to the CI or relationship they record, so an
+// event exists exactly when its change was committed. The relay publishes each event at least
+// once; consumers that must not see an event twice deduplicate by its ID.
+package outbox
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/cmdb-lite/backend/internal/models"
+)
+
+// EventPublisher publishes the outbox's events to an integration, e.g. a message bus. Publish
+// returns nil only once the integration has accepted the event; the relay retries an event
+// whose publishing failed.
+type EventPublisher interface {
+	Publish(ctx context.Context, event *models.OutboxEvent) error
+}
+
+// LogPublisher publishes events by writing them to the log
+type LogPublisher struct{}
+
+// NewLogPublisher creates a new LogPublisher
+func NewLogPublisher() *LogPublisher {
+	return &LogPublisher{}
+}
+
+// Publish writes an event to the log
+func (p *LogPublisher) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	log.Printf("Outbox event %d %s %s %s: %s", event.Seq, event.ID, event.EventType, event.AggregateID, event.Payload)
+	return nil
+}
+
+// MemoryPublisher keeps the events it publishes in memory, for tests
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []*models.OutboxEvent
+	err    error
+}
+
+// NewMemoryPublisher creates a new MemoryPublisher
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish keeps a copy of an event, or returns the error set with FailWith
+func (p *MemoryPublisher) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	published := *event
+	p.events = append(p.events, &published)
+	return nil
+}
+
+// FailWith makes Publish return err, or succeed again when err is nil
+func (p *MemoryPublisher) FailWith(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.err = err
+}
+
+// Events returns the events published so far, in the order they were published
+func (p *MemoryPublisher) Events() []*models.OutboxEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*models.OutboxEvent(nil), p.events...)
+}
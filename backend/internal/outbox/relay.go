@@ -0,0 +1,139 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+)
+
+// batchSize is the number of events claimed and published at once
+const batchSize = 100
+
+// Retries of events whose publishing failed
+const (
+	initialBackoff = time.Second
+	maxBackoff     = 5 * time.Minute
+)
+
+// Relay periodically publishes the outbox's pending events. Events are leased while they are
+// published, so several relays, e.g. one per replica, can run side by side; the lease must
+// outlast publishing a batch.
+type Relay struct {
+	repo      repositories.OutboxRepository
+	publisher EventPublisher
+	interval  time.Duration
+	lease     time.Duration
+	retention time.Duration
+}
+
+// NewRelay creates a new Relay that runs every interval, leases the events it claims for the
+// lease, and deletes published events once they are older than the retention period
+func NewRelay(repo repositories.OutboxRepository, publisher EventPublisher, interval, lease, retention time.Duration) *Relay {
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		interval:  interval,
+		lease:     lease,
+		retention: retention,
+	}
+}
+
+// Run publishes the pending events once and then every interval until the context is
+// cancelled. An interval or lease that is not positive disables the relay, and a retention
+// period that is not positive keeps published events.
+func (r *Relay) Run(ctx context.Context) {
+	if r.interval <= 0 || r.lease <= 0 {
+		log.Println("Outbox relay is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while full batches are pending, so that a backlog drains without
+		// waiting an interval per batch
+		for {
+			claimed, err := r.RelayPending(ctx, time.Now())
+			if err != nil {
+				log.Printf("Failed to relay outbox events: %v", err)
+			}
+			if err != nil || claimed < batchSize {
+				break
+			}
+		}
+
+		if r.retention > 0 {
+			if _, err := r.repo.DeletePublished(ctx, time.Now().Add(-r.retention)); err != nil {
+				log.Printf("Failed to delete published outbox events: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayPending claims a batch of the events pending at now and publishes them one by one in
+// the order they were recorded. An event whose publishing fails is retried after a backoff,
+// after the events recorded later. It returns the number of events claimed, and the errors of
+// the events that were not published.
+func (r *Relay) RelayPending(ctx context.Context, now time.Time) (int, error) {
+	events, err := r.repo.ClaimPending(ctx, now, r.lease, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	for _, event := range events {
+		// Events left unpublished are published again once their lease ends
+		if ctx.Err() != nil {
+			break
+		}
+		if err := r.publish(ctx, event, now); err != nil {
+			errs = append(errs, fmt.Errorf("event %s: %w", event.ID, err))
+		}
+	}
+
+	return len(events), errors.Join(errs...)
+}
+
+// publish publishes an event and records the outcome, returning the publisher's error once it
+// is recorded. When the context is cancelled during publishing nothing is recorded. The
+// outcome is only recorded while the claim's lease holds; once it is lost the event belongs
+// to the relay that claimed it again, and repositories.ErrOutboxLeaseLost is returned.
+func (r *Relay) publish(ctx context.Context, event *models.OutboxEvent, now time.Time) error {
+	err := r.publisher.Publish(ctx, event)
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	if err == nil {
+		return r.repo.MarkPublished(ctx, event.ID, event.AvailableAt, now)
+	}
+	if markErr := r.repo.MarkFailed(ctx, event.ID, event.AvailableAt, now.Add(Backoff(event.Attempts+1)), err.Error()); markErr != nil {
+		return markErr
+	}
+	return err
+}
+
+// Backoff returns the delay before the next attempt to publish an event that failed the given
+// number of attempts: one second, doubling with each attempt up to five minutes
+func Backoff(attempts int) time.Duration {
+	backoff := initialBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,326 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOutboxRepository keeps the outbox in memory and leases events like the database does
+type fakeOutboxRepository struct {
+	repositories.OutboxRepository
+	mu       sync.Mutex
+	events   map[uuid.UUID]*models.OutboxEvent
+	claimErr error
+}
+
+// newFakeOutboxRepository returns an outbox with count events of a CI, available at now
+func newFakeOutboxRepository(count int, now time.Time) *fakeOutboxRepository {
+	r := &fakeOutboxRepository{events: make(map[uuid.UUID]*models.OutboxEvent)}
+	aggregateID := uuid.New()
+	for seq := 1; seq <= count; seq++ {
+		id := uuid.New()
+		payload, _ := json.Marshal(map[string]interface{}{"id": id, "type": "configuration_item.update"})
+		r.events[id] = &models.OutboxEvent{
+			ID:            id,
+			Seq:           int64(seq),
+			AggregateType: "configuration_item",
+			AggregateID:   aggregateID,
+			EventType:     "configuration_item.update",
+			Payload:       payload,
+			CreatedAt:     now,
+			AvailableAt:   now,
+		}
+	}
+	return r
+}
+
+func (r *fakeOutboxRepository) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.claimErr != nil {
+		return nil, r.claimErr
+	}
+
+	var pending []*models.OutboxEvent
+	for _, event := range r.events {
+		if event.PublishedAt == nil && !event.AvailableAt.After(now) {
+			pending = append(pending, event)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Seq < pending[j].Seq })
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+
+	claimed := make([]*models.OutboxEvent, len(pending))
+	for i, event := range pending {
+		event.AvailableAt = now.Add(lease)
+		copied := *event
+		claimed[i] = &copied
+	}
+	return claimed, nil
+}
+
+func (r *fakeOutboxRepository) MarkPublished(ctx context.Context, id uuid.UUID, leasedUntil, publishedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event := r.events[id]
+	if event.PublishedAt != nil || !event.AvailableAt.Equal(leasedUntil) {
+		return repositories.ErrOutboxLeaseLost
+	}
+	event.PublishedAt = &publishedAt
+	event.Attempts++
+	event.LastError = ""
+	return nil
+}
+
+func (r *fakeOutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID, leasedUntil, availableAt time.Time, lastError string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event := r.events[id]
+	if event.PublishedAt != nil || !event.AvailableAt.Equal(leasedUntil) {
+		return repositories.ErrOutboxLeaseLost
+	}
+	event.AvailableAt = availableAt
+	event.Attempts++
+	event.LastError = lastError
+	return nil
+}
+
+// event returns a copy of the event with a sequence number
+func (r *fakeOutboxRepository) event(seq int64) models.OutboxEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, event := range r.events {
+		if event.Seq == seq {
+			return *event
+		}
+	}
+	return models.OutboxEvent{}
+}
+
+// publisherFunc publishes events by calling a function
+type publisherFunc func(ctx context.Context, event *models.OutboxEvent) error
+
+func (f publisherFunc) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	return f(ctx, event)
+}
+
+// publishedSeqs returns the sequence numbers of the published events
+func publishedSeqs(publisher *MemoryPublisher) []int64 {
+	var seqs []int64
+	for _, event := range publisher.Events() {
+		seqs = append(seqs, event.Seq)
+	}
+	return seqs
+}
+
+func TestRelay_RelayPending(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	repo := newFakeOutboxRepository(3, now)
+	publisher := NewMemoryPublisher()
+	relay := NewRelay(repo, publisher, time.Second, time.Minute, time.Hour)
+
+	claimed, err := relay.RelayPending(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 3, claimed)
+
+	// Events are published in the order they were recorded and marked published
+	assert.Equal(t, []int64{1, 2, 3}, publishedSeqs(publisher))
+	for seq := int64(1); seq <= 3; seq++ {
+		event := repo.event(seq)
+		require.NotNil(t, event.PublishedAt)
+		assert.Equal(t, now, *event.PublishedAt)
+		assert.Equal(t, 1, event.Attempts)
+	}
+
+	// Published events are not published again
+	claimed, err = relay.RelayPending(context.Background(), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, claimed)
+	assert.Len(t, publisher.Events(), 3)
+}
+
+func TestRelay_RelayPendingInBatches(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	repo := newFakeOutboxRepository(batchSize+1, now)
+	publisher := NewMemoryPublisher()
+	relay := NewRelay(repo, publisher, time.Second, time.Minute, time.Hour)
+
+	claimed, err := relay.RelayPending(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, batchSize, claimed)
+
+	claimed, err = relay.RelayPending(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, claimed)
+	assert.Len(t, publisher.Events(), batchSize+1)
+}
+
+func TestRelay_RetriesFailedEvents(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	repo := newFakeOutboxRepository(2, now)
+	publisher := NewMemoryPublisher()
+	relay := NewRelay(repo, publisher, time.Second, time.Minute, time.Hour)
+
+	publishErr := errors.New("broker unavailable")
+	publisher.FailWith(publishErr)
+
+	_, err := relay.RelayPending(context.Background(), now)
+	assert.ErrorIs(t, err, publishErr)
+
+	event := repo.event(1)
+	assert.Nil(t, event.PublishedAt)
+	assert.Equal(t, 1, event.Attempts)
+	assert.Equal(t, "broker unavailable", event.LastError)
+	assert.Equal(t, now.Add(Backoff(1)), event.AvailableAt)
+
+	// Nothing is retried before the backoff ends
+	claimed, err := relay.RelayPending(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, claimed)
+
+	publisher.FailWith(nil)
+	later := now.Add(Backoff(1))
+	claimed, err = relay.RelayPending(context.Background(), later)
+	require.NoError(t, err)
+	assert.Equal(t, 2, claimed)
+
+	event = repo.event(1)
+	require.NotNil(t, event.PublishedAt)
+	assert.Equal(t, 2, event.Attempts)
+	assert.Empty(t, event.LastError)
+	assert.Equal(t, []int64{1, 2}, publishedSeqs(publisher))
+}
+
+func TestRelay_LeasesClaimedEvents(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	repo := newFakeOutboxRepository(1, now)
+
+	// The first relay stops after claiming its batch
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stopped := NewRelay(repo, NewMemoryPublisher(), time.Second, time.Minute, time.Hour)
+	claimed, err := stopped.RelayPending(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, claimed)
+
+	// Another relay does not claim the event while it is leased, only once the lease ends
+	publisher := NewMemoryPublisher()
+	relay := NewRelay(repo, publisher, time.Second, time.Minute, time.Hour)
+	claimed, err = relay.RelayPending(context.Background(), now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 0, claimed)
+
+	claimed, err = relay.RelayPending(context.Background(), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, claimed)
+	assert.Equal(t, []int64{1}, publishedSeqs(publisher))
+}
+
+func TestRelay_LostLease(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	for name, publishErr := range map[string]error{
+		"Published event":    nil,
+		"Failed publication": errors.New("broker unavailable"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo := newFakeOutboxRepository(1, now)
+
+			// Publishing outlasts the lease, and another relay claims the event meanwhile
+			var reclaimed []*models.OutboxEvent
+			slow := publisherFunc(func(ctx context.Context, event *models.OutboxEvent) error {
+				var err error
+				reclaimed, err = repo.ClaimPending(ctx, now.Add(time.Minute), time.Minute, batchSize)
+				require.NoError(t, err)
+				return publishErr
+			})
+			relay := NewRelay(repo, slow, time.Second, time.Minute, time.Hour)
+
+			_, err := relay.RelayPending(context.Background(), now)
+			assert.ErrorIs(t, err, repositories.ErrOutboxLeaseLost)
+			require.Len(t, reclaimed, 1)
+
+			// The outcome is left to the relay holding the lease
+			event := repo.event(1)
+			assert.Nil(t, event.PublishedAt)
+			assert.Equal(t, 0, event.Attempts)
+			assert.Equal(t, reclaimed[0].AvailableAt, event.AvailableAt)
+		})
+	}
+}
+
+func TestRelay_ConcurrentRelaysPublishOnce(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	repo := newFakeOutboxRepository(batchSize*3, now)
+	publisher := NewMemoryPublisher()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			relay := NewRelay(repo, publisher, time.Second, time.Minute, time.Hour)
+			for {
+				claimed, err := relay.RelayPending(context.Background(), now)
+				if err != nil || claimed == 0 {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	seqs := publishedSeqs(publisher)
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	require.Len(t, seqs, batchSize*3)
+	for i, seq := range seqs {
+		assert.Equal(t, int64(i+1), seq)
+	}
+}
+
+func TestRelay_ClaimError(t *testing.T) {
+	repo := newFakeOutboxRepository(1, time.Now())
+	repo.claimErr = errors.New("connection refused")
+	relay := NewRelay(repo, NewMemoryPublisher(), time.Second, time.Minute, time.Hour)
+
+	claimed, err := relay.RelayPending(context.Background(), time.Now())
+	assert.ErrorIs(t, err, repo.claimErr)
+	assert.Equal(t, 0, claimed)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, Backoff(1))
+	assert.Equal(t, 2*time.Second, Backoff(2))
+	assert.Equal(t, 4*time.Second, Backoff(3))
+	assert.Equal(t, maxBackoff, Backoff(20))
+}
+
+func TestMemoryPublisher(t *testing.T) {
+	publisher := NewMemoryPublisher()
+	event := &models.OutboxEvent{ID: uuid.New(), Seq: 1}
+
+	require.NoError(t, publisher.Publish(context.Background(), event))
+
+	// The publisher keeps a copy of the event
+	event.Seq = 2
+	events := publisher.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(1), events[0].Seq)
+}
@@ -0,0 +1,95 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned when a cursor token is malformed or its signature does not match
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor identifies a position in a list ordered by (timestamp, id), newest first.
+// Backward cursors page towards newer rows (prev_cursor); forward cursors page towards
+// older rows (next_cursor).
+type Cursor struct {
+	Timestamp time.Time `json:"t"`
+	ID        uuid.UUID `json:"i"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// Codec encodes cursors into opaque, HMAC-signed tokens and decodes them again
+type Codec struct {
+	secret []byte
+}
+
+// NewCodec creates a new Codec that signs cursors with the given secret
+func NewCodec(secret string) *Codec {
+	return &Codec{secret: []byte(secret)}
+}
+
+// Encode turns a cursor into an opaque token
+func (c *Codec) Encode(cursor Cursor) string {
+	payload, _ := json.Marshal(cursor)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + c.sign(encoded)
+}
+
+// Decode verifies a token's signature and returns the cursor it encodes
+func (c *Codec) Decode(token string) (Cursor, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(c.sign(encoded))) {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// sign returns the base64 encoded HMAC-SHA256 signature of the encoded payload
+func (c *Codec) sign(encoded string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+var (
+	defaultCodec   = NewCodec("cmdb-lite-cursor")
+	defaultCodecMu sync.RWMutex
+)
+
+// SetDefaultSecret replaces the secret used by the package level Encode and Decode functions
+func SetDefaultSecret(secret string) {
+	defaultCodecMu.Lock()
+	defer defaultCodecMu.Unlock()
+	defaultCodec = NewCodec(secret)
+}
+
+// Encode turns a cursor into an opaque token using the default codec
+func Encode(cursor Cursor) string {
+	defaultCodecMu.RLock()
+	defer defaultCodecMu.RUnlock()
+	return defaultCodec.Encode(cursor)
+}
+
+// Decode verifies and decodes a token using the default codec
+func Decode(token string) (Cursor, error) {
+	defaultCodecMu.RLock()
+	defer defaultCodecMu.RUnlock()
+	return defaultCodec.Decode(token)
+}
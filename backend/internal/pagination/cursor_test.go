@@ -0,0 +1,90 @@
+package pagination
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec_EncodeDecode(t *testing.T) {
+	// Setup test data
+	cursor := Cursor{
+		Timestamp: time.Date(2025, 9, 20, 11, 0, 0, 123456000, time.UTC),
+		ID:        uuid.New(),
+		Backward:  true,
+	}
+
+	tests := []struct {
+		name          string
+		token         func(codec *Codec) string
+		expectedError bool
+	}{
+		{
+			name: "Round trip",
+			token: func(codec *Codec) string {
+				return codec.Encode(cursor)
+			},
+			expectedError: false,
+		},
+		{
+			name: "Tampered payload",
+			token: func(codec *Codec) string {
+				forged := NewCodec("other-secret").Encode(Cursor{Timestamp: cursor.Timestamp, ID: uuid.New()})
+				payload, _, _ := strings.Cut(forged, ".")
+				_, signature, _ := strings.Cut(codec.Encode(cursor), ".")
+				return payload + "." + signature
+			},
+			expectedError: true,
+		},
+		{
+			name: "Signed with another secret",
+			token: func(codec *Codec) string {
+				return NewCodec("other-secret").Encode(cursor)
+			},
+			expectedError: true,
+		},
+		{
+			name: "Malformed token",
+			token: func(codec *Codec) string {
+				return "not-a-cursor"
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec := NewCodec("test-secret")
+
+			decoded, err := codec.Decode(tt.token(codec))
+
+			if tt.expectedError {
+				assert.ErrorIs(t, err, ErrInvalidCursor)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, cursor.Timestamp.Equal(decoded.Timestamp))
+			assert.Equal(t, cursor.ID, decoded.ID)
+			assert.Equal(t, cursor.Backward, decoded.Backward)
+		})
+	}
+}
+
+func TestSetDefaultSecret(t *testing.T) {
+	cursor := Cursor{Timestamp: time.Now().UTC(), ID: uuid.New()}
+
+	SetDefaultSecret("first-secret")
+	token := Encode(cursor)
+
+	decoded, err := Decode(token)
+	require.NoError(t, err)
+	assert.Equal(t, cursor.ID, decoded.ID)
+
+	SetDefaultSecret("second-secret")
+	_, err = Decode(token)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
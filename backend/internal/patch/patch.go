@@ -0,0 +1,383 @@
+// Package patch applies JSON Merge Patch (RFC 7386) and JSON Patch (RFC 6902) documents to
+// JSON values decoded with encoding/json.
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media types of the supported patch formats
+const (
+	MergePatchMediaType = "application/merge-patch+json"
+	JSONPatchMediaType  = "application/json-patch+json"
+)
+
+// JSON Patch operations
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+	OpMove    = "move"
+	OpCopy    = "copy"
+	OpTest    = "test"
+)
+
+// Error is a JSON Patch that cannot be applied. Index is the position of the failing operation
+// and TestFailed is set when the operation is a test whose value did not match.
+type Error struct {
+	Index      int
+	Message    string
+	TestFailed bool
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("operation %d: %s", e.Index, e.Message)
+}
+
+// Operation is a single JSON Patch operation. Value is the raw JSON value, which is empty when
+// the operation has none, so that a missing value can be told apart from null.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// MergePatch applies a JSON Merge Patch to the target and returns the result. Objects in the
+// patch are merged into the target recursively, null members remove the member, and any other
+// value replaces the target value. The target may be modified.
+func MergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = MergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// ParseJSONPatch decodes a JSON Patch document and checks that every operation is complete
+func ParseJSONPatch(data []byte) ([]Operation, error) {
+	var operations []Operation
+	if err := json.Unmarshal(data, &operations); err != nil {
+		return nil, fmt.Errorf("a JSON Patch must be an array of operations: %v", err)
+	}
+
+	for i, operation := range operations {
+		switch operation.Op {
+		case OpAdd, OpReplace, OpTest:
+			if len(operation.Value) == 0 {
+				return nil, &Error{Index: i, Message: fmt.Sprintf("%s requires a value", operation.Op)}
+			}
+		case OpMove, OpCopy:
+			if _, err := parsePointer(operation.From); err != nil {
+				return nil, &Error{Index: i, Message: "from: " + err.Error()}
+			}
+		case OpRemove:
+		default:
+			return nil, &Error{Index: i, Message: fmt.Sprintf("unknown op %q", operation.Op)}
+		}
+		if _, err := parsePointer(operation.Path); err != nil {
+			return nil, &Error{Index: i, Message: "path: " + err.Error()}
+		}
+	}
+	return operations, nil
+}
+
+// ApplyJSONPatch applies the operations to the document in order and returns the result. It
+// stops at the first operation that fails, returning an *Error; the document may then be
+// partly modified and should be discarded.
+func ApplyJSONPatch(doc interface{}, operations []Operation) (interface{}, error) {
+	for i, operation := range operations {
+		var err error
+		doc, err = apply(doc, operation)
+		if err != nil {
+			if patchErr, ok := err.(*Error); ok {
+				patchErr.Index = i
+				return nil, patchErr
+			}
+			return nil, &Error{Index: i, Message: err.Error()}
+		}
+	}
+	return doc, nil
+}
+
+// apply applies a single operation
+func apply(doc interface{}, operation Operation) (interface{}, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case OpAdd, OpReplace, OpTest:
+		var value interface{}
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return nil, fmt.Errorf("invalid value: %v", err)
+		}
+		switch operation.Op {
+		case OpAdd:
+			return add(doc, path, value)
+		case OpReplace:
+			return replace(doc, path, value)
+		}
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, &Error{Message: fmt.Sprintf("test failed: value at %q does not match", operation.Path), TestFailed: true}
+		}
+		return doc, nil
+
+	case OpRemove:
+		return remove(doc, path)
+
+	case OpMove, OpCopy:
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == OpCopy {
+			return add(doc, path, deepCopy(value))
+		}
+		if isPrefix(from, path) && len(from) < len(path) {
+			return nil, fmt.Errorf("cannot move %q into one of its children", operation.From)
+		}
+		doc, err = remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	}
+	return nil, fmt.Errorf("unknown op %q", operation.Op)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%q is not a JSON Pointer", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// get returns the value at the path
+func get(doc interface{}, path []string) (interface{}, error) {
+	current := doc
+	for i, token := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, notFound(path[:i+1])
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, notFound(path[:i+1])
+			}
+			current = node[index]
+		default:
+			return nil, notFound(path[:i+1])
+		}
+	}
+	return current, nil
+}
+
+// add adds the value at the path: it sets an object member or inserts into an array, where
+// "-" appends
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	return modify(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			index := len(node)
+			if token != "-" {
+				var err error
+				if index, err = arrayIndex(token, len(node)); err != nil {
+					return nil, err
+				}
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		}
+		return nil, notFound(path)
+	}, value)
+}
+
+// remove removes the value at the path, which must exist
+func remove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the whole document")
+	}
+	return modify(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := node[token]; !ok {
+				return nil, notFound(path)
+			}
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, notFound(path)
+			}
+			return append(node[:index], node[index+1:]...), nil
+		}
+		return nil, notFound(path)
+	}, nil)
+}
+
+// replace replaces the value at the path, which must exist
+func replace(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	return modify(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := node[token]; !ok {
+				return nil, notFound(path)
+			}
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, notFound(path)
+			}
+			node[index] = value
+			return node, nil
+		}
+		return nil, notFound(path)
+	}, value)
+}
+
+// modify walks to the parent of the path and calls change with it and the last token. The
+// changed parent, which may be a new array, is stored back into its own parent. An empty
+// path stands for the whole document, which is replaced by root.
+func modify(doc interface{}, path []string, change func(parent interface{}, token string) (interface{}, error), root interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return root, nil
+	}
+	if len(path) == 1 {
+		return change(doc, path[0])
+	}
+
+	token := path[0]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok {
+			return nil, notFound(path[:1])
+		}
+		changed, err := modify(child, path[1:], change, root)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = changed
+		return node, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, notFound(path[:1])
+		}
+		changed, err := modify(node[index], path[1:], change, root)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = changed
+		return node, nil
+	}
+	return nil, notFound(path[:1])
+}
+
+// arrayIndex parses an array index token, which must be between 0 and max. Leading zeros
+// are not allowed.
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max {
+		return 0, fmt.Errorf("array index %q out of range", token)
+	}
+	return index, nil
+}
+
+// notFound returns the error for a path that does not exist
+func notFound(path []string) error {
+	return fmt.Errorf("path %q does not exist", formatPointer(path))
+}
+
+// formatPointer joins reference tokens back into a JSON Pointer
+func formatPointer(path []string) string {
+	var builder strings.Builder
+	for _, token := range path {
+		builder.WriteString("/")
+		builder.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return builder.String()
+}
+
+// isPrefix reports whether prefix is a prefix of path
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// deepCopy copies a decoded JSON value so that copies do not share objects or arrays
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	}
+	return value
+}
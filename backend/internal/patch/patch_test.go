@@ -0,0 +1,127 @@
+package patch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decode decodes a JSON document for the tests
+func decode(t *testing.T, data string) interface{} {
+	var value interface{}
+	require.NoError(t, json.Unmarshal([]byte(data), &value))
+	return value
+}
+
+func TestMergePatch(t *testing.T) {
+	// The examples of RFC 7386 appendix A
+	tests := []struct {
+		name     string
+		target   string
+		patch    string
+		expected string
+	}{
+		{name: "Replace member", target: `{"a":"b"}`, patch: `{"a":"c"}`, expected: `{"a":"c"}`},
+		{name: "Add member", target: `{"a":"b"}`, patch: `{"b":"c"}`, expected: `{"a":"b","b":"c"}`},
+		{name: "Remove member", target: `{"a":"b"}`, patch: `{"a":null}`, expected: `{}`},
+		{name: "Remove one of two members", target: `{"a":"b","b":"c"}`, patch: `{"a":null}`, expected: `{"b":"c"}`},
+		{name: "Replace array with scalar", target: `{"a":["b"]}`, patch: `{"a":"c"}`, expected: `{"a":"c"}`},
+		{name: "Replace scalar with array", target: `{"a":"c"}`, patch: `{"a":["b"]}`, expected: `{"a":["b"]}`},
+		{name: "Merge nested object", target: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, expected: `{"a":{"b":"d"}}`},
+		{name: "Replace array of objects", target: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, expected: `{"a":[1]}`},
+		{name: "Replace array", target: `["a","b"]`, patch: `["c","d"]`, expected: `["c","d"]`},
+		{name: "Replace object with array", target: `{"a":"b"}`, patch: `["c"]`, expected: `["c"]`},
+		{name: "Replace with null", target: `{"a":"foo"}`, patch: `null`, expected: `null`},
+		{name: "Replace with string", target: `{"a":"foo"}`, patch: `"bar"`, expected: `"bar"`},
+		{name: "Null in target is kept", target: `{"e":null}`, patch: `{"a":1}`, expected: `{"e":null,"a":1}`},
+		{name: "Replace array with object", target: `[1,2]`, patch: `{"a":"b","c":null}`, expected: `{"a":"b"}`},
+		{name: "Create nested object", target: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, expected: `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := MergePatch(decode(t, tt.target), decode(t, tt.patch))
+			assert.Equal(t, decode(t, tt.expected), result)
+		})
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        string
+		patch      string
+		expected   string
+		errorIndex int
+		testFailed bool
+	}{
+		{name: "Add object member", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":"qux"}]`, expected: `{"baz":"qux","foo":"bar"}`},
+		{name: "Add array element", doc: `{"foo":["bar","baz"]}`, patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`, expected: `{"foo":["bar","qux","baz"]}`},
+		{name: "Append array element", doc: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, expected: `{"foo":["bar",["abc","def"]]}`},
+		{name: "Add null value", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":null}]`, expected: `{"foo":"bar","baz":null}`},
+		{name: "Add to missing parent", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`, errorIndex: 0},
+		{name: "Remove object member", doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, expected: `{"foo":"bar"}`},
+		{name: "Remove array element", doc: `{"foo":["bar","qux","baz"]}`, patch: `[{"op":"remove","path":"/foo/1"}]`, expected: `{"foo":["bar","baz"]}`},
+		{name: "Remove missing member", doc: `{"foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, errorIndex: 0},
+		{name: "Replace value", doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"replace","path":"/baz","value":"boo"}]`, expected: `{"baz":"boo","foo":"bar"}`},
+		{name: "Replace whole document", doc: `{"foo":"bar"}`, patch: `[{"op":"replace","path":"","value":{"baz":1}}]`, expected: `{"baz":1}`},
+		{name: "Move value", doc: `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, expected: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{name: "Move array element", doc: `{"foo":["all","grass","cows","eat"]}`, patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, expected: `{"foo":["all","cows","eat","grass"]}`},
+		{name: "Move into own child", doc: `{"foo":{"bar":1}}`, patch: `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`, errorIndex: 0},
+		{name: "Copy value", doc: `{"foo":{"bar":1}}`, patch: `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`, expected: `{"foo":{"bar":1},"baz":{"bar":2}}`},
+		{name: "Test success", doc: `{"baz":"qux","foo":["a",2,"c"]}`, patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, expected: `{"baz":"qux","foo":["a",2,"c"]}`},
+		{name: "Test failure", doc: `{"baz":"qux"}`, patch: `[{"op":"add","path":"/a","value":1},{"op":"test","path":"/baz","value":"bar"}]`, errorIndex: 1, testFailed: true},
+		{name: "Test of number against string", doc: `{"baz":"1"}`, patch: `[{"op":"test","path":"/baz","value":1}]`, errorIndex: 0, testFailed: true},
+		{name: "Escaped pointer", doc: `{"/":9,"~1":10}`, patch: `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`, expected: `{"~1":10}`},
+		{name: "Array index out of range", doc: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/2","value":1}]`, errorIndex: 0},
+		{name: "Array index with leading zero", doc: `{"foo":["bar","baz"]}`, patch: `[{"op":"replace","path":"/foo/01","value":1}]`, errorIndex: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operations, err := ParseJSONPatch([]byte(tt.patch))
+			require.NoError(t, err)
+
+			result, err := ApplyJSONPatch(decode(t, tt.doc), operations)
+			if tt.expected == "" {
+				require.Error(t, err)
+				patchErr, ok := err.(*Error)
+				require.True(t, ok)
+				assert.Equal(t, tt.errorIndex, patchErr.Index)
+				assert.Equal(t, tt.testFailed, patchErr.TestFailed)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, decode(t, tt.expected), result)
+		})
+	}
+}
+
+func TestParseJSONPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		valid bool
+	}{
+		{name: "Valid", patch: `[{"op":"add","path":"/a","value":1},{"op":"remove","path":"/b"}]`, valid: true},
+		{name: "Empty", patch: `[]`, valid: true},
+		{name: "Not an array", patch: `{"op":"add","path":"/a","value":1}`},
+		{name: "Unknown op", patch: `[{"op":"merge","path":"/a","value":1}]`},
+		{name: "Missing value", patch: `[{"op":"add","path":"/a"}]`},
+		{name: "Missing from", patch: `[{"op":"move","from":"a","path":"/b"}]`},
+		{name: "Invalid path", patch: `[{"op":"remove","path":"a"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseJSONPatch([]byte(tt.patch))
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
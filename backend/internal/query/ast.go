@@ -0,0 +1,146 @@
+// Package query implements the CI search language: a small boolean expression syntax over
+// CI fields and attributes, e.g.
+//
+//	type = "server" AND attributes.env = "prod" AND attributes.cpu >= 8 AND tags CONTAINS "pci"
+//
+// Parse turns a query into an AST and Compile renders the AST as a parameterized
+// PostgreSQL condition over the configuration_items table.
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Expr is a node of the query AST
+type Expr interface {
+	// Column returns the 1-based column where the expression starts
+	Column() int
+	String() string
+}
+
+// LogicalOp is a boolean connective
+type LogicalOp string
+
+// Logical operators
+const (
+	OpAnd LogicalOp = "AND"
+	OpOr  LogicalOp = "OR"
+)
+
+// Operator is a comparison operator
+type Operator string
+
+// Comparison operators
+const (
+	OpEqual        Operator = "="
+	OpNotEqual     Operator = "!="
+	OpLess         Operator = "<"
+	OpLessEqual    Operator = "<="
+	OpGreater      Operator = ">"
+	OpGreaterEqual Operator = ">="
+	OpContains     Operator = "CONTAINS"
+)
+
+// IsOrdering reports whether the operator compares by order rather than equality
+func (o Operator) IsOrdering() bool {
+	return o == OpLess || o == OpLessEqual || o == OpGreater || o == OpGreaterEqual
+}
+
+// ValueKind is the type of a literal value
+type ValueKind int
+
+// Literal value kinds
+const (
+	KindString ValueKind = iota
+	KindNumber
+	KindBool
+	KindNull
+)
+
+// Value is a literal in a comparison. Text holds the unquoted string, the number as written,
+// "true"/"false" or "null".
+type Value struct {
+	Kind ValueKind
+	Text string
+}
+
+// JSON returns the value encoded as a JSON document, suitable for casting to jsonb
+func (v Value) JSON() string {
+	if v.Kind == KindString {
+		encoded, _ := json.Marshal(v.Text)
+		return string(encoded)
+	}
+	return v.Text
+}
+
+// String returns the value as it would be written in a query
+func (v Value) String() string {
+	if v.Kind == KindString {
+		return fmt.Sprintf("%q", v.Text)
+	}
+	return v.Text
+}
+
+// Field is the left-hand side of a comparison. Path is set for attribute fields and holds
+// the segments after "attributes.".
+type Field struct {
+	Name string
+	Path []string
+}
+
+// IsAttribute reports whether the field refers to a value inside the attributes document
+func (f Field) IsAttribute() bool {
+	return len(f.Path) > 0
+}
+
+// String returns the field as it would be written in a query
+func (f Field) String() string {
+	if f.IsAttribute() {
+		return f.Name + "." + strings.Join(f.Path, ".")
+	}
+	return f.Name
+}
+
+// BinaryExpr combines two expressions with AND or OR
+type BinaryExpr struct {
+	Op    LogicalOp
+	Left  Expr
+	Right Expr
+}
+
+// Column returns the column of the left operand
+func (e *BinaryExpr) Column() int { return e.Left.Column() }
+
+func (e *BinaryExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.Left, e.Op, e.Right)
+}
+
+// NotExpr negates an expression
+type NotExpr struct {
+	Expr   Expr
+	column int
+}
+
+// Column returns the column of the NOT keyword
+func (e *NotExpr) Column() int { return e.column }
+
+func (e *NotExpr) String() string {
+	return fmt.Sprintf("NOT %s", e.Expr)
+}
+
+// Comparison compares a field with a literal value
+type Comparison struct {
+	Field  Field
+	Op     Operator
+	Value  Value
+	column int
+}
+
+// Column returns the column of the field name
+func (e *Comparison) Column() int { return e.column }
+
+func (e *Comparison) String() string {
+	return fmt.Sprintf("%s %s %s", e.Field, e.Op, e.Value)
+}
@@ -0,0 +1,88 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// sqlOperators maps the comparison operators to their SQL spelling
+var sqlOperators = map[Operator]string{
+	OpEqual:        "=",
+	OpNotEqual:     "<>",
+	OpLess:         "<",
+	OpLessEqual:    "<=",
+	OpGreater:      ">",
+	OpGreaterEqual: ">=",
+}
+
+// Compile renders a parsed expression as a SQL condition over configuration_items. bind is
+// called with every value in the query and must return the placeholder to use for it, so the
+// condition can be combined with the caller's own arguments.
+//
+// Attribute comparisons follow JSON semantics: = and != compare jsonb values, so 8 and "8" are
+// different and a missing attribute compares as null. Ordering operators only match
+// attributes of the same JSON type as the literal, and CONTAINS matches an element of an
+// array attribute.
+func Compile(expr Expr, bind func(interface{}) string) string {
+	switch e := expr.(type) {
+	case *BinaryExpr:
+		return fmt.Sprintf("(%s %s %s)", Compile(e.Left, bind), e.Op, Compile(e.Right, bind))
+	case *NotExpr:
+		return fmt.Sprintf("NOT (%s)", Compile(e.Expr, bind))
+	case *Comparison:
+		if e.Field.IsAttribute() {
+			return compileAttribute(e, bind)
+		}
+		return compileColumn(e, bind)
+	default:
+		panic(fmt.Sprintf("query: unexpected expression %T", expr))
+	}
+}
+
+// compileColumn renders a comparison on one of the configuration_items columns
+func compileColumn(c *Comparison, bind func(interface{}) string) string {
+	switch c.Field.Name {
+	case "tags":
+		return fmt.Sprintf("%s = ANY(tags)", bind(c.Value.Text))
+	case "created_at", "updated_at":
+		// Parse has already checked the timestamp
+		t, _ := time.Parse(time.RFC3339, c.Value.Text)
+		return fmt.Sprintf("%s %s %s", c.Field.Name, sqlOperators[c.Op], bind(t))
+	}
+
+	if c.Op == OpContains {
+		return fmt.Sprintf("%s ILIKE %s", c.Field.Name, bind("%"+EscapeLike(c.Value.Text)+"%"))
+	}
+	return fmt.Sprintf("%s %s %s", c.Field.Name, sqlOperators[c.Op], bind(c.Value.Text))
+}
+
+// compileAttribute renders a comparison on a value inside the attributes document
+func compileAttribute(c *Comparison, bind func(interface{}) string) string {
+	path := bind(pq.Array(c.Field.Path)) + "::text[]"
+
+	switch {
+	case c.Op == OpContains:
+		return fmt.Sprintf("attributes #> %s @> %s::jsonb", path, bind(c.Value.JSON()))
+	case c.Op.IsOrdering() && c.Value.Kind == KindNumber:
+		return fmt.Sprintf(
+			"CASE WHEN jsonb_typeof(attributes #> %s) = 'number' THEN (attributes #>> %s)::numeric END %s %s::numeric",
+			path, path, sqlOperators[c.Op], bind(c.Value.Text),
+		)
+	case c.Op.IsOrdering():
+		return fmt.Sprintf(
+			"CASE WHEN jsonb_typeof(attributes #> %s) = 'string' THEN attributes #>> %s END %s %s",
+			path, path, sqlOperators[c.Op], bind(c.Value.Text),
+		)
+	default:
+		return fmt.Sprintf("COALESCE(attributes #> %s, 'null'::jsonb) %s %s::jsonb", path, sqlOperators[c.Op], bind(c.Value.JSON()))
+	}
+}
+
+// EscapeLike escapes the LIKE wildcards in a user supplied pattern
+func EscapeLike(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(s)
+}
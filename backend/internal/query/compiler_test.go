@@ -0,0 +1,80 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expectedSQL  string
+		expectedArgs []interface{}
+	}{
+		{
+			name:         "Column equality",
+			input:        `type = "server"`,
+			expectedSQL:  "type = $1",
+			expectedArgs: []interface{}{"server"},
+		},
+		{
+			name:         "Tags contains",
+			input:        `tags CONTAINS "pci"`,
+			expectedSQL:  "$1 = ANY(tags)",
+			expectedArgs: []interface{}{"pci"},
+		},
+		{
+			name:         "Name contains escapes wildcards",
+			input:        `name CONTAINS "50%_off"`,
+			expectedSQL:  "name ILIKE $1",
+			expectedArgs: []interface{}{`%50\%\_off%`},
+		},
+		{
+			name:         "Attribute equality",
+			input:        `attributes.env = "prod"`,
+			expectedSQL:  "COALESCE(attributes #> $1::text[], 'null'::jsonb) = $2::jsonb",
+			expectedArgs: []interface{}{pq.Array([]string{"env"}), `"prod"`},
+		},
+		{
+			name:         "Numeric attribute ordering",
+			input:        `attributes.hw.cpu >= 8`,
+			expectedSQL:  "CASE WHEN jsonb_typeof(attributes #> $1::text[]) = 'number' THEN (attributes #>> $1::text[])::numeric END >= $2::numeric",
+			expectedArgs: []interface{}{pq.Array([]string{"hw", "cpu"}), "8"},
+		},
+		{
+			name:         "Attribute array contains",
+			input:        `attributes.zones CONTAINS "eu-west-1"`,
+			expectedSQL:  "attributes #> $1::text[] @> $2::jsonb",
+			expectedArgs: []interface{}{pq.Array([]string{"zones"}), `"eu-west-1"`},
+		},
+		{
+			name:        "Logical operators",
+			input:       `type = "server" AND NOT (attributes.env = "prod" OR tags CONTAINS "pci")`,
+			expectedSQL: "(type = $1 AND NOT ((COALESCE(attributes #> $2::text[], 'null'::jsonb) = $3::jsonb OR $4 = ANY(tags))))",
+			expectedArgs: []interface{}{
+				"server", pq.Array([]string{"env"}), `"prod"`, "pci",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			require.NoError(t, err)
+
+			var args []interface{}
+			sql := Compile(expr, func(value interface{}) string {
+				args = append(args, value)
+				return fmt.Sprintf("$%d", len(args))
+			})
+
+			assert.Equal(t, tt.expectedSQL, sql)
+			assert.Equal(t, tt.expectedArgs, args)
+		})
+	}
+}
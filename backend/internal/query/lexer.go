@@ -0,0 +1,178 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifies the lexical class of a token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenContains
+	tokenTrue
+	tokenFalse
+	tokenNull
+)
+
+// keywords maps the upper-cased keywords to their token kinds. Keywords are case-insensitive.
+var keywords = map[string]tokenKind{
+	"AND":      tokenAnd,
+	"OR":       tokenOr,
+	"NOT":      tokenNot,
+	"CONTAINS": tokenContains,
+	"TRUE":     tokenTrue,
+	"FALSE":    tokenFalse,
+	"NULL":     tokenNull,
+}
+
+// token is a single lexical token. Column is the 1-based column of its first character.
+type token struct {
+	kind   tokenKind
+	text   string
+	column int
+}
+
+// describe returns a human readable description of the token for error messages
+func (t token) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// lexer splits a query into tokens
+type lexer struct {
+	input []rune
+	pos   int
+}
+
+// newLexer creates a lexer for the query
+func newLexer(input string) *lexer {
+	return &lexer{input: []rune(input)}
+}
+
+// next returns the next token, or a SyntaxError for malformed input
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.input) && unicode.IsSpace(l.input[l.pos]) {
+		l.pos++
+	}
+
+	start := l.pos
+	column := start + 1
+	if l.pos >= len(l.input) {
+		return token{kind: tokenEOF, column: column}, nil
+	}
+
+	c := l.input[l.pos]
+	switch {
+	case c == '(':
+		l.pos++
+		return token{kind: tokenLParen, text: "(", column: column}, nil
+	case c == ')':
+		l.pos++
+		return token{kind: tokenRParen, text: ")", column: column}, nil
+	case c == '=':
+		l.pos++
+		return token{kind: tokenOperator, text: "=", column: column}, nil
+	case c == '!' || c == '<' || c == '>':
+		l.pos++
+		if l.pos < len(l.input) && l.input[l.pos] == '=' {
+			l.pos++
+			return token{kind: tokenOperator, text: string(c) + "=", column: column}, nil
+		}
+		if c == '!' {
+			return token{}, newSyntaxError(column, "unexpected character '!', did you mean '!='?")
+		}
+		return token{kind: tokenOperator, text: string(c), column: column}, nil
+	case c == '"' || c == '\'':
+		return l.lexString(c)
+	case c == '-' || c == '.' || unicode.IsDigit(c):
+		return l.lexNumber()
+	case c == '_' || unicode.IsLetter(c):
+		for l.pos < len(l.input) && isIdentRune(l.input[l.pos]) {
+			l.pos++
+		}
+		text := string(l.input[start:l.pos])
+		if kind, ok := keywords[strings.ToUpper(text)]; ok {
+			return token{kind: kind, text: text, column: column}, nil
+		}
+		return token{kind: tokenIdent, text: text, column: column}, nil
+	default:
+		return token{}, newSyntaxError(column, fmt.Sprintf("unexpected character %q", c))
+	}
+}
+
+// lexString reads a string literal delimited by quote. A backslash escapes the next character.
+func (l *lexer) lexString(quote rune) (token, error) {
+	column := l.pos + 1
+	l.pos++
+
+	var sb strings.Builder
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		l.pos++
+		switch c {
+		case quote:
+			return token{kind: tokenString, text: sb.String(), column: column}, nil
+		case '\\':
+			if l.pos >= len(l.input) {
+				return token{}, newSyntaxError(column, "unterminated string")
+			}
+			sb.WriteRune(l.input[l.pos])
+			l.pos++
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return token{}, newSyntaxError(column, "unterminated string")
+}
+
+// lexNumber reads an optionally signed decimal number such as 8, -2 or 0.75
+func (l *lexer) lexNumber() (token, error) {
+	start := l.pos
+	column := start + 1
+	if l.input[l.pos] == '-' {
+		l.pos++
+	}
+
+	digits, dots := 0, 0
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		if c == '.' {
+			dots++
+		} else if unicode.IsDigit(c) {
+			digits++
+		} else {
+			break
+		}
+		l.pos++
+	}
+
+	text := string(l.input[start:l.pos])
+	if digits == 0 || dots > 1 || strings.HasSuffix(text, ".") {
+		return token{}, newSyntaxError(column, fmt.Sprintf("invalid number %q", text))
+	}
+	return token{kind: tokenNumber, text: text, column: column}, nil
+}
+
+// isIdentRune reports whether c may appear after the first character of a field name.
+// Dots separate attribute path segments and hyphens are common in attribute keys.
+func isIdentRune(c rune) bool {
+	return c == '_' || c == '.' || c == '-' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
@@ -0,0 +1,277 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxQueryLength bounds the size of a query accepted by Parse
+const MaxQueryLength = 4096
+
+// attributesField is the field prefix for attribute paths
+const attributesField = "attributes"
+
+// SyntaxError describes an invalid query. Column is the 1-based column of the offending token.
+type SyntaxError struct {
+	Column  int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Message)
+}
+
+// newSyntaxError creates a SyntaxError at the column
+func newSyntaxError(column int, message string) *SyntaxError {
+	return &SyntaxError{Column: column, Message: message}
+}
+
+// Parse parses a query into an AST. Every error returned is a *SyntaxError.
+//
+// Grammar:
+//
+//	expr       = and { OR and }
+//	and        = unary { AND unary }
+//	unary      = NOT unary | "(" expr ")" | comparison
+//	comparison = field ( "=" | "!=" | "<" | "<=" | ">" | ">=" | CONTAINS ) value
+//	value      = string | number | TRUE | FALSE | NULL
+func Parse(input string) (Expr, error) {
+	if len(input) > MaxQueryLength {
+		return nil, newSyntaxError(1, fmt.Sprintf("query must be at most %d characters", MaxQueryLength))
+	}
+
+	p := &parser{lexer: newLexer(input)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenEOF {
+		return nil, newSyntaxError(p.tok.column, "query is empty")
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.unexpected("AND, OR or end of query")
+	}
+	return expr, nil
+}
+
+// parser is a recursive descent parser holding one token of look-ahead
+type parser struct {
+	lexer *lexer
+	tok   token
+}
+
+// advance moves to the next token
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// unexpected returns an error for the current token
+func (p *parser) unexpected(expected string) error {
+	return newSyntaxError(p.tok.column, fmt.Sprintf("expected %s, found %s", expected, p.tok.describe()))
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokenOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: OpOr, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokenAnd {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: OpAnd, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	switch p.tok.kind {
+	case tokenNot:
+		column := p.tok.column
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{Expr: expr, column: column}, nil
+	case tokenLParen:
+		column := p.tok.column
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokenRParen {
+			if p.tok.kind == tokenEOF {
+				return nil, newSyntaxError(column, "unclosed parenthesis")
+			}
+			return nil, p.unexpected("')'")
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	if p.tok.kind != tokenIdent {
+		return nil, p.unexpected("field name")
+	}
+	fieldTok := p.tok
+	field, err := parseField(fieldTok)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var op Operator
+	switch p.tok.kind {
+	case tokenOperator:
+		op = Operator(p.tok.text)
+	case tokenContains:
+		op = OpContains
+	default:
+		return nil, p.unexpected("comparison operator")
+	}
+	opColumn := p.tok.column
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var value Value
+	valueTok := p.tok
+	switch p.tok.kind {
+	case tokenString:
+		value = Value{Kind: KindString, Text: p.tok.text}
+	case tokenNumber:
+		value = Value{Kind: KindNumber, Text: p.tok.text}
+	case tokenTrue, tokenFalse:
+		value = Value{Kind: KindBool, Text: strings.ToLower(p.tok.text)}
+	case tokenNull:
+		value = Value{Kind: KindNull, Text: "null"}
+	default:
+		return nil, p.unexpected("value")
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	comparison := &Comparison{Field: field, Op: op, Value: value, column: fieldTok.column}
+	if err := checkComparison(comparison, opColumn, valueTok.column); err != nil {
+		return nil, err
+	}
+	return comparison, nil
+}
+
+// parseField validates a field name token
+func parseField(tok token) (Field, error) {
+	name, path, hasPath := strings.Cut(tok.text, ".")
+	if name == attributesField {
+		if !hasPath {
+			return Field{}, newSyntaxError(tok.column, "attributes must be followed by a path, e.g. attributes.env")
+		}
+		segments := strings.Split(path, ".")
+		for _, segment := range segments {
+			if segment == "" {
+				return Field{}, newSyntaxError(tok.column, fmt.Sprintf("invalid attribute path %q", tok.text))
+			}
+		}
+		return Field{Name: attributesField, Path: segments}, nil
+	}
+
+	switch tok.text {
+	case "id", "name", "type", "tags", "created_at", "updated_at":
+		return Field{Name: tok.text}, nil
+	}
+	return Field{}, newSyntaxError(tok.column, fmt.Sprintf(
+		"unknown field %q; expected one of id, name, type, tags, created_at, updated_at or attributes.<path>", tok.text))
+}
+
+// checkComparison verifies the operator and value are valid for the field
+func checkComparison(c *Comparison, opColumn, valueColumn int) error {
+	field := c.Field.String()
+
+	if c.Field.IsAttribute() {
+		switch {
+		case c.Op.IsOrdering() && c.Value.Kind != KindString && c.Value.Kind != KindNumber:
+			return newSyntaxError(valueColumn, fmt.Sprintf("%s can only be compared with a string or number", c.Op))
+		case c.Op == OpContains && c.Value.Kind == KindNull:
+			return newSyntaxError(valueColumn, "CONTAINS requires a non-null value")
+		}
+		return nil
+	}
+
+	switch c.Field.Name {
+	case "tags":
+		if c.Op != OpContains {
+			return newSyntaxError(opColumn, "tags only supports CONTAINS")
+		}
+	case "id":
+		if c.Op != OpEqual && c.Op != OpNotEqual {
+			return newSyntaxError(opColumn, "id only supports = and !=")
+		}
+	case "created_at", "updated_at":
+		if c.Op == OpContains {
+			return newSyntaxError(opColumn, fmt.Sprintf("%s does not support CONTAINS", field))
+		}
+	}
+
+	if c.Value.Kind != KindString {
+		return newSyntaxError(valueColumn, fmt.Sprintf("%s must be compared with a string", field))
+	}
+
+	switch c.Field.Name {
+	case "id":
+		if _, err := uuid.Parse(c.Value.Text); err != nil {
+			return newSyntaxError(valueColumn, "id must be a valid UUID")
+		}
+	case "created_at", "updated_at":
+		if _, err := time.Parse(time.RFC3339, c.Value.Text); err != nil {
+			return newSyntaxError(valueColumn, fmt.Sprintf("%s must be an RFC3339 timestamp", field))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Single comparison",
+			input:    `type = "server"`,
+			expected: `type = "server"`,
+		},
+		{
+			name:     "AND binds tighter than OR",
+			input:    `type = "server" OR type = "database" AND attributes.env = "prod"`,
+			expected: `(type = "server" OR (type = "database" AND attributes.env = "prod"))`,
+		},
+		{
+			name:     "Parentheses and NOT",
+			input:    `NOT (attributes.env = 'prod' or attributes.env = 'staging') and tags contains "pci"`,
+			expected: `(NOT (attributes.env = "prod" OR attributes.env = "staging") AND tags CONTAINS "pci")`,
+		},
+		{
+			name:     "Typed values",
+			input:    `attributes.cpu >= 8 AND attributes.load < -0.5 AND attributes.active != false AND attributes.owner = null`,
+			expected: `(((attributes.cpu >= 8 AND attributes.load < -0.5) AND attributes.active != false) AND attributes.owner = null)`,
+		},
+		{
+			name:     "Escaped quotes and nested attribute paths",
+			input:    `attributes.network.host-name = "db \"primary\""`,
+			expected: `attributes.network.host-name = "db \"primary\""`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, expr.String())
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		expectedColumn int
+		expectedError  string
+	}{
+		{
+			name:           "Empty query",
+			input:          "   ",
+			expectedColumn: 4,
+			expectedError:  "query is empty",
+		},
+		{
+			name:           "Missing value",
+			input:          `type = `,
+			expectedColumn: 8,
+			expectedError:  "expected value, found end of query",
+		},
+		{
+			name:           "Unknown field",
+			input:          `type = "server" AND color = "red"`,
+			expectedColumn: 21,
+			expectedError:  `unknown field "color"`,
+		},
+		{
+			name:           "Unterminated string",
+			input:          `name = "web`,
+			expectedColumn: 8,
+			expectedError:  "unterminated string",
+		},
+		{
+			name:           "Unclosed parenthesis",
+			input:          `(type = "server"`,
+			expectedColumn: 1,
+			expectedError:  "unclosed parenthesis",
+		},
+		{
+			name:           "Trailing tokens",
+			input:          `type = "server" "db"`,
+			expectedColumn: 17,
+			expectedError:  "expected AND, OR or end of query",
+		},
+		{
+			name:           "Tags require CONTAINS",
+			input:          `tags = "pci"`,
+			expectedColumn: 6,
+			expectedError:  "tags only supports CONTAINS",
+		},
+		{
+			name:           "Ordering on a boolean",
+			input:          `attributes.active > true`,
+			expectedColumn: 21,
+			expectedError:  "can only be compared with a string or number",
+		},
+		{
+			name:           "Invalid timestamp",
+			input:          `created_at > "yesterday"`,
+			expectedColumn: 14,
+			expectedError:  "RFC3339",
+		},
+		{
+			name:           "Invalid character",
+			input:          `type ~ "server"`,
+			expectedColumn: 6,
+			expectedError:  "unexpected character",
+		},
+		{
+			name:           "Bare attributes field",
+			input:          `attributes = "x"`,
+			expectedColumn: 1,
+			expectedError:  "must be followed by a path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+
+			require.Error(t, err)
+			syntaxErr, ok := err.(*SyntaxError)
+			require.True(t, ok, "expected *SyntaxError, got %T", err)
+			assert.Equal(t, tt.expectedColumn, syntaxErr.Column)
+			assert.Contains(t, syntaxErr.Message, tt.expectedError)
+		})
+	}
+}
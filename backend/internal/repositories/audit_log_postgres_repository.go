@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/cmdb-lite/backend/internal/models"
 	"github.com/google/uuid"
@@ -12,7 +13,7 @@ import (
 
 // AuditLogPostgresRepository implements the AuditLogRepository interface for PostgreSQL
 type AuditLogPostgresRepository struct {
-	db *sqlx.DB
+	db dbtx
 }
 
 // NewAuditLogPostgresRepository creates a new AuditLogPostgresRepository
@@ -20,28 +21,91 @@ func NewAuditLogPostgresRepository(db *sqlx.DB) *AuditLogPostgresRepository {
 	return &AuditLogPostgresRepository{db: db}
 }
 
-// Create creates a new audit log in the database
+// auditLogEventPayload is the JSON of the event an audit log "a" records, as it is published
+// to webhooks and through the outbox
+const auditLogEventPayload = `jsonb_build_object(
+			'id', a.id,
+			'type', a.entity_type || '.' || a.action,
+			'entity_type', a.entity_type,
+			'entity_id', a.entity_id,
+			'action', a.action,
+			'changed_by', a.changed_by,
+			'changed_at', a.changed_at,
+			'details', a.details
+		)`
+
+// Create creates a new audit log in the database, together with the webhook deliveries and
+// outbox event of the event it records
 func (r *AuditLogPostgresRepository) Create(ctx context.Context, auditLog *models.AuditLog) error {
 	query := `
 		INSERT INTO audit_logs (id, entity_type, entity_id, action, changed_by, changed_at, details)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 	
-	_, err := r.db.ExecContext(ctx, query,
-		auditLog.ID,
-		auditLog.EntityType,
-		auditLog.EntityID,
-		auditLog.Action,
-		auditLog.ChangedBy,
-		auditLog.ChangedAt,
-		auditLog.Details,
-	)
-	
-	if err != nil {
+	return inTx(ctx, r.db, func(tx dbtx) error {
+		_, err := tx.ExecContext(ctx, query,
+			auditLog.ID,
+			auditLog.EntityType,
+			auditLog.EntityID,
+			auditLog.Action,
+			auditLog.ChangedBy,
+			auditLog.ChangedAt,
+			auditLog.Details,
+		)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditLogEvents(ctx, tx, []uuid.UUID{auditLog.ID})
+	})
+}
+
+// CreateBatch creates the audit logs with one statement, together with the webhook deliveries
+// and outbox events of the events they record
+func (r *AuditLogPostgresRepository) CreateBatch(ctx context.Context, auditLogs []*models.AuditLog) error {
+	if len(auditLogs) == 0 {
+		return nil
+	}
+
+	b := &queryBuilder{}
+	rows := make([][]interface{}, len(auditLogs))
+	for i, auditLog := range auditLogs {
+		rows[i] = []interface{}{
+			auditLog.ID,
+			auditLog.EntityType,
+			auditLog.EntityID,
+			auditLog.Action,
+			auditLog.ChangedBy,
+			auditLog.ChangedAt,
+			auditLog.Details,
+		}
+	}
+	values := b.valuesList([]string{"uuid", "text", "uuid", "text", "text", "timestamptz", "jsonb"}, rows)
+
+	query := `
+		INSERT INTO audit_logs (id, entity_type, entity_id, action, changed_by, changed_at, details)
+		` + values
+
+	ids := make([]uuid.UUID, len(auditLogs))
+	for i, auditLog := range auditLogs {
+		ids[i] = auditLog.ID
+	}
+
+	return inTx(ctx, r.db, func(tx dbtx) error {
+		if _, err := tx.ExecContext(ctx, query, b.args...); err != nil {
+			return err
+		}
+		return recordAuditLogEvents(ctx, tx, ids)
+	})
+}
+
+// recordAuditLogEvents creates the webhook deliveries and outbox events of the events recorded
+// by audit logs, in the transaction that created the audit logs
+func recordAuditLogEvents(ctx context.Context, tx dbtx, auditLogIDs []uuid.UUID) error {
+	if err := enqueueWebhookDeliveries(ctx, tx, auditLogIDs); err != nil {
 		return err
 	}
-	
-	return nil
+	return enqueueOutboxEvents(ctx, tx, auditLogIDs)
 }
 
 // GetByID retrieves an audit log by ID
@@ -154,4 +218,92 @@ func (r *AuditLogPostgresRepository) Delete(ctx context.Context, id uuid.UUID) e
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// auditLogColumns is the column list selected for every audit log query
+const auditLogColumns = "id, entity_type, entity_id, action, changed_by, changed_at, details"
+
+// newAuditLogQueryBuilder creates a query builder with the WHERE conditions for the filter
+func newAuditLogQueryBuilder(filter AuditLogFilter) *queryBuilder {
+	b := &queryBuilder{}
+
+	if filter.EntityType != "" {
+		b.where("entity_type = " + b.bind(filter.EntityType))
+	}
+	if filter.EntityID != uuid.Nil {
+		b.where("entity_id = " + b.bind(filter.EntityID))
+	}
+	if filter.ChangedBy != "" {
+		b.where("changed_by = " + b.bind(filter.ChangedBy))
+	}
+
+	return b
+}
+
+// List retrieves the audit logs matching the filter, newest first
+func (r *AuditLogPostgresRepository) List(ctx context.Context, filter AuditLogFilter) ([]*models.AuditLog, error) {
+	builder := newAuditLogQueryBuilder(filter)
+	query := auditLogSelectQuery(builder, filter)
+
+	var auditLogs []*models.AuditLog
+	err := r.db.SelectContext(ctx, &auditLogs, query, builder.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return auditLogs, nil
+}
+
+// Count returns the number of audit logs matching the filter, ignoring pagination
+func (r *AuditLogPostgresRepository) Count(ctx context.Context, filter AuditLogFilter) (int, error) {
+	builder := newAuditLogQueryBuilder(filter)
+	query := "SELECT COUNT(*) FROM audit_logs " + builder.whereClause()
+
+	var count int
+	err := r.db.GetContext(ctx, &count, query, builder.args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// auditLogSelectQuery renders the SELECT statement of the filter with ordering and pagination
+func auditLogSelectQuery(builder *queryBuilder, filter AuditLogFilter) string {
+	if filter.Cursor != nil {
+		return builder.keysetQuery(auditLogColumns, "audit_logs", "changed_at", filter.Cursor, filter.Limit)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM audit_logs %s ORDER BY changed_at DESC, id DESC", auditLogColumns, builder.whereClause())
+	if filter.Limit > 0 {
+		query += " LIMIT " + builder.bind(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += " OFFSET " + builder.bind(filter.Offset)
+	}
+	return query
+}
+
+// Stream calls fn with each audit log matching the filter as the audit logs are read from the
+// database
+func (r *AuditLogPostgresRepository) Stream(ctx context.Context, filter AuditLogFilter, fn func(auditLog *models.AuditLog) error) error {
+	builder := newAuditLogQueryBuilder(filter)
+	query := auditLogSelectQuery(builder, filter)
+
+	return streamRows(ctx, r.db, query, builder.args, fn)
+}
+
+// DetailPaths returns the sorted dotted paths of the detail values of the audit logs matching
+// the filter
+func (r *AuditLogPostgresRepository) DetailPaths(ctx context.Context, filter AuditLogFilter) ([]string, error) {
+	builder := newAuditLogQueryBuilder(filter)
+	query := jsonPathsQuery("details", "SELECT details FROM audit_logs "+builder.whereClause())
+
+	paths := []string{}
+	err := r.db.SelectContext(ctx, &paths, query, builder.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
@@ -4,14 +4,32 @@ import (
 	"context"
 
 	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/pagination"
 	"github.com/google/uuid"
 )
 
+// AuditLogFilter describes the filtering and pagination options used when listing audit logs.
+// Zero values mean "no restriction"; a zero Limit returns every matching row.
+type AuditLogFilter struct {
+	EntityType string
+	EntityID   uuid.UUID
+	ChangedBy  string
+
+	Limit  int
+	Offset int
+
+	// Cursor, when set, pages by (changed_at, id) from the cursor position instead of using Offset
+	Cursor *pagination.Cursor
+}
+
 // AuditLogRepository defines the interface for audit log repository operations
 type AuditLogRepository interface {
 	// Create creates a new audit log in the database
 	Create(ctx context.Context, auditLog *models.AuditLog) error
 
+	// CreateBatch creates the audit logs with one statement
+	CreateBatch(ctx context.Context, auditLogs []*models.AuditLog) error
+
 	// GetByID retrieves an audit log by ID
 	GetByID(ctx context.Context, id uuid.UUID) (*models.AuditLog, error)
 
@@ -29,4 +47,18 @@ type AuditLogRepository interface {
 
 	// Delete deletes an audit log from the database
 	Delete(ctx context.Context, id uuid.UUID) error
+
+	// List retrieves the audit logs matching the filter, newest first
+	List(ctx context.Context, filter AuditLogFilter) ([]*models.AuditLog, error)
+
+	// Count returns the number of audit logs matching the filter, ignoring pagination
+	Count(ctx context.Context, filter AuditLogFilter) (int, error)
+
+	// Stream calls fn with each audit log matching the filter, newest first, as the audit
+	// logs are read from the database. It stops at the first error fn returns.
+	Stream(ctx context.Context, filter AuditLogFilter, fn func(auditLog *models.AuditLog) error) error
+
+	// DetailPaths returns the sorted dotted paths of the detail values of the audit logs
+	// matching the filter, ignoring pagination
+	DetailPaths(ctx context.Context, filter AuditLogFilter) ([]string, error)
 }
@@ -4,15 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/cmdb-lite/backend/internal/models"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // CIPostgresRepository implements the CIRepository interface for PostgreSQL
 type CIPostgresRepository struct {
-	db *sqlx.DB
+	db dbtx
 }
 
 // NewCIPostgresRepository creates a new CIPostgresRepository
@@ -23,10 +25,11 @@ func NewCIPostgresRepository(db *sqlx.DB) *CIPostgresRepository {
 // Create creates a new CI in the database
 func (r *CIPostgresRepository) Create(ctx context.Context, ci *models.CI) error {
 	query := `
-		INSERT INTO configuration_items (id, name, type, attributes, tags, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO configuration_items (id, name, type, attributes, tags, version, created_at, updated_at, search_vector)
+		VALUES ($1, $2, $3, $4, $5, 1, $6, $7, ci_search_vector($2, $3, $5, $4))
 	`
 
+	ci.Version = 1
 	_, err := r.db.ExecContext(ctx, query,
 		ci.ID,
 		ci.Name,
@@ -47,9 +50,9 @@ func (r *CIPostgresRepository) Create(ctx context.Context, ci *models.CI) error
 // GetByID retrieves a CI by ID
 func (r *CIPostgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.CI, error) {
 	query := `
-		SELECT id, name, type, attributes, tags, created_at, updated_at
+		SELECT id, name, type, attributes, tags, version, created_at, updated_at
 		FROM configuration_items
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	var ci models.CI
@@ -64,19 +67,61 @@ func (r *CIPostgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*mode
 	return &ci, nil
 }
 
-// GetByName retrieves a CI by name
+// Lock locks the rows of the CIs in ID order, so that transactions locking the same CIs cannot
+// deadlock. The lock leaves the CIs' keys alone, so relationships to them can still be created.
+func (r *CIPostgresRepository) Lock(ctx context.Context, ids ...uuid.UUID) error {
+	query := `
+		SELECT id
+		FROM configuration_items
+		WHERE id = ANY($1::uuid[])
+		ORDER BY id
+		FOR NO KEY UPDATE
+	`
+
+	var locked []uuid.UUID
+	return r.db.SelectContext(ctx, &locked, query, pq.Array(uuidStrings(ids)))
+}
+
+// GetByName retrieves a CI by name. Names are not unique, so it fails with ErrNameNotUnique
+// rather than pick one of several CIs with the name.
 func (r *CIPostgresRepository) GetByName(ctx context.Context, name string) (*models.CI, error) {
 	query := `
-		SELECT id, name, type, attributes, tags, created_at, updated_at
+		SELECT id, name, type, attributes, tags, version, created_at, updated_at
 		FROM configuration_items
-		WHERE name = $1
+		WHERE name = $1 AND deleted_at IS NULL
+		LIMIT 2
+	`
+
+	var cis []*models.CI
+	err := r.db.SelectContext(ctx, &cis, query, name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(cis) {
+	case 0:
+		return nil, errors.New("CI not found")
+	case 1:
+		return cis[0], nil
+	default:
+		return nil, ErrNameNotUnique
+	}
+}
+
+// GetByExternalID retrieves the CI with an external ID
+func (r *CIPostgresRepository) GetByExternalID(ctx context.Context, source, externalID string) (*models.CI, error) {
+	query := `
+		SELECT ci.id, ci.name, ci.type, ci.attributes, ci.tags, ci.version, ci.created_at, ci.updated_at
+		FROM configuration_items ci
+		JOIN ci_external_ids ext ON ext.ci_id = ci.id
+		WHERE ext.source = $1 AND ext.external_id = $2 AND ci.deleted_at IS NULL
 	`
 
 	var ci models.CI
-	err := r.db.GetContext(ctx, &ci, query, name)
+	err := r.db.GetContext(ctx, &ci, query, source, externalID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("CI not found")
+			return nil, ErrCINotFound
 		}
 		return nil, err
 	}
@@ -84,52 +129,143 @@ func (r *CIPostgresRepository) GetByName(ctx context.Context, name string) (*mod
 	return &ci, nil
 }
 
-// GetAll retrieves all CIs from the database
-func (r *CIPostgresRepository) GetAll(ctx context.Context) ([]*models.CI, error) {
+// AddExternalID gives a CI an external ID, unless the external ID already belongs to a CI
+func (r *CIPostgresRepository) AddExternalID(ctx context.Context, externalID *models.CIExternalID) error {
 	query := `
-		SELECT id, name, type, attributes, tags, created_at, updated_at
-		FROM configuration_items
-		ORDER BY created_at DESC
+		INSERT INTO ci_external_ids (source, external_id, ci_id, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (source, external_id) DO NOTHING
 	`
 
-	var cis []*models.CI
-	err := r.db.SelectContext(ctx, &cis, query)
+	result, err := r.db.ExecContext(ctx, query,
+		externalID.Source,
+		externalID.ExternalID,
+		externalID.CIID,
+		externalID.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	// The external ID exists already, which is fine if it is the CI's own
+	var owner uuid.UUID
+	err = r.db.GetContext(ctx, &owner, `
+		SELECT ci_id FROM ci_external_ids WHERE source = $1 AND external_id = $2
+	`, externalID.Source, externalID.ExternalID)
+	if err != nil {
+		return err
+	}
+
+	if owner != externalID.CIID {
+		return ErrExternalIDTaken
+	}
+	return nil
+}
+
+// ListExternalIDs retrieves the external IDs of a CI, ordered by source and external ID
+func (r *CIPostgresRepository) ListExternalIDs(ctx context.Context, ciID uuid.UUID) ([]*models.CIExternalID, error) {
+	query := `
+		SELECT ci_id, source, external_id, created_at
+		FROM ci_external_ids
+		WHERE ci_id = $1
+		ORDER BY source, external_id
+	`
+
+	externalIDs := []*models.CIExternalID{}
+	err := r.db.SelectContext(ctx, &externalIDs, query, ciID)
 	if err != nil {
 		return nil, err
 	}
 
-	return cis, nil
+	return externalIDs, nil
 }
 
-// GetByType retrieves CIs by type
-func (r *CIPostgresRepository) GetByType(ctx context.Context, ciType string) ([]*models.CI, error) {
+// FindExternalIDs retrieves the given external IDs of a source that belong to a CI, live or in
+// the trash
+func (r *CIPostgresRepository) FindExternalIDs(ctx context.Context, source string, externalIDs []string) ([]*models.CIExternalID, error) {
 	query := `
-		SELECT id, name, type, attributes, tags, created_at, updated_at
-		FROM configuration_items
-		WHERE type = $1
-		ORDER BY created_at DESC
+		SELECT ci_id, source, external_id, created_at
+		FROM ci_external_ids
+		WHERE source = $1 AND external_id = ANY($2)
 	`
 
-	var cis []*models.CI
-	err := r.db.SelectContext(ctx, &cis, query, ciType)
+	found := []*models.CIExternalID{}
+	err := r.db.SelectContext(ctx, &found, query, source, pq.Array(externalIDs))
 	if err != nil {
 		return nil, err
 	}
 
-	return cis, nil
+	return found, nil
 }
 
-// GetByStatus retrieves CIs by status
-func (r *CIPostgresRepository) GetByStatus(ctx context.Context, status string) ([]*models.CI, error) {
+// AddExternalIDs gives CIs external IDs with one statement. It returns ErrExternalIDTaken if
+// any of the external IDs exists already.
+func (r *CIPostgresRepository) AddExternalIDs(ctx context.Context, externalIDs []*models.CIExternalID) error {
+	if len(externalIDs) == 0 {
+		return nil
+	}
+
+	b := &queryBuilder{}
+	rows := make([][]interface{}, len(externalIDs))
+	for i, externalID := range externalIDs {
+		rows[i] = []interface{}{externalID.Source, externalID.ExternalID, externalID.CIID, externalID.CreatedAt}
+	}
+	values := b.valuesList([]string{"text", "text", "uuid", "timestamptz"}, rows)
+
 	query := `
-		SELECT id, name, type, attributes, tags, created_at, updated_at
-		FROM configuration_items
-		WHERE attributes->>'status' = $1
-		ORDER BY created_at DESC
+		INSERT INTO ci_external_ids (source, external_id, ci_id, created_at)
+		SELECT v.source, v.external_id, v.ci_id, v.created_at
+		FROM (` + values + `) AS v(source, external_id, ci_id, created_at)
+		ON CONFLICT (source, external_id) DO NOTHING
 	`
 
+	result, err := r.db.ExecContext(ctx, query, b.args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != int64(len(externalIDs)) {
+		return ErrExternalIDTaken
+	}
+	return nil
+}
+
+// GetAll retrieves all CIs from the database
+func (r *CIPostgresRepository) GetAll(ctx context.Context) ([]*models.CI, error) {
+	return r.List(ctx, CIFilter{})
+}
+
+// GetByType retrieves CIs by type
+func (r *CIPostgresRepository) GetByType(ctx context.Context, ciType string) ([]*models.CI, error) {
+	return r.List(ctx, CIFilter{Type: ciType})
+}
+
+// GetByStatus retrieves CIs by status
+func (r *CIPostgresRepository) GetByStatus(ctx context.Context, status string) ([]*models.CI, error) {
+	return r.List(ctx, CIFilter{Attributes: map[string]string{"status": status}})
+}
+
+// List retrieves the CIs matching the filter, sorted and paginated in the database
+func (r *CIPostgresRepository) List(ctx context.Context, filter CIFilter) ([]*models.CI, error) {
+	builder := newCIQueryBuilder(filter)
+	query := builder.selectQuery(filter)
+
 	var cis []*models.CI
-	err := r.db.SelectContext(ctx, &cis, query, status)
+	err := r.db.SelectContext(ctx, &cis, query, builder.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -137,56 +273,381 @@ func (r *CIPostgresRepository) GetByStatus(ctx context.Context, status string) (
 	return cis, nil
 }
 
-// Update updates a CI in the database
+// Count returns the number of CIs matching the filter, ignoring sorting and pagination
+func (r *CIPostgresRepository) Count(ctx context.Context, filter CIFilter) (int, error) {
+	builder := newCIQueryBuilder(filter)
+	query := builder.countQuery()
+
+	var count int
+	err := r.db.GetContext(ctx, &count, query, builder.args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Stream calls fn with each CI matching the filter as the CIs are read from the database
+func (r *CIPostgresRepository) Stream(ctx context.Context, filter CIFilter, fn func(ci *models.CI) error) error {
+	builder := newCIQueryBuilder(filter)
+	query := builder.selectQuery(filter)
+
+	return streamRows(ctx, r.db, query, builder.args, fn)
+}
+
+// AttributePaths returns the sorted dotted paths of the attribute values of the CIs matching
+// the filter
+func (r *CIPostgresRepository) AttributePaths(ctx context.Context, filter CIFilter) ([]string, error) {
+	builder := newCIQueryBuilder(filter)
+	query := jsonPathsQuery("attributes", "SELECT attributes FROM "+builder.table+" "+builder.whereClause())
+
+	paths := []string{}
+	err := r.db.SelectContext(ctx, &paths, query, builder.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// ciSearchRow is a row returned by the full-text search query
+type ciSearchRow struct {
+	models.CI
+	Rank                float64        `db:"rank"`
+	NameHighlight       sql.NullString `db:"name_highlight"`
+	TypeHighlight       sql.NullString `db:"type_highlight"`
+	TagsHighlight       sql.NullString `db:"tags_highlight"`
+	AttributesHighlight sql.NullString `db:"attributes_highlight"`
+}
+
+// Search retrieves the CIs matching the full-text search, most relevant first
+func (r *CIPostgresRepository) Search(ctx context.Context, text string, limit, offset int) ([]*models.CISearchResult, error) {
+	tsQuery := ciSearchTSQuery(text)
+	if tsQuery == "" {
+		return []*models.CISearchResult{}, nil
+	}
+
+	query := `
+		SELECT id, name, type, attributes, tags, version, created_at, updated_at,
+			ts_rank(search_vector, search.q) AS rank,
+			CASE WHEN ci_search_lexemes(name) @@ search.q
+				THEN ts_headline('simple', name, search.q, $2) END AS name_highlight,
+			CASE WHEN ci_search_lexemes(type) @@ search.q
+				THEN ts_headline('simple', type, search.q, $2) END AS type_highlight,
+			CASE WHEN ci_search_lexemes(array_to_string(tags, ' ')) @@ search.q
+				THEN ts_headline('simple', array_to_string(tags, ' '), search.q, $2) END AS tags_highlight,
+			CASE WHEN ci_search_lexemes(ci_attribute_text(attributes)) @@ search.q
+				THEN ts_headline('simple', ci_attribute_text(attributes), search.q, $2) END AS attributes_highlight
+		FROM configuration_items, (SELECT $1::tsquery AS q) AS search
+		WHERE search_vector @@ search.q AND deleted_at IS NULL
+		ORDER BY rank DESC, name ASC, id ASC
+		LIMIT $3 OFFSET $4
+	`
+
+	var rows []ciSearchRow
+	err := r.db.SelectContext(ctx, &rows, query, tsQuery, ciHighlightOptions, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*models.CISearchResult, 0, len(rows))
+	for _, row := range rows {
+		highlights := make(map[string]string)
+		for field, highlight := range map[string]sql.NullString{
+			"name":       row.NameHighlight,
+			"type":       row.TypeHighlight,
+			"tags":       row.TagsHighlight,
+			"attributes": row.AttributesHighlight,
+		} {
+			if highlight.Valid {
+				highlights[field] = highlight.String
+			}
+		}
+		results = append(results, &models.CISearchResult{CI: row.CI, Rank: row.Rank, Highlights: highlights})
+	}
+
+	return results, nil
+}
+
+// CountSearch returns the number of CIs matching the full-text search
+func (r *CIPostgresRepository) CountSearch(ctx context.Context, text string) (int, error) {
+	tsQuery := ciSearchTSQuery(text)
+	if tsQuery == "" {
+		return 0, nil
+	}
+
+	query := `SELECT COUNT(*) FROM configuration_items WHERE search_vector @@ $1::tsquery AND deleted_at IS NULL`
+
+	var count int
+	err := r.db.GetContext(ctx, &count, query, tsQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Update updates a CI in the database if its stored version still equals ci.Version. The
+// check and the version increment happen in one statement, so concurrent updates cannot both
+// succeed; ci.Version is set to the new version.
 func (r *CIPostgresRepository) Update(ctx context.Context, ci *models.CI) error {
 	query := `
 		UPDATE configuration_items
-		SET name = $2, type = $3, attributes = $4, tags = $5, updated_at = $6
-		WHERE id = $1
+		SET name = $2, type = $3, attributes = $4, tags = $5, updated_at = $6,
+			search_vector = ci_search_vector($2, $3, $5, $4), version = version + 1
+		WHERE id = $1 AND version = $7 AND deleted_at IS NULL
+		RETURNING version
 	`
 
-	result, err := r.db.ExecContext(ctx, query,
+	var version int
+	err := r.db.GetContext(ctx, &version, query,
 		ci.ID,
 		ci.Name,
 		ci.Type,
 		ci.Attributes,
 		ci.Tags,
 		ci.UpdatedAt,
+		ci.Version,
 	)
 
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return r.versionConflictOrNotFound(ctx, ci.ID)
+		}
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	ci.Version = version
+	return nil
+}
+
+// versionConflictOrNotFound explains why an update matched no row: the CI exists with
+// another version, or it does not exist
+func (r *CIPostgresRepository) versionConflictOrNotFound(ctx context.Context, id uuid.UUID) error {
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM configuration_items WHERE id = $1 AND deleted_at IS NULL)`, id)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return errors.New("CI not found")
+	if exists {
+		return ErrVersionConflict
 	}
+	return ErrCINotFound
+}
 
-	return nil
+// Delete moves a CI to the trash if it is at the given version. Its relationships are moved
+// to the trash with it, stamped with the same deletion time, so the CI can be restored
+// together with them.
+func (r *CIPostgresRepository) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	return inTx(ctx, r.db, func(tx dbtx) error {
+		var deletedAt time.Time
+		err := tx.GetContext(ctx, &deletedAt, `
+			UPDATE configuration_items SET deleted_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+			RETURNING deleted_at
+		`, id, version)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return r.versionConflictOrNotFound(ctx, id)
+			}
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE relationships SET deleted_at = $2
+			WHERE (source_id = $1 OR target_id = $1) AND deleted_at IS NULL
+		`, id, deletedAt)
+		return err
+	})
+}
+
+// ciBatchRow is a CI written by a batch statement and its version after the write
+type ciBatchRow struct {
+	ID      uuid.UUID `db:"id"`
+	Version int       `db:"version"`
 }
 
-// Delete deletes a CI from the database
-func (r *CIPostgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM configuration_items WHERE id = $1`
+// CreateBatch creates the CIs with one statement and returns the IDs of those created. CIs
+// whose ID is taken, by a live CI or one in the trash, are skipped.
+func (r *CIPostgresRepository) CreateBatch(ctx context.Context, cis []*models.CI) ([]uuid.UUID, error) {
+	if len(cis) == 0 {
+		return []uuid.UUID{}, nil
+	}
+
+	b := &queryBuilder{}
+	rows := make([][]interface{}, len(cis))
+	for i, ci := range cis {
+		rows[i] = []interface{}{ci.ID, ci.Name, ci.Type, ci.Attributes, pq.Array(ci.Tags), ci.CreatedAt, ci.UpdatedAt}
+	}
+	values := b.valuesList([]string{"uuid", "text", "text", "jsonb", "text[]", "timestamptz", "timestamptz"}, rows)
+
+	query := `
+		INSERT INTO configuration_items (id, name, type, attributes, tags, version, created_at, updated_at, search_vector)
+		SELECT v.id, v.name, v.type, v.attributes, v.tags, 1, v.created_at, v.updated_at,
+			ci_search_vector(v.name, v.type, v.tags, v.attributes)
+		FROM (` + values + `) AS v(id, name, type, attributes, tags, created_at, updated_at)
+		ON CONFLICT (id) DO NOTHING
+		RETURNING id, version
+	`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	var created []ciBatchRow
+	err := r.db.SelectContext(ctx, &created, query, b.args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	return applyBatchVersions(cis, created), nil
+}
+
+// UpdateBatch updates the CIs with one statement, each only if its stored version still
+// equals ci.Version, and returns the IDs of those updated. Their Version is set to the new
+// version.
+func (r *CIPostgresRepository) UpdateBatch(ctx context.Context, cis []*models.CI) ([]uuid.UUID, error) {
+	if len(cis) == 0 {
+		return []uuid.UUID{}, nil
+	}
+
+	b := &queryBuilder{}
+	rows := make([][]interface{}, len(cis))
+	for i, ci := range cis {
+		rows[i] = []interface{}{ci.ID, ci.Name, ci.Type, ci.Attributes, pq.Array(ci.Tags), ci.UpdatedAt, ci.Version}
+	}
+	values := b.valuesList([]string{"uuid", "text", "text", "jsonb", "text[]", "timestamptz", "integer"}, rows)
+
+	query := `
+		UPDATE configuration_items ci
+		SET name = v.name, type = v.type, attributes = v.attributes, tags = v.tags, updated_at = v.updated_at,
+			search_vector = ci_search_vector(v.name, v.type, v.tags, v.attributes), version = ci.version + 1
+		FROM (` + values + `) AS v(id, name, type, attributes, tags, updated_at, version)
+		WHERE ci.id = v.id AND ci.version = v.version AND ci.deleted_at IS NULL
+		RETURNING ci.id, ci.version
+	`
+
+	var updated []ciBatchRow
+	err := r.db.SelectContext(ctx, &updated, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return applyBatchVersions(cis, updated), nil
+}
+
+// DeleteBatch moves the CIs and their relationships to the trash with one statement, each CI
+// only if its stored version still equals ci.Version, and returns the IDs of those deleted
+func (r *CIPostgresRepository) DeleteBatch(ctx context.Context, cis []*models.CI) ([]uuid.UUID, error) {
+	if len(cis) == 0 {
+		return []uuid.UUID{}, nil
+	}
+
+	ids := make([]string, len(cis))
+	versions := make([]int64, len(cis))
+	for i, ci := range cis {
+		ids[i] = ci.ID.String()
+		versions[i] = int64(ci.Version)
+	}
+
+	query := `
+		WITH deleted AS (
+			UPDATE configuration_items ci SET deleted_at = CURRENT_TIMESTAMP
+			FROM unnest($1::uuid[], $2::integer[]) AS v(id, version)
+			WHERE ci.id = v.id AND ci.version = v.version AND ci.deleted_at IS NULL
+			RETURNING ci.id, ci.deleted_at
+		), trashed_relationships AS (
+			UPDATE relationships rel SET deleted_at = deleted.deleted_at
+			FROM deleted
+			WHERE (rel.source_id = deleted.id OR rel.target_id = deleted.id) AND rel.deleted_at IS NULL
+		)
+		SELECT id FROM deleted
+	`
+
+	var deleted []uuid.UUID
+	err := r.db.SelectContext(ctx, &deleted, query, pq.Array(ids), pq.Array(versions))
+	if err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// applyBatchVersions sets the new version of each CI written by a batch statement and returns
+// the IDs of those CIs
+func applyBatchVersions(cis []*models.CI, rows []ciBatchRow) []uuid.UUID {
+	versions := make(map[uuid.UUID]int, len(rows))
+	ids := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		versions[row.ID] = row.Version
+		ids[i] = row.ID
+	}
+
+	for _, ci := range cis {
+		if version, ok := versions[ci.ID]; ok {
+			ci.Version = version
+		}
+	}
+	return ids
+}
+
+// ListDeleted retrieves the CIs in the trash, most recently deleted first
+func (r *CIPostgresRepository) ListDeleted(ctx context.Context, limit, offset int) ([]*models.DeletedCI, error) {
+	query := `
+		SELECT ci.id, ci.name, ci.type, ci.attributes, ci.tags, ci.version, ci.created_at, ci.updated_at,
+			ci.deleted_at,
+			(SELECT COUNT(*) FROM relationships rel
+			 WHERE (rel.source_id = ci.id OR rel.target_id = ci.id) AND rel.deleted_at IS NOT NULL) AS relationships
+		FROM configuration_items ci
+		WHERE ci.deleted_at IS NOT NULL
+		ORDER BY ci.deleted_at DESC, ci.id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	var cis []*models.DeletedCI
+	err := r.db.SelectContext(ctx, &cis, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return cis, nil
+}
+
+// CountDeleted returns the number of CIs in the trash
+func (r *CIPostgresRepository) CountDeleted(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM configuration_items WHERE deleted_at IS NOT NULL`)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Restore takes a CI out of the trash, leaving its relationships to be checked against the
+// rules of their types and restored one by one
+func (r *CIPostgresRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE configuration_items SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`, id)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return errors.New("CI not found")
+	return expectRowsAffected(result, ErrNotInTrash)
+}
+
+// PurgeDeleted permanently deletes the CIs that were moved to the trash before the given
+// time, together with their relationships, and returns the purged CIs
+func (r *CIPostgresRepository) PurgeDeleted(ctx context.Context, before time.Time) ([]*models.CI, error) {
+	query := `
+		DELETE FROM configuration_items
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1
+		RETURNING ` + ciColumns
+
+	var cis []*models.CI
+	err := r.db.SelectContext(ctx, &cis, query, before)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return cis, nil
 }
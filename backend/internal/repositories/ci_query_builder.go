@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cmdb-lite/backend/internal/query"
+	"github.com/lib/pq"
+)
+
+// ciColumns is the column list selected for every CI query
+const ciColumns = "id, name, type, attributes, tags, version, created_at, updated_at"
+
+// CISortFields lists the fields CIs can be sorted by
+var CISortFields = map[string]string{
+	"name":       "name",
+	"type":       "type",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// ciQueryBuilder turns a CIFilter into a parameterized SQL query over configuration_items
+type ciQueryBuilder struct {
+	queryBuilder
+
+	// table is the FROM item the query reads: the live table, or its history as of a time
+	table string
+}
+
+// newCIQueryBuilder creates a query builder with the WHERE conditions for the filter
+func newCIQueryBuilder(filter CIFilter) *ciQueryBuilder {
+	b := &ciQueryBuilder{}
+	b.table = b.tableAsOf("configuration_items", ciColumns, "configuration_items", filter.AsOf)
+
+	if len(filter.IDs) > 0 {
+		ids := make([]string, len(filter.IDs))
+		for i, id := range filter.IDs {
+			ids[i] = id.String()
+		}
+		b.where("id = ANY(" + b.bind(pq.Array(ids)) + "::uuid[])")
+	}
+
+	if filter.Type != "" {
+		b.where("type = " + b.bind(filter.Type))
+	}
+
+	if len(filter.Tags) > 0 {
+		if filter.TagsMatch == TagsMatchAll {
+			b.where("tags @> " + b.bind(pq.Array(filter.Tags)) + "::text[]")
+		} else {
+			b.where("tags && " + b.bind(pq.Array(filter.Tags)) + "::text[]")
+		}
+	}
+
+	if filter.NamePrefix != "" {
+		b.where("name ILIKE " + b.bind(query.EscapeLike(filter.NamePrefix)+"%"))
+	}
+
+	if filter.NameContains != "" {
+		b.where("name ILIKE " + b.bind("%"+query.EscapeLike(filter.NameContains)+"%"))
+	}
+
+	if filter.CreatedAfter != nil {
+		b.where("created_at >= " + b.bind(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		b.where("created_at < " + b.bind(*filter.CreatedBefore))
+	}
+	if filter.UpdatedAfter != nil {
+		b.where("updated_at >= " + b.bind(*filter.UpdatedAfter))
+	}
+	if filter.UpdatedBefore != nil {
+		b.where("updated_at < " + b.bind(*filter.UpdatedBefore))
+	}
+
+	for _, condition := range b.attributeConditions("attributes", filter.Attributes) {
+		b.where(condition)
+	}
+
+	if filter.Query != nil {
+		b.where(query.Compile(filter.Query, b.bind))
+	}
+
+	return b
+}
+
+// selectQuery renders the SELECT statement with ordering and pagination
+func (b *ciQueryBuilder) selectQuery(filter CIFilter) string {
+	if filter.Cursor != nil {
+		return b.keysetQuery(ciColumns, b.table, "created_at", filter.Cursor, filter.Limit)
+	}
+
+	sortColumn, ok := CISortFields[filter.SortField]
+	if !ok {
+		sortColumn = "created_at"
+		if filter.SortField == "" {
+			filter.SortDesc = true
+		}
+	}
+
+	direction := "ASC"
+	if filter.SortDesc {
+		direction = "DESC"
+	}
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s %s ORDER BY %s %s, id %s",
+		ciColumns, b.table, b.whereClause(), sortColumn, direction, direction,
+	)
+
+	if filter.Limit > 0 {
+		query += " LIMIT " + b.bind(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += " OFFSET " + b.bind(filter.Offset)
+	}
+
+	return query
+}
+
+// countQuery renders the COUNT(*) statement for the filter
+func (b *ciQueryBuilder) countQuery() string {
+	return "SELECT COUNT(*) FROM " + b.table + " " + b.whereClause()
+}
+
+// ciHighlightOptions configures ts_headline for search snippets
+const ciHighlightOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=3, MaxWords=15, MinWords=5"
+
+// ciSearchTSQuery turns free text into a tsquery that matches CIs containing every word, each
+// as a prefix, e.g. "Web-01 SN12" becomes 'web-01':* & 'sn12':*. The lexemes are passed to
+// PostgreSQL verbatim so hostnames and serial numbers are not split up by the parser.
+func ciSearchTSQuery(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `''`)
+
+	terms := strings.Fields(strings.ToLower(text))
+	for i, term := range terms {
+		terms[i] = "'" + replacer.Replace(term) + "':*"
+	}
+	return strings.Join(terms, " & ")
+}
@@ -2,11 +2,76 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/pagination"
+	"github.com/cmdb-lite/backend/internal/query"
 	"github.com/google/uuid"
 )
 
+// Tag match modes for CIFilter.TagsMatch
+const (
+	TagsMatchAny = "any"
+	TagsMatchAll = "all"
+)
+
+// CIFilter describes the filtering, sorting and pagination options used when listing CIs.
+// Zero values mean "no restriction"; a zero Limit returns every matching row.
+type CIFilter struct {
+	IDs           []uuid.UUID
+	Type          string
+	Tags          []string
+	TagsMatch     string
+	NamePrefix    string
+	NameContains  string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+
+	// Attributes maps dotted attribute paths (e.g. "status" or "network.vlan") to the
+	// value they must equal
+	Attributes map[string]string
+
+	// Query is a parsed search expression that CIs must also match
+	Query query.Expr
+
+	// AsOf, when set, reads the CIs as they were at that time from the history table
+	AsOf *time.Time
+
+	// SortField is one of CISortFields; SortDesc reverses the order
+	SortField string
+	SortDesc  bool
+
+	Limit  int
+	Offset int
+
+	// Cursor, when set, pages by (created_at, id) from the cursor position instead of
+	// using Offset; the sort fields are ignored and results are newest first
+	Cursor *pagination.Cursor
+}
+
+// ErrVersionConflict is returned by updates when the stored version no longer equals the
+// version the caller read, i.e. someone else changed the row in the meantime
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrCINotFound is returned by writes to a CI that does not exist, and by lookups by
+// external ID that find no live CI
+var ErrCINotFound = errors.New("CI not found")
+
+// ErrNotInTrash is returned by Restore when the CI is not in the trash
+var ErrNotInTrash = errors.New("CI not found in trash")
+
+// ErrNameNotUnique is returned by GetByName when several CIs have the name. Names are not
+// unique; external IDs identify CIs from other systems.
+var ErrNameNotUnique = errors.New("CI name is not unique")
+
+// ErrExternalIDTaken is returned by AddExternalID and AddExternalIDs when an external ID
+// belongs to another CI
+var ErrExternalIDTaken = errors.New("external ID belongs to another CI")
+
 // CIRepository defines the interface for CI (Configuration Item) repository operations
 type CIRepository interface {
 	// Create creates a new CI in the database
@@ -15,21 +80,96 @@ type CIRepository interface {
 	// GetByID retrieves a CI by ID
 	GetByID(ctx context.Context, id uuid.UUID) (*models.CI, error)
 
-	// GetByName retrieves a CI by name
+	// Lock locks the rows of the CIs until the transaction ends, so that checks spanning the
+	// CIs' relationships are not interleaved with another transaction's. It waits for the
+	// locks other transactions hold on the CIs.
+	Lock(ctx context.Context, ids ...uuid.UUID) error
+
+	// GetByName retrieves a CI by name. It returns ErrNameNotUnique if several CIs have the
+	// name.
 	GetByName(ctx context.Context, name string) (*models.CI, error)
 
+	// GetByExternalID retrieves the CI with an external ID. It returns ErrCINotFound if no live
+	// CI has the external ID.
+	GetByExternalID(ctx context.Context, source, externalID string) (*models.CI, error)
+
+	// AddExternalID gives a CI an external ID. Adding one the CI already has does nothing; it
+	// returns ErrExternalIDTaken if the external ID belongs to another CI, even one in the trash.
+	AddExternalID(ctx context.Context, externalID *models.CIExternalID) error
+
+	// ListExternalIDs retrieves the external IDs of a CI
+	ListExternalIDs(ctx context.Context, ciID uuid.UUID) ([]*models.CIExternalID, error)
+
+	// FindExternalIDs retrieves the given external IDs of a source that belong to a CI, live
+	// or in the trash
+	FindExternalIDs(ctx context.Context, source string, externalIDs []string) ([]*models.CIExternalID, error)
+
+	// AddExternalIDs gives CIs external IDs with one statement. It returns ErrExternalIDTaken
+	// if any of the external IDs exists already.
+	AddExternalIDs(ctx context.Context, externalIDs []*models.CIExternalID) error
+
 	// GetByType retrieves CIs by type
 	GetByType(ctx context.Context, ciType string) ([]*models.CI, error)
 
 	// GetAll retrieves all CIs from the database
 	GetAll(ctx context.Context) ([]*models.CI, error)
 
-	// Update updates a CI in the database
+	// Update updates a CI in the database if its stored version equals ci.Version, and
+	// increments ci.Version. It returns ErrVersionConflict if the version has moved on.
 	Update(ctx context.Context, ci *models.CI) error
 
-	// Delete deletes a CI from the database
-	Delete(ctx context.Context, id uuid.UUID) error
+	// Delete moves a CI and its relationships to the trash if its stored version equals
+	// version. It returns ErrVersionConflict if the version has moved on and ErrCINotFound if
+	// there is no such CI.
+	Delete(ctx context.Context, id uuid.UUID, version int) error
+
+	// CreateBatch creates the CIs with one statement and returns the IDs of those created.
+	// CIs whose ID is already taken are skipped.
+	CreateBatch(ctx context.Context, cis []*models.CI) ([]uuid.UUID, error)
+
+	// UpdateBatch updates the CIs whose stored version equals ci.Version with one statement,
+	// increments their Version and returns their IDs
+	UpdateBatch(ctx context.Context, cis []*models.CI) ([]uuid.UUID, error)
+
+	// DeleteBatch moves the CIs whose stored version equals ci.Version, and their
+	// relationships, to the trash with one statement and returns their IDs
+	DeleteBatch(ctx context.Context, cis []*models.CI) ([]uuid.UUID, error)
+
+	// ListDeleted retrieves the CIs in the trash, most recently deleted first
+	ListDeleted(ctx context.Context, limit, offset int) ([]*models.DeletedCI, error)
+
+	// CountDeleted returns the number of CIs in the trash
+	CountDeleted(ctx context.Context) (int, error)
+
+	// Restore takes a CI out of the trash. Its relationships stay in the trash; they are
+	// restored one by one with RelationshipRepository.Restore. It returns ErrNotInTrash if the
+	// CI is not in the trash.
+	Restore(ctx context.Context, id uuid.UUID) error
+
+	// PurgeDeleted permanently deletes the CIs moved to the trash before the given time, and
+	// their relationships, returning the purged CIs
+	PurgeDeleted(ctx context.Context, before time.Time) ([]*models.CI, error)
 
 	// GetByStatus retrieves CIs by status
 	GetByStatus(ctx context.Context, status string) ([]*models.CI, error)
+
+	// List retrieves the CIs matching the filter, sorted and paginated in the database
+	List(ctx context.Context, filter CIFilter) ([]*models.CI, error)
+
+	// Count returns the number of CIs matching the filter, ignoring sorting and pagination
+	Count(ctx context.Context, filter CIFilter) (int, error)
+
+	// Stream calls fn with each CI matching the filter, in the order List returns them, as
+	// the CIs are read from the database. It stops at the first error fn returns.
+	Stream(ctx context.Context, filter CIFilter, fn func(ci *models.CI) error) error
+
+	// AttributePaths returns the sorted dotted paths of the attribute values of the CIs
+	// matching the filter, ignoring sorting and pagination
+	AttributePaths(ctx context.Context, filter CIFilter) ([]string, error)
+
+	// Search retrieves the CIs matching the full-text search, most relevant first
+	Search(ctx context.Context, text string, limit, offset int) ([]*models.CISearchResult, error)
+
+	// CountSearch returns the number of CIs matching the full-text search
+	CountSearch(ctx context.Context, text string) (int, error)
 }
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// CITypePostgresRepository implements the CITypeRepository interface for PostgreSQL
+type CITypePostgresRepository struct {
+	db dbtx
+}
+
+// NewCITypePostgresRepository creates a new CITypePostgresRepository
+func NewCITypePostgresRepository(db *sqlx.DB) *CITypePostgresRepository {
+	return &CITypePostgresRepository{db: db}
+}
+
+// Create creates a new CI type in the database
+func (r *CITypePostgresRepository) Create(ctx context.Context, ciType *models.CIType) error {
+	query := `
+		INSERT INTO ci_types (id, name, description, schema, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		ciType.ID,
+		ciType.Name,
+		ciType.Description,
+		ciType.Schema,
+		ciType.CreatedAt,
+		ciType.UpdatedAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetByID retrieves a CI type by ID
+func (r *CITypePostgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.CIType, error) {
+	query := `
+		SELECT id, name, description, schema, created_at, updated_at
+		FROM ci_types
+		WHERE id = $1
+	`
+
+	var ciType models.CIType
+	err := r.db.GetContext(ctx, &ciType, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("CI type not found")
+		}
+		return nil, err
+	}
+
+	return &ciType, nil
+}
+
+// GetByName retrieves a CI type by name
+func (r *CITypePostgresRepository) GetByName(ctx context.Context, name string) (*models.CIType, error) {
+	query := `
+		SELECT id, name, description, schema, created_at, updated_at
+		FROM ci_types
+		WHERE name = $1
+	`
+
+	var ciType models.CIType
+	err := r.db.GetContext(ctx, &ciType, query, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("CI type not found")
+		}
+		return nil, err
+	}
+
+	return &ciType, nil
+}
+
+// GetAll retrieves all CI types from the database
+func (r *CITypePostgresRepository) GetAll(ctx context.Context) ([]*models.CIType, error) {
+	query := `
+		SELECT id, name, description, schema, created_at, updated_at
+		FROM ci_types
+		ORDER BY name ASC
+	`
+
+	var ciTypes []*models.CIType
+	err := r.db.SelectContext(ctx, &ciTypes, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return ciTypes, nil
+}
+
+// Update updates a CI type in the database
+func (r *CITypePostgresRepository) Update(ctx context.Context, ciType *models.CIType) error {
+	query := `
+		UPDATE ci_types
+		SET name = $2, description = $3, schema = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		ciType.ID,
+		ciType.Name,
+		ciType.Description,
+		ciType.Schema,
+		ciType.UpdatedAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("CI type not found")
+	}
+
+	return nil
+}
+
+// Delete deletes a CI type from the database
+func (r *CITypePostgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM ci_types WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("CI type not found")
+	}
+
+	return nil
+}
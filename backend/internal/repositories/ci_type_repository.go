@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// CITypeRepository defines the interface for CI type registry operations
+type CITypeRepository interface {
+	// Create creates a new CI type in the database
+	Create(ctx context.Context, ciType *models.CIType) error
+
+	// GetByID retrieves a CI type by ID
+	GetByID(ctx context.Context, id uuid.UUID) (*models.CIType, error)
+
+	// GetByName retrieves a CI type by name
+	GetByName(ctx context.Context, name string) (*models.CIType, error)
+
+	// GetAll retrieves all CI types from the database
+	GetAll(ctx context.Context) ([]*models.CIType, error)
+
+	// Update updates a CI type in the database
+	Update(ctx context.Context, ciType *models.CIType) error
+
+	// Delete deletes a CI type from the database
+	Delete(ctx context.Context, id uuid.UUID) error
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// eventQuery selects the events of the change feed with the current tags of their CIs
+const eventQuery = `
+		SELECT a.seq, a.id, a.entity_type || '.' || a.action AS type, a.entity_type, a.entity_id,
+			a.action, a.changed_by, a.changed_at, a.details, COALESCE(ci.tags, '{}') AS tags
+		FROM audit_logs a
+		LEFT JOIN configuration_items ci ON a.entity_type = 'configuration_item' AND ci.id = a.entity_id
+		WHERE a.entity_type IN ('configuration_item', 'relationship', 'user')
+	`
+
+// EventPostgresRepository implements the EventRepository interface for PostgreSQL
+type EventPostgresRepository struct {
+	db dbtx
+}
+
+// NewEventPostgresRepository creates a new EventPostgresRepository
+func NewEventPostgresRepository(db *sqlx.DB) *EventPostgresRepository {
+	return &EventPostgresRepository{db: db}
+}
+
+// GetBySeqs retrieves the events with the sequence numbers, in order
+func (r *EventPostgresRepository) GetBySeqs(ctx context.Context, seqs []int64) ([]*models.Event, error) {
+	events := []*models.Event{}
+	if len(seqs) == 0 {
+		return events, nil
+	}
+
+	query := eventQuery + ` AND a.seq = ANY($1::bigint[]) ORDER BY a.seq ASC`
+
+	if err := r.db.SelectContext(ctx, &events, query, pq.Array(seqs)); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// ListAfter retrieves up to limit events with a sequence number after afterSeq, in order
+func (r *EventPostgresRepository) ListAfter(ctx context.Context, afterSeq int64, limit int) ([]*models.Event, error) {
+	query := eventQuery + ` AND a.seq > $1 ORDER BY a.seq ASC LIMIT $2`
+
+	events := []*models.Event{}
+	if err := r.db.SelectContext(ctx, &events, query, afterSeq, limit); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// LatestSeq returns the sequence number of the latest audit log
+func (r *EventPostgresRepository) LatestSeq(ctx context.Context) (int64, error) {
+	query := `SELECT COALESCE(MAX(seq), 0) FROM audit_logs`
+
+	var seq int64
+	if err := r.db.GetContext(ctx, &seq, query); err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/cmdb-lite/backend/internal/models"
+)
+
+// EventRepository defines the interface for reading the change feed: the audit logs of CIs,
+// relationships and users, in the order they were recorded
+type EventRepository interface {
+	// GetBySeqs retrieves the events with the sequence numbers, in order. Numbers of audit
+	// logs that are not events are skipped.
+	GetBySeqs(ctx context.Context, seqs []int64) ([]*models.Event, error)
+
+	// ListAfter retrieves up to limit events with a sequence number after afterSeq, in order
+	ListAfter(ctx context.Context, afterSeq int64, limit int) ([]*models.Event, error)
+
+	// LatestSeq returns the sequence number of the latest audit log, or 0 if there is none
+	LatestSeq(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// graphNodeColumns is the column list selected for the CIs reached by a traversal
+const graphNodeColumns = "ci.id, ci.name, ci.type, ci.attributes, ci.tags, ci.version, ci.created_at, ci.updated_at, reached.depth"
+
+// graphNodesQuery renders a recursive CTE that walks the relationships from the root breadth
+// first and returns up to limit CIs with their shortest distance from the root, nearest first.
+// Each row of the walk is a whole step: the CIs entered at that depth and every CI reached
+// so far. A step only enters CIs not reached before, so each CI is entered once, at its
+// shortest distance, and the cost of the walk is bounded by the CIs and relationships it
+// reaches rather than by the paths between them. Within a step CIs are entered in ID order up
+// to the limit, so a truncated walk always keeps the same CIs. With filter.AsOf set the walk
+// reads the history tables instead of the live ones.
+func graphNodesQuery(b *queryBuilder, rootID uuid.UUID, filter GraphFilter, limit int) string {
+	root := b.bind(rootID)
+
+	var join, next string
+	switch filter.Direction {
+	case GraphDirectionOut:
+		join, next = "rel.source_id = ANY(walk.frontier)", "rel.target_id"
+	case GraphDirectionIn:
+		join, next = "rel.target_id = ANY(walk.frontier)", "rel.source_id"
+	default:
+		join = "(rel.source_id = ANY(walk.frontier) OR rel.target_id = ANY(walk.frontier))"
+		next = "CASE WHEN rel.source_id = ANY(walk.frontier) THEN rel.target_id ELSE rel.source_id END"
+	}
+
+	relationships := b.tableAsOf("relationships", relationshipColumns, "rel", filter.AsOf)
+	cis := b.tableAsOf("configuration_items", ciColumns, "ci", filter.AsOf)
+
+	conditions := []string{
+		join,
+		"NOT ci.id = ANY(walk.visited)",
+	}
+	if len(filter.RelationshipTypes) > 0 {
+		conditions = append(conditions, "rel.type = ANY("+b.bind(pq.Array(filter.RelationshipTypes))+"::text[])")
+	}
+	if len(filter.CITypes) > 0 {
+		conditions = append(conditions, "ci.type = ANY("+b.bind(pq.Array(filter.CITypes))+"::text[])")
+	}
+	conditions = append(conditions, b.attributeConditions("rel.attributes", filter.RelationshipAttributes)...)
+
+	return fmt.Sprintf(`
+		WITH RECURSIVE walk(depth, frontier, visited) AS (
+			SELECT 0, ARRAY[%[1]s::uuid], ARRAY[%[1]s::uuid]
+			UNION ALL
+			SELECT walk.depth + 1, step.entered, walk.visited || step.entered
+			FROM walk
+			CROSS JOIN LATERAL (
+				SELECT array_agg(next.id ORDER BY next.id) AS entered
+				FROM (
+					SELECT DISTINCT ci.id
+					FROM %[7]s
+					JOIN %[8]s ON ci.id = %[2]s
+					WHERE %[3]s
+					ORDER BY ci.id
+					LIMIT %[5]s - cardinality(walk.visited)
+				) next
+			) step
+			WHERE walk.depth < %[4]s AND cardinality(walk.visited) < %[5]s AND step.entered IS NOT NULL
+		)
+		SELECT %[6]s
+		FROM (
+			SELECT entered.ci_id, walk.depth
+			FROM walk
+			CROSS JOIN LATERAL unnest(walk.frontier) AS entered(ci_id)
+		) reached
+		JOIN %[8]s ON ci.id = reached.ci_id
+		ORDER BY reached.depth, reached.ci_id
+	`, root, next, strings.Join(conditions, " AND "), b.bind(filter.Depth), b.bind(limit), graphNodeColumns, relationships, cis)
+}
+
+// graphEdgesQuery renders the query for the relationships between the given CIs that the
+// filter lets a traversal follow
+func graphEdgesQuery(b *queryBuilder, ciIDs []uuid.UUID, filter GraphFilter) string {
+	nodes := b.bind(pq.Array(uuidStrings(ciIDs))) + "::uuid[]"
+	b.where("source_id = ANY(" + nodes + ")")
+	b.where("target_id = ANY(" + nodes + ")")
+	if len(filter.RelationshipTypes) > 0 {
+		b.where("type = ANY(" + b.bind(pq.Array(filter.RelationshipTypes)) + "::text[])")
+	}
+	for _, condition := range b.attributeConditions("attributes", filter.RelationshipAttributes) {
+		b.where(condition)
+	}
+
+	table := b.tableAsOf("relationships", relationshipColumns, "relationships", filter.AsOf)
+	return fmt.Sprintf("SELECT %s FROM %s %s ORDER BY created_at, id", relationshipColumns, table, b.whereClause())
+}
+
+// uuidStrings returns the IDs as strings, to bind them as a uuid[] array
+func uuidStrings(ids []uuid.UUID) []string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+	return values
+}
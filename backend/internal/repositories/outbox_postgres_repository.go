@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// outboxEntityTypes are the entity types whose changes are written to the outbox
+var outboxEntityTypes = []string{"configuration_item", "relationship"}
+
+// OutboxPostgresRepository implements the OutboxRepository interface for PostgreSQL
+type OutboxPostgresRepository struct {
+	db dbtx
+}
+
+// NewOutboxPostgresRepository creates a new OutboxPostgresRepository
+func NewOutboxPostgresRepository(db *sqlx.DB) *OutboxPostgresRepository {
+	return &OutboxPostgresRepository{db: db}
+}
+
+// ClaimPending leases up to limit available unpublished events. Events locked by another
+// relay's claim are skipped rather than waited for.
+func (r *OutboxPostgresRepository) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.OutboxEvent, error) {
+	// RETURNING does not keep the order of the claim, so the claimed events are sorted after
+	query := `
+		WITH claimed AS (
+			UPDATE outbox
+			SET available_at = $2
+			FROM (
+				SELECT id
+				FROM outbox
+				WHERE published_at IS NULL AND available_at <= $1
+				ORDER BY seq ASC
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			) pending
+			WHERE outbox.id = pending.id
+			RETURNING outbox.id, seq, aggregate_type, aggregate_id, event_type, payload, created_at,
+				attempts, available_at, published_at, last_error
+		)
+		SELECT * FROM claimed ORDER BY seq ASC
+	`
+
+	events := []*models.OutboxEvent{}
+	if err := r.db.SelectContext(ctx, &events, query, now, now.Add(lease), limit); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// MarkPublished records that an event was published. The event is only updated while it is
+// still leased until leasedUntil, since another claim moves its available_at.
+func (r *OutboxPostgresRepository) MarkPublished(ctx context.Context, id uuid.UUID, leasedUntil, publishedAt time.Time) error {
+	query := `
+		UPDATE outbox
+		SET published_at = $3, attempts = attempts + 1, last_error = ''
+		WHERE id = $1 AND available_at = $2 AND published_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, leasedUntil, publishedAt)
+	if err != nil {
+		return err
+	}
+
+	return expectRowsAffected(result, ErrOutboxLeaseLost)
+}
+
+// MarkFailed records a failed attempt to publish an event, while the event is still leased
+// until leasedUntil
+func (r *OutboxPostgresRepository) MarkFailed(ctx context.Context, id uuid.UUID, leasedUntil, availableAt time.Time, lastError string) error {
+	query := `
+		UPDATE outbox
+		SET available_at = $3, attempts = attempts + 1, last_error = $4
+		WHERE id = $1 AND available_at = $2 AND published_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, leasedUntil, availableAt, lastError)
+	if err != nil {
+		return err
+	}
+
+	return expectRowsAffected(result, ErrOutboxLeaseLost)
+}
+
+// DeletePublished deletes the events published before a time
+func (r *OutboxPostgresRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM outbox
+		WHERE published_at IS NOT NULL AND published_at < $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// enqueueOutboxEvents writes the events recorded by audit logs of CIs and relationships to the
+// outbox in the order of the audit logs. The event type is "<entity type>.<action>" and the
+// payload is the event as webhooks receive it.
+func enqueueOutboxEvents(ctx context.Context, db dbtx, auditLogIDs []uuid.UUID) error {
+	if len(auditLogIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO outbox (id, aggregate_type, aggregate_id, event_type, payload, created_at, available_at)
+		SELECT a.id, a.entity_type, a.entity_id, a.entity_type || '.' || a.action, ` + auditLogEventPayload + `,
+			a.changed_at, CURRENT_TIMESTAMP
+		FROM audit_logs a
+		WHERE a.id = ANY($1::uuid[]) AND a.entity_type = ANY($2::text[])
+		ORDER BY array_position($1::uuid[], a.id)
+	`
+
+	ids := make([]string, len(auditLogIDs))
+	for i, id := range auditLogIDs {
+		ids[i] = id.String()
+	}
+
+	_, err := db.ExecContext(ctx, query, pq.Array(ids), pq.Array(outboxEntityTypes))
+	return err
+}
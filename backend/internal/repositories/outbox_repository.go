@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrOutboxLeaseLost is returned when an outbox event is no longer leased by the claim that
+// leased it, because the lease ended and the event was claimed again, or it was published or
+// deleted
+var ErrOutboxLeaseLost = errors.New("outbox event lease lost")
+
+// OutboxRepository defines the interface for relaying the outbox's events. Events are written
+// to the outbox with the audit logs of the changes to CIs and relationships they record.
+type OutboxRepository interface {
+	// ClaimPending leases up to limit unpublished events that are available at now, in the
+	// order they were recorded, by making them available again at the end of the lease. An
+	// event leased by one relay is not claimed by another until the lease ends, so an event
+	// whose relay stopped is published again.
+	ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.OutboxEvent, error)
+
+	// MarkPublished records that an event was published at a time. leasedUntil is the end of
+	// the lease given by ClaimPending; it returns ErrOutboxLeaseLost if the lease was lost.
+	MarkPublished(ctx context.Context, id uuid.UUID, leasedUntil, publishedAt time.Time) error
+
+	// MarkFailed records a failed attempt to publish an event and makes the event available
+	// again at a time. leasedUntil is the end of the lease given by ClaimPending; it returns
+	// ErrOutboxLeaseLost if the lease was lost.
+	MarkFailed(ctx context.Context, id uuid.UUID, leasedUntil, availableAt time.Time, lastError string) error
+
+	// DeletePublished deletes the events published before a time and returns how many were
+	// deleted
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/pagination"
+	"github.com/lib/pq"
+)
+
+// queryBuilder collects WHERE conditions and their positional arguments for list queries
+type queryBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+// bind appends a query argument and returns its placeholder
+func (b *queryBuilder) bind(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// where adds a condition that must hold for every returned row
+func (b *queryBuilder) where(condition string) {
+	b.conditions = append(b.conditions, condition)
+}
+
+// whereClause renders the WHERE clause, or an empty string if there are no conditions
+func (b *queryBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+// tableAsOf renders a FROM item for the table under the alias. With a nil asOf that is the
+// rows of the live table that are not in the trash; otherwise it is the rows of the table's
+// history table that were current at asOf, so queries read the table as it was then. Either
+// way only the given columns are selected.
+func (b *queryBuilder) tableAsOf(table, columns, alias string, asOf *time.Time) string {
+	if asOf == nil {
+		return fmt.Sprintf("(SELECT %s FROM %s WHERE deleted_at IS NULL) %s", columns, table, alias)
+	}
+
+	at := b.bind(*asOf)
+	return fmt.Sprintf(
+		"(SELECT %s FROM %s_history WHERE valid_from <= %s AND (valid_to IS NULL OR valid_to > %s)) %s",
+		columns, table, at, at, alias,
+	)
+}
+
+// attributeConditions renders a condition for each dotted attribute path (e.g. "network.vlan")
+// requiring the JSONB column to hold the given text value at that path. Paths are sorted so
+// the same filter always renders the same query.
+func (b *queryBuilder) attributeConditions(column string, attributes map[string]string) []string {
+	paths := make([]string, 0, len(attributes))
+	for path := range attributes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	conditions := make([]string, len(paths))
+	for i, path := range paths {
+		conditions[i] = column + " #>> " + b.bind(pq.Array(strings.Split(path, "."))) + "::text[] = " + b.bind(attributes[path])
+	}
+	return conditions
+}
+
+// keysetQuery renders a newest-first SELECT that pages by (timeColumn, id) from the cursor.
+// Backward cursors are fetched in ascending order and re-sorted so callers always receive
+// rows newest first.
+func (b *queryBuilder) keysetQuery(columns, table, timeColumn string, cursor *pagination.Cursor, limit int) string {
+	position := fmt.Sprintf("(%s, id)", timeColumn)
+	comparison, direction := "<", "DESC"
+	if cursor.Backward {
+		comparison, direction = ">", "ASC"
+	}
+	b.where(fmt.Sprintf("%s %s (%s, %s)", position, comparison, b.bind(cursor.Timestamp), b.bind(cursor.ID)))
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s %s ORDER BY %s %s, id %s",
+		columns, table, b.whereClause(), timeColumn, direction, direction,
+	)
+	if limit > 0 {
+		query += " LIMIT " + b.bind(limit)
+	}
+
+	if cursor.Backward {
+		query = fmt.Sprintf("SELECT * FROM (%s) page ORDER BY %s DESC, id DESC", query, timeColumn)
+	}
+	return query
+}
+
+// jsonPathsQuery renders a query for the sorted, distinct dotted paths of the values in a JSONB
+// column that are not objects, over the rows selected by from, e.g. os.name for
+// {"os": {"name": "linux"}}
+func jsonPathsQuery(column, from string) string {
+	return `
+		WITH RECURSIVE paths (path, value) AS (
+			SELECT e.key, e.value
+			FROM (` + from + `) AS t,
+				jsonb_each(CASE WHEN jsonb_typeof(t.` + column + `) = 'object' THEN t.` + column + ` ELSE '{}'::jsonb END) AS e
+			UNION ALL
+			SELECT p.path || '.' || e.key, e.value
+			FROM paths p,
+				jsonb_each(CASE WHEN jsonb_typeof(p.value) = 'object' THEN p.value ELSE '{}'::jsonb END) AS e
+		)
+		SELECT DISTINCT path FROM paths WHERE jsonb_typeof(value) <> 'object' ORDER BY path
+	`
+}
+
+// valuesList binds the rows and renders them as a VALUES list. Every column is cast to its
+// type, so the list can be compared with and written to table columns in batch statements.
+func (b *queryBuilder) valuesList(types []string, rows [][]interface{}) string {
+	rendered := make([]string, len(rows))
+	for i, row := range rows {
+		placeholders := make([]string, len(row))
+		for j, value := range row {
+			placeholders[j] = b.bind(value) + "::" + types[j]
+		}
+		rendered[i] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+	return "VALUES " + strings.Join(rendered, ", ")
+}
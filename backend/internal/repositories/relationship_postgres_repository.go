@@ -4,15 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/cmdb-lite/backend/internal/models"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // RelationshipPostgresRepository implements the RelationshipRepository interface for PostgreSQL
 type RelationshipPostgresRepository struct {
-	db *sqlx.DB
+	db dbtx
 }
 
 // NewRelationshipPostgresRepository creates a new RelationshipPostgresRepository
@@ -23,16 +25,19 @@ func NewRelationshipPostgresRepository(db *sqlx.DB) *RelationshipPostgresReposit
 // Create creates a new relationship in the database
 func (r *RelationshipPostgresRepository) Create(ctx context.Context, relationship *models.Relationship) error {
 	query := `
-		INSERT INTO relationships (id, source_id, target_id, type, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO relationships (id, source_id, target_id, type, attributes, version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 1, $6, $7)
 	`
 
+	relationship.Version = 1
 	_, err := r.db.ExecContext(ctx, query,
 		relationship.ID,
 		relationship.SourceID,
 		relationship.TargetID,
 		relationship.Type,
+		relationship.Attributes,
 		relationship.CreatedAt,
+		relationship.UpdatedAt,
 	)
 
 	if err != nil {
@@ -42,12 +47,19 @@ func (r *RelationshipPostgresRepository) Create(ctx context.Context, relationshi
 	return nil
 }
 
+// LockType takes a transaction-level advisory lock on the relationship type, keyed apart from
+// the other advisory locks by the relationship_type namespace
+func (r *RelationshipPostgresRepository) LockType(ctx context.Context, relationshipType string) error {
+	_, err := r.db.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('relationship_type'), hashtext($1))", relationshipType)
+	return err
+}
+
 // GetByID retrieves a relationship by ID
 func (r *RelationshipPostgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Relationship, error) {
 	query := `
-		SELECT id, source_id, target_id, type, created_at
+		SELECT id, source_id, target_id, type, attributes, version, created_at, updated_at
 		FROM relationships
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	var relationship models.Relationship
@@ -65,9 +77,9 @@ func (r *RelationshipPostgresRepository) GetByID(ctx context.Context, id uuid.UU
 // GetBySourceCI retrieves relationships by source CI ID
 func (r *RelationshipPostgresRepository) GetBySourceCI(ctx context.Context, sourceCIID uuid.UUID) ([]*models.Relationship, error) {
 	query := `
-		SELECT id, source_id, target_id, type, created_at
+		SELECT id, source_id, target_id, type, attributes, version, created_at, updated_at
 		FROM relationships
-		WHERE source_id = $1
+		WHERE source_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
 
@@ -83,9 +95,9 @@ func (r *RelationshipPostgresRepository) GetBySourceCI(ctx context.Context, sour
 // GetByTargetCI retrieves relationships by target CI ID
 func (r *RelationshipPostgresRepository) GetByTargetCI(ctx context.Context, targetCIID uuid.UUID) ([]*models.Relationship, error) {
 	query := `
-		SELECT id, source_id, target_id, type, created_at
+		SELECT id, source_id, target_id, type, attributes, version, created_at, updated_at
 		FROM relationships
-		WHERE target_id = $1
+		WHERE target_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
 
@@ -98,12 +110,38 @@ func (r *RelationshipPostgresRepository) GetByTargetCI(ctx context.Context, targ
 	return relationships, nil
 }
 
+// GetByTargetCIs retrieves the relationships of the given types whose target is any of the
+// CIs, filtered by attributes
+func (r *RelationshipPostgresRepository) GetByTargetCIs(ctx context.Context, targetCIIDs []uuid.UUID, types []string, attributes map[string]string) ([]*models.Relationship, error) {
+	b := &queryBuilder{}
+	b.where("target_id = ANY(" + b.bind(pq.Array(uuidStrings(targetCIIDs))) + "::uuid[])")
+	b.where("type = ANY(" + b.bind(pq.Array(types)) + "::text[])")
+	b.where("deleted_at IS NULL")
+	for _, condition := range b.attributeConditions("attributes", attributes) {
+		b.where(condition)
+	}
+
+	query := `
+		SELECT id, source_id, target_id, type, attributes, version, created_at, updated_at
+		FROM relationships
+		` + b.whereClause() + `
+		ORDER BY created_at, id
+	`
+
+	relationships := []*models.Relationship{}
+	if err := r.db.SelectContext(ctx, &relationships, query, b.args...); err != nil {
+		return nil, err
+	}
+
+	return relationships, nil
+}
+
 // GetBySourceAndTarget retrieves relationships by source and target CI IDs
 func (r *RelationshipPostgresRepository) GetBySourceAndTarget(ctx context.Context, sourceCIID, targetCIID uuid.UUID) ([]*models.Relationship, error) {
 	query := `
-		SELECT id, source_id, target_id, type, created_at
+		SELECT id, source_id, target_id, type, attributes, version, created_at, updated_at
 		FROM relationships
-		WHERE source_id = $1 AND target_id = $2
+		WHERE source_id = $1 AND target_id = $2 AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
 
@@ -119,9 +157,9 @@ func (r *RelationshipPostgresRepository) GetBySourceAndTarget(ctx context.Contex
 // GetByType retrieves relationships by type
 func (r *RelationshipPostgresRepository) GetByType(ctx context.Context, relationshipType string) ([]*models.Relationship, error) {
 	query := `
-		SELECT id, source_id, target_id, type, created_at
+		SELECT id, source_id, target_id, type, attributes, version, created_at, updated_at
 		FROM relationships
-		WHERE type = $1
+		WHERE type = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
 
@@ -137,8 +175,9 @@ func (r *RelationshipPostgresRepository) GetByType(ctx context.Context, relation
 // GetAll retrieves all relationships from the database
 func (r *RelationshipPostgresRepository) GetAll(ctx context.Context) ([]*models.Relationship, error) {
 	query := `
-		SELECT id, source_id, target_id, type, created_at
+		SELECT id, source_id, target_id, type, attributes, version, created_at, updated_at
 		FROM relationships
+		WHERE deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
 
@@ -151,42 +190,58 @@ func (r *RelationshipPostgresRepository) GetAll(ctx context.Context) ([]*models.
 	return relationships, nil
 }
 
-// Update updates a relationship in the database
+// Update updates a relationship in the database if its stored version still equals
+// relationship.Version, atomically incrementing it; relationship.Version is set to the new version
 func (r *RelationshipPostgresRepository) Update(ctx context.Context, relationship *models.Relationship) error {
 	query := `
 		UPDATE relationships
-		SET source_id = $2, target_id = $3, type = $4
-		WHERE id = $1
+		SET source_id = $2, target_id = $3, type = $4, attributes = $5, updated_at = $6, version = version + 1
+		WHERE id = $1 AND version = $7 AND deleted_at IS NULL
+		RETURNING version
 	`
 
-	result, err := r.db.ExecContext(ctx, query,
+	var version int
+	err := r.db.GetContext(ctx, &version, query,
 		relationship.ID,
 		relationship.SourceID,
 		relationship.TargetID,
 		relationship.Type,
+		relationship.Attributes,
+		relationship.UpdatedAt,
+		relationship.Version,
 	)
 
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return r.versionConflictOrNotFound(ctx, relationship.ID)
+		}
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	relationship.Version = version
+	return nil
+}
+
+// versionConflictOrNotFound explains why an update matched no row: the relationship exists
+// with another version, or it does not exist
+func (r *RelationshipPostgresRepository) versionConflictOrNotFound(ctx context.Context, id uuid.UUID) error {
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM relationships WHERE id = $1 AND deleted_at IS NULL)`, id)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return errors.New("relationship not found")
+	if exists {
+		return ErrVersionConflict
 	}
-
-	return nil
+	return ErrRelationshipNotFound
 }
 
-// Delete deletes a relationship from the database
-func (r *RelationshipPostgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM relationships WHERE id = $1`
+// Delete deletes a relationship from the database if it is at the given version
+func (r *RelationshipPostgresRepository) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	query := `DELETE FROM relationships WHERE id = $1 AND version = $2 AND deleted_at IS NULL`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.db.ExecContext(ctx, query, id, version)
 	if err != nil {
 		return err
 	}
@@ -197,15 +252,82 @@ func (r *RelationshipPostgresRepository) Delete(ctx context.Context, id uuid.UUI
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("relationship not found")
+		return r.versionConflictOrNotFound(ctx, id)
 	}
 
 	return nil
 }
 
+// ListDeletedWithCIs retrieves the relationships in the trash that carry the deletion time
+// of one of the CIs
+func (r *RelationshipPostgresRepository) ListDeletedWithCIs(ctx context.Context, ciIDs []uuid.UUID) ([]*models.Relationship, error) {
+	query := `
+		SELECT rel.id, rel.source_id, rel.target_id, rel.type, rel.attributes, rel.version, rel.created_at, rel.updated_at
+		FROM relationships rel
+		WHERE rel.deleted_at IS NOT NULL
+			AND EXISTS (
+				SELECT 1 FROM configuration_items ci
+				WHERE ci.id = ANY($1::uuid[]) AND ci.id IN (rel.source_id, rel.target_id)
+					AND ci.deleted_at = rel.deleted_at
+			)
+		ORDER BY rel.created_at, rel.id
+	`
+
+	relationships := []*models.Relationship{}
+	if err := r.db.SelectContext(ctx, &relationships, query, pq.Array(uuidStrings(ciIDs))); err != nil {
+		return nil, err
+	}
+
+	return relationships, nil
+}
+
+// ListRestorable retrieves the relationships of a CI in the trash that can come back with it.
+// Of several relationships in the trash between the same CIs with the same type, the oldest
+// is restorable.
+func (r *RelationshipPostgresRepository) ListRestorable(ctx context.Context, ciID uuid.UUID) ([]*models.Relationship, error) {
+	query := `
+		SELECT * FROM (
+			SELECT DISTINCT ON (rel.source_id, rel.target_id, rel.type)
+				rel.id, rel.source_id, rel.target_id, rel.type, rel.attributes, rel.version, rel.created_at, rel.updated_at
+			FROM relationships rel
+			WHERE (rel.source_id = $1 OR rel.target_id = $1) AND rel.deleted_at IS NOT NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM configuration_items ci
+					WHERE ci.id IN (rel.source_id, rel.target_id) AND ci.id <> $1 AND ci.deleted_at IS NOT NULL
+				)
+				AND NOT EXISTS (
+					SELECT 1 FROM relationships live
+					WHERE live.source_id = rel.source_id AND live.target_id = rel.target_id
+						AND live.type = rel.type AND live.deleted_at IS NULL
+				)
+			ORDER BY rel.source_id, rel.target_id, rel.type, rel.created_at, rel.id
+		) restorable
+		ORDER BY created_at, id
+	`
+
+	relationships := []*models.Relationship{}
+	if err := r.db.SelectContext(ctx, &relationships, query, ciID); err != nil {
+		return nil, err
+	}
+
+	return relationships, nil
+}
+
+// Restore takes a relationship out of the trash
+func (r *RelationshipPostgresRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE relationships SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	return expectRowsAffected(result, ErrRelationshipNotFound)
+}
+
 // DeleteBySourceCI deletes all relationships for a source CI
 func (r *RelationshipPostgresRepository) DeleteBySourceCI(ctx context.Context, sourceCIID uuid.UUID) error {
-	query := `DELETE FROM relationships WHERE source_id = $1`
+	query := `DELETE FROM relationships WHERE source_id = $1 AND deleted_at IS NULL`
 
 	_, err := r.db.ExecContext(ctx, query, sourceCIID)
 	if err != nil {
@@ -217,7 +339,7 @@ func (r *RelationshipPostgresRepository) DeleteBySourceCI(ctx context.Context, s
 
 // DeleteByTargetCI deletes all relationships for a target CI
 func (r *RelationshipPostgresRepository) DeleteByTargetCI(ctx context.Context, targetCIID uuid.UUID) error {
-	query := `DELETE FROM relationships WHERE target_id = $1`
+	query := `DELETE FROM relationships WHERE target_id = $1 AND deleted_at IS NULL`
 
 	_, err := r.db.ExecContext(ctx, query, targetCIID)
 	if err != nil {
@@ -226,3 +348,144 @@ func (r *RelationshipPostgresRepository) DeleteByTargetCI(ctx context.Context, t
 
 	return nil
 }
+
+// relationshipColumns is the column list selected for every relationship query
+const relationshipColumns = "id, source_id, target_id, type, attributes, version, created_at, updated_at"
+
+// newRelationshipQueryBuilder creates a query builder with the WHERE conditions for the filter
+func newRelationshipQueryBuilder(filter RelationshipFilter) *queryBuilder {
+	b := &queryBuilder{}
+	b.where("deleted_at IS NULL")
+
+	if filter.SourceID != uuid.Nil {
+		b.where("source_id = " + b.bind(filter.SourceID))
+	}
+	if filter.TargetID != uuid.Nil {
+		b.where("target_id = " + b.bind(filter.TargetID))
+	}
+	if filter.Type != "" {
+		b.where("type = " + b.bind(filter.Type))
+	}
+	for _, condition := range b.attributeConditions("attributes", filter.Attributes) {
+		b.where(condition)
+	}
+
+	return b
+}
+
+// List retrieves the relationships matching the filter, newest first
+func (r *RelationshipPostgresRepository) List(ctx context.Context, filter RelationshipFilter) ([]*models.Relationship, error) {
+	builder := newRelationshipQueryBuilder(filter)
+	query := relationshipSelectQuery(builder, filter)
+
+	var relationships []*models.Relationship
+	err := r.db.SelectContext(ctx, &relationships, query, builder.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return relationships, nil
+}
+
+// relationshipSelectQuery renders the SELECT statement of the filter with ordering and
+// pagination
+func relationshipSelectQuery(builder *queryBuilder, filter RelationshipFilter) string {
+	if filter.Cursor != nil {
+		return builder.keysetQuery(relationshipColumns, "relationships", "created_at", filter.Cursor, filter.Limit)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM relationships %s ORDER BY created_at DESC, id DESC", relationshipColumns, builder.whereClause())
+	if filter.Limit > 0 {
+		query += " LIMIT " + builder.bind(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += " OFFSET " + builder.bind(filter.Offset)
+	}
+	return query
+}
+
+// Count returns the number of relationships matching the filter, ignoring pagination
+func (r *RelationshipPostgresRepository) Count(ctx context.Context, filter RelationshipFilter) (int, error) {
+	builder := newRelationshipQueryBuilder(filter)
+	query := "SELECT COUNT(*) FROM relationships " + builder.whereClause()
+
+	var count int
+	err := r.db.GetContext(ctx, &count, query, builder.args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Stream calls fn with each relationship matching the filter as the relationships are read
+// from the database
+func (r *RelationshipPostgresRepository) Stream(ctx context.Context, filter RelationshipFilter, fn func(relationship *models.Relationship) error) error {
+	builder := newRelationshipQueryBuilder(filter)
+	query := relationshipSelectQuery(builder, filter)
+
+	return streamRows(ctx, r.db, query, builder.args, fn)
+}
+
+// AttributePaths returns the sorted dotted paths of the attribute values of the relationships
+// matching the filter
+func (r *RelationshipPostgresRepository) AttributePaths(ctx context.Context, filter RelationshipFilter) ([]string, error) {
+	builder := newRelationshipQueryBuilder(filter)
+	query := jsonPathsQuery("attributes", "SELECT attributes FROM relationships "+builder.whereClause())
+
+	paths := []string{}
+	err := r.db.SelectContext(ctx, &paths, query, builder.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// Traverse walks the relationship graph from the root CI and returns the CIs reached and
+// the relationships between them
+func (r *RelationshipPostgresRepository) Traverse(ctx context.Context, rootID uuid.UUID, filter GraphFilter) (*models.CIGraph, error) {
+	maxNodes := filter.MaxNodes
+	if maxNodes <= 0 {
+		maxNodes = DefaultGraphNodes
+	}
+
+	// Reach one node more than the cap to find out whether the graph was truncated
+	nodesBuilder := &queryBuilder{}
+	nodesQuery := graphNodesQuery(nodesBuilder, rootID, filter, maxNodes+1)
+
+	var nodes []*models.GraphNode
+	err := r.db.SelectContext(ctx, &nodes, nodesQuery, nodesBuilder.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.CIGraph{
+		RootID: rootID,
+		Nodes:  nodes,
+		Edges:  []*models.Relationship{},
+	}
+	if len(nodes) > maxNodes {
+		result.Nodes = nodes[:maxNodes]
+		result.Truncated = true
+	}
+	if len(result.Nodes) == 0 {
+		result.Nodes = []*models.GraphNode{}
+		return result, nil
+	}
+
+	ids := make([]uuid.UUID, len(result.Nodes))
+	for i, node := range result.Nodes {
+		ids[i] = node.ID
+	}
+
+	edgesBuilder := &queryBuilder{}
+	edgesQuery := graphEdgesQuery(edgesBuilder, ids, filter)
+
+	err = r.db.SelectContext(ctx, &result.Edges, edgesQuery, edgesBuilder.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
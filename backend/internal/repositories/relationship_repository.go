@@ -2,16 +2,77 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/pagination"
 	"github.com/google/uuid"
 )
 
+// ErrRelationshipNotFound is returned by writes to a relationship that does not exist
+var ErrRelationshipNotFound = errors.New("relationship not found")
+
+// RelationshipFilter describes the filtering and pagination options used when listing relationships.
+// Zero values mean "no restriction"; a zero Limit returns every matching row.
+type RelationshipFilter struct {
+	SourceID uuid.UUID
+	TargetID uuid.UUID
+	Type     string
+
+	// Attributes maps dotted attribute paths (e.g. "protocol" or "link.speed") to the
+	// value they must equal
+	Attributes map[string]string
+
+	Limit  int
+	Offset int
+
+	// Cursor, when set, pages by (created_at, id) from the cursor position instead of using Offset
+	Cursor *pagination.Cursor
+}
+
+// Graph traversal directions for GraphFilter.Direction
+const (
+	GraphDirectionOut  = "out"
+	GraphDirectionIn   = "in"
+	GraphDirectionBoth = "both"
+)
+
+// Graph traversal limits
+const (
+	MaxGraphDepth     = 10
+	DefaultGraphNodes = 500
+)
+
+// GraphFilter describes a traversal from a root CI. Direction "out" follows relationships from
+// source to target, "in" from target to source and "both" ignores their direction. Empty type
+// lists mean "no restriction"; CIs of other types are neither returned nor traversed.
+type GraphFilter struct {
+	Depth             int
+	Direction         string
+	RelationshipTypes []string
+	CITypes           []string
+
+	// RelationshipAttributes restricts the relationships followed to those whose dotted
+	// attribute paths equal the given values
+	RelationshipAttributes map[string]string
+
+	// AsOf, when set, walks the graph as it was at that time using the history tables
+	AsOf *time.Time
+
+	// MaxNodes caps the number of returned CIs, nearest first; zero means DefaultGraphNodes
+	MaxNodes int
+}
+
 // RelationshipRepository defines the interface for relationship repository operations
 type RelationshipRepository interface {
 	// Create creates a new relationship in the database
 	Create(ctx context.Context, relationship *models.Relationship) error
 
+	// LockType serializes the writes of relationships of a type until the transaction ends:
+	// it waits for the transactions that locked the type before
+	LockType(ctx context.Context, relationshipType string) error
+
 	// GetByID retrieves a relationship by ID
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Relationship, error)
 
@@ -21,6 +82,10 @@ type RelationshipRepository interface {
 	// GetByTargetCI retrieves relationships by target CI ID
 	GetByTargetCI(ctx context.Context, targetCIID uuid.UUID) ([]*models.Relationship, error)
 
+	// GetByTargetCIs retrieves the relationships of the given types whose target is any of the
+	// CIs and whose attributes hold the given values at their dotted paths
+	GetByTargetCIs(ctx context.Context, targetCIIDs []uuid.UUID, types []string, attributes map[string]string) ([]*models.Relationship, error)
+
 	// GetBySourceAndTarget retrieves relationships by source and target CI IDs
 	GetBySourceAndTarget(ctx context.Context, sourceCIID, targetCIID uuid.UUID) ([]*models.Relationship, error)
 
@@ -30,15 +95,50 @@ type RelationshipRepository interface {
 	// GetAll retrieves all relationships from the database
 	GetAll(ctx context.Context) ([]*models.Relationship, error)
 
-	// Update updates a relationship in the database
+	// Update updates a relationship in the database if its stored version equals
+	// relationship.Version, and increments relationship.Version. It returns
+	// ErrVersionConflict if the version has moved on.
 	Update(ctx context.Context, relationship *models.Relationship) error
 
-	// Delete deletes a relationship from the database
-	Delete(ctx context.Context, id uuid.UUID) error
+	// Delete deletes a relationship from the database if its stored version equals version.
+	// It returns ErrVersionConflict if the version has moved on and ErrRelationshipNotFound if
+	// there is no such relationship.
+	Delete(ctx context.Context, id uuid.UUID, version int) error
+
+	// ListDeletedWithCIs retrieves the relationships in the trash that were moved there with
+	// one of the CIs, i.e. that carry the CI's deletion time, oldest first
+	ListDeletedWithCIs(ctx context.Context, ciIDs []uuid.UUID) ([]*models.Relationship, error)
+
+	// ListRestorable retrieves the relationships of a CI that are in the trash and can come
+	// back with it, oldest first: those whose other end is live and that would not duplicate
+	// a live relationship or each other
+	ListRestorable(ctx context.Context, ciID uuid.UUID) ([]*models.Relationship, error)
+
+	// Restore takes a relationship out of the trash. It returns ErrRelationshipNotFound if the
+	// relationship is not in the trash.
+	Restore(ctx context.Context, id uuid.UUID) error
 
 	// DeleteBySourceCI deletes all relationships for a source CI
 	DeleteBySourceCI(ctx context.Context, sourceCIID uuid.UUID) error
 
 	// DeleteByTargetCI deletes all relationships for a target CI
 	DeleteByTargetCI(ctx context.Context, targetCIID uuid.UUID) error
+
+	// List retrieves the relationships matching the filter, newest first
+	List(ctx context.Context, filter RelationshipFilter) ([]*models.Relationship, error)
+
+	// Count returns the number of relationships matching the filter, ignoring pagination
+	Count(ctx context.Context, filter RelationshipFilter) (int, error)
+
+	// Stream calls fn with each relationship matching the filter, newest first, as the
+	// relationships are read from the database. It stops at the first error fn returns.
+	Stream(ctx context.Context, filter RelationshipFilter, fn func(relationship *models.Relationship) error) error
+
+	// AttributePaths returns the sorted dotted paths of the attribute values of the
+	// relationships matching the filter, ignoring pagination
+	AttributePaths(ctx context.Context, filter RelationshipFilter) ([]string, error)
+
+	// Traverse walks the relationship graph from the root CI and returns the CIs reached and
+	// the relationships between them
+	Traverse(ctx context.Context, rootID uuid.UUID, filter GraphFilter) (*models.CIGraph, error)
 }
@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// relationshipTypeColumns is the column list selected for every relationship type query
+const relationshipTypeColumns = `id, name, inverse_name, description, allowed_source_types, allowed_target_types,
+	cardinality, acyclic, created_at, updated_at`
+
+// RelationshipTypePostgresRepository implements the RelationshipTypeRepository interface for PostgreSQL
+type RelationshipTypePostgresRepository struct {
+	db dbtx
+}
+
+// NewRelationshipTypePostgresRepository creates a new RelationshipTypePostgresRepository
+func NewRelationshipTypePostgresRepository(db *sqlx.DB) *RelationshipTypePostgresRepository {
+	return &RelationshipTypePostgresRepository{db: db}
+}
+
+// Create creates a new relationship type in the database
+func (r *RelationshipTypePostgresRepository) Create(ctx context.Context, relType *models.RelationshipType) error {
+	query := `
+		INSERT INTO relationship_types (id, name, inverse_name, description, allowed_source_types,
+			allowed_target_types, cardinality, acyclic, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		relType.ID,
+		relType.Name,
+		relType.InverseName,
+		relType.Description,
+		relType.AllowedSourceTypes,
+		relType.AllowedTargetTypes,
+		relType.Cardinality,
+		relType.Acyclic,
+		relType.CreatedAt,
+		relType.UpdatedAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetByID retrieves a relationship type by ID
+func (r *RelationshipTypePostgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.RelationshipType, error) {
+	query := `SELECT ` + relationshipTypeColumns + ` FROM relationship_types WHERE id = $1`
+
+	var relType models.RelationshipType
+	err := r.db.GetContext(ctx, &relType, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("relationship type not found")
+		}
+		return nil, err
+	}
+
+	return &relType, nil
+}
+
+// GetByName retrieves a relationship type by its name or, failing that, its inverse name
+func (r *RelationshipTypePostgresRepository) GetByName(ctx context.Context, name string) (*models.RelationshipType, error) {
+	query := `
+		SELECT ` + relationshipTypeColumns + `
+		FROM relationship_types
+		WHERE name = $1 OR inverse_name = $1
+		ORDER BY name = $1 DESC
+		LIMIT 1
+	`
+
+	var relType models.RelationshipType
+	err := r.db.GetContext(ctx, &relType, query, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("relationship type not found")
+		}
+		return nil, err
+	}
+
+	return &relType, nil
+}
+
+// GetAll retrieves all relationship types from the database
+func (r *RelationshipTypePostgresRepository) GetAll(ctx context.Context) ([]*models.RelationshipType, error) {
+	query := `SELECT ` + relationshipTypeColumns + ` FROM relationship_types ORDER BY name ASC`
+
+	var relTypes []*models.RelationshipType
+	err := r.db.SelectContext(ctx, &relTypes, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return relTypes, nil
+}
+
+// Update updates a relationship type in the database
+func (r *RelationshipTypePostgresRepository) Update(ctx context.Context, relType *models.RelationshipType) error {
+	query := `
+		UPDATE relationship_types
+		SET name = $2, inverse_name = $3, description = $4, allowed_source_types = $5,
+			allowed_target_types = $6, cardinality = $7, acyclic = $8, updated_at = $9
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		relType.ID,
+		relType.Name,
+		relType.InverseName,
+		relType.Description,
+		relType.AllowedSourceTypes,
+		relType.AllowedTargetTypes,
+		relType.Cardinality,
+		relType.Acyclic,
+		relType.UpdatedAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("relationship type not found")
+	}
+
+	return nil
+}
+
+// Delete deletes a relationship type from the database
+func (r *RelationshipTypePostgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM relationship_types WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("relationship type not found")
+	}
+
+	return nil
+}
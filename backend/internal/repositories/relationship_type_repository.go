@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// RelationshipTypeRepository defines the interface for relationship type registry operations
+type RelationshipTypeRepository interface {
+	// Create creates a new relationship type in the database
+	Create(ctx context.Context, relType *models.RelationshipType) error
+
+	// GetByID retrieves a relationship type by ID
+	GetByID(ctx context.Context, id uuid.UUID) (*models.RelationshipType, error)
+
+	// GetByName retrieves a relationship type by its name or, failing that, its inverse name
+	GetByName(ctx context.Context, name string) (*models.RelationshipType, error)
+
+	// GetAll retrieves all relationship types from the database
+	GetAll(ctx context.Context) ([]*models.RelationshipType, error)
+
+	// Update updates a relationship type in the database
+	Update(ctx context.Context, relType *models.RelationshipType) error
+
+	// Delete deletes a relationship type from the database
+	Delete(ctx context.Context, id uuid.UUID) error
+}
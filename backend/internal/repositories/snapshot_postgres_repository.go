@@ -0,0 +1,282 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// snapshotTable describes how a snapshot table is read and restored
+type snapshotTable struct {
+	// orderBy orders the rows of the snapshot
+	orderBy string
+	// secret is a column with credentials, left out unless asked for. Restored rows without
+	// it get an empty value.
+	secret string
+	// generated is a column the database assigns, left out of the snapshot
+	generated string
+	// clear removes the existing rows before the restore
+	clear bool
+	// keepExisting skips restored rows whose keys are taken
+	keepExisting bool
+	// historyOf is the table whose history the table records. The history triggers record
+	// rows as the table is restored; they are replaced by the snapshot's.
+	historyOf string
+	// quiet restores the rows without notifying the change feed, whose clients would
+	// otherwise receive the restored history as new changes
+	quiet bool
+}
+
+// snapshotTables describes the SnapshotTables
+var snapshotTables = map[string]snapshotTable{
+	"ci_types":                    {orderBy: "id", clear: true},
+	"relationship_types":          {orderBy: "id", clear: true},
+	"users":                       {orderBy: "id", secret: "password_hash", keepExisting: true},
+	"configuration_items":         {orderBy: "id", clear: true},
+	"ci_external_ids":             {orderBy: "source, external_id"},
+	"relationships":               {orderBy: "id"},
+	"configuration_items_history": {orderBy: "history_id", generated: "history_id", historyOf: "configuration_items"},
+	"relationships_history":       {orderBy: "history_id", generated: "history_id", historyOf: "relationships"},
+	"audit_logs":                  {orderBy: "seq", generated: "seq", keepExisting: true, quiet: true},
+}
+
+// snapshotLiveTables are the tables that must have no rows outside the trash for a snapshot to
+// be restored
+var snapshotLiveTables = []string{"configuration_items", "relationships"}
+
+// SnapshotPostgresRepository implements the SnapshotRepository interface for PostgreSQL
+type SnapshotPostgresRepository struct {
+	db dbtx
+}
+
+// NewSnapshotPostgresRepository creates a new SnapshotPostgresRepository
+func NewSnapshotPostgresRepository(db *sqlx.DB) *SnapshotPostgresRepository {
+	return &SnapshotPostgresRepository{db: db}
+}
+
+// InSnapshot calls fn with a repository reading a consistent snapshot of the database. It
+// runs in a read-only repeatable read transaction; inside a unit of work it uses that
+// transaction instead.
+func (r *SnapshotPostgresRepository) InSnapshot(ctx context.Context, fn func(snapshot SnapshotRepository) error) error {
+	sqlxDB, ok := r.db.(*sqlx.DB)
+	if !ok {
+		return fn(r)
+	}
+
+	tx, err := sqlxDB.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&SnapshotPostgresRepository{db: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SchemaVersion returns the version of the latest migration applied to the database
+func (r *SnapshotPostgresRepository) SchemaVersion(ctx context.Context) (int64, error) {
+	query := `SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied`
+
+	var version int64
+	if err := r.db.GetContext(ctx, &version, query); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Columns returns the columns of a snapshot table that a snapshot holds
+func (r *SnapshotPostgresRepository) Columns(ctx context.Context, table string, secrets bool) ([]string, error) {
+	spec, err := getSnapshotTable(table)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+		ORDER BY ordinal_position
+	`
+
+	var all []string
+	if err := r.db.SelectContext(ctx, &all, query, table); err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, 0, len(all))
+	for _, column := range all {
+		if column == spec.generated || (column == spec.secret && !secrets) {
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// StreamRows calls fn with the columns of each row of a snapshot table as they are read
+func (r *SnapshotPostgresRepository) StreamRows(ctx context.Context, table string, columns []string, fn func(row json.RawMessage) error) error {
+	spec, err := getSnapshotTable(table)
+	if err != nil {
+		return err
+	}
+
+	// Build the object column by column to keep the table's column order
+	fields := make([]string, len(columns))
+	for i, column := range columns {
+		fields[i] = pq.QuoteLiteral(column) + ", " + pq.QuoteIdentifier(column)
+	}
+	query := fmt.Sprintf("SELECT json_build_object(%s) FROM %s ORDER BY %s",
+		strings.Join(fields, ", "), pq.QuoteIdentifier(table), spec.orderBy)
+
+	rows, err := r.db.QueryxContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row json.RawMessage
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// NonEmptyTables returns the tables with CIs or relationships outside the trash
+func (r *SnapshotPostgresRepository) NonEmptyTables(ctx context.Context) ([]string, error) {
+	tables := []string{}
+	for _, table := range snapshotLiveTables {
+		var exists bool
+		query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE deleted_at IS NULL)", pq.QuoteIdentifier(table))
+		if err := r.db.GetContext(ctx, &exists, query); err != nil {
+			return nil, err
+		}
+		if exists {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+// PrepareRestore makes way for the rows of a snapshot table
+func (r *SnapshotPostgresRepository) PrepareRestore(ctx context.Context, table string) error {
+	spec, err := getSnapshotTable(table)
+	if err != nil {
+		return err
+	}
+
+	var query string
+	switch {
+	case spec.clear:
+		query = fmt.Sprintf("DELETE FROM %s", pq.QuoteIdentifier(table))
+	case spec.historyOf != "":
+		query = fmt.Sprintf("DELETE FROM %s WHERE id IN (SELECT id FROM %s)",
+			pq.QuoteIdentifier(table), pq.QuoteIdentifier(spec.historyOf))
+	default:
+		return nil
+	}
+
+	_, err = r.db.ExecContext(ctx, query)
+	return err
+}
+
+// RestoreRows inserts rows of a snapshot table with one statement. The database parses the
+// rows into the table's row type, so every column is converted as it would be on insert.
+func (r *SnapshotPostgresRepository) RestoreRows(ctx context.Context, table string, columns []string, rows []json.RawMessage) (int64, error) {
+	spec, err := getSnapshotTable(table)
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	targets := make([]string, len(columns))
+	for i, column := range columns {
+		targets[i] = pq.QuoteIdentifier(column)
+	}
+	values := append([]string{}, targets...)
+	if spec.secret != "" && !containsString(columns, spec.secret) {
+		targets = append(targets, pq.QuoteIdentifier(spec.secret))
+		values = append(values, "''")
+	}
+
+	query := fmt.Sprintf("INSERT INTO %[1]s (%[2]s) SELECT %[3]s FROM jsonb_populate_recordset(NULL::%[1]s, $1::jsonb)",
+		pq.QuoteIdentifier(table), strings.Join(targets, ", "), strings.Join(values, ", "))
+	if spec.keepExisting {
+		query += " ON CONFLICT DO NOTHING"
+	}
+
+	array := append([]byte{'['}, bytes.Join(rowsAsBytes(rows), []byte{','})...)
+	array = append(array, ']')
+
+	var restored int64
+	err = inTx(ctx, r.db, func(tx dbtx) error {
+		// The change feed's trigger skips the notifications of a restoring transaction
+		if spec.quiet {
+			if _, err := tx.ExecContext(ctx, "SELECT set_config('cmdb.restoring', 'on', true)"); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.ExecContext(ctx, query, string(array))
+		if err != nil {
+			return err
+		}
+		restored, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		// Data exceptions and integrity constraint violations are caused by the rows
+		if errors.As(err, &pqErr) && (pqErr.Code.Class() == "22" || pqErr.Code.Class() == "23") {
+			return 0, fmt.Errorf("%w: %s", ErrSnapshotRowsRejected, pqErr.Message)
+		}
+		return 0, err
+	}
+
+	return restored, nil
+}
+
+// getSnapshotTable returns the description of a snapshot table
+func getSnapshotTable(table string) (snapshotTable, error) {
+	spec, ok := snapshotTables[table]
+	if !ok {
+		return snapshotTable{}, fmt.Errorf("%s is not a snapshot table", table)
+	}
+	return spec, nil
+}
+
+// rowsAsBytes returns the rows as byte slices
+func rowsAsBytes(rows []json.RawMessage) [][]byte {
+	data := make([][]byte, len(rows))
+	for i, row := range rows {
+		data[i] = row
+	}
+	return data
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// SnapshotTables are the tables a snapshot of the CMDB holds, in the order they are restored
+// so that rows come after the rows they reference
+var SnapshotTables = []string{
+	"ci_types",
+	"relationship_types",
+	"users",
+	"configuration_items",
+	"ci_external_ids",
+	"relationships",
+	"configuration_items_history",
+	"relationships_history",
+	"audit_logs",
+}
+
+// ErrSnapshotRowsRejected is returned by RestoreRows when the database rejects rows, e.g.
+// because they reference rows the snapshot does not hold. Errors wrapping it carry the
+// database's message.
+var ErrSnapshotRowsRejected = errors.New("snapshot rows rejected")
+
+// SnapshotRepository defines the interface for reading and restoring whole tables of the
+// CMDB. Rows are JSON objects keyed by column.
+type SnapshotRepository interface {
+	// InSnapshot calls fn with a repository reading a consistent snapshot of the database,
+	// unaffected by changes made while fn runs
+	InSnapshot(ctx context.Context, fn func(snapshot SnapshotRepository) error) error
+
+	// SchemaVersion returns the version of the latest migration applied to the database
+	SchemaVersion(ctx context.Context) (int64, error)
+
+	// Columns returns the columns of a snapshot table that a snapshot holds, in table order.
+	// Columns with credentials, like password hashes, are only included with secrets.
+	Columns(ctx context.Context, table string, secrets bool) ([]string, error)
+
+	// StreamRows calls fn with the columns of each row of a snapshot table as they are read
+	StreamRows(ctx context.Context, table string, columns []string, fn func(row json.RawMessage) error) error
+
+	// NonEmptyTables returns the tables with CIs or relationships outside the trash. A
+	// snapshot can only be restored when there are none.
+	NonEmptyTables(ctx context.Context) ([]string, error)
+
+	// PrepareRestore makes way for the rows of a snapshot table. The CI and relationship types
+	// are removed, since the snapshot's replace them, and so are the CIs in the trash with
+	// their relationships and external IDs. The history of the restored CIs and relationships,
+	// including the rows recorded while they were restored, is replaced by the snapshot's.
+	PrepareRestore(ctx context.Context, table string) error
+
+	// RestoreRows inserts rows of a snapshot table with the columns and returns the number
+	// inserted. Users whose ID or username is taken are skipped and keep their account, and
+	// audit logs that exist are skipped; users without a password hash get one no password
+	// matches. Restored audit logs are not sent to the change feed's listeners.
+	RestoreRows(ctx context.Context, table string, columns []string, rows []json.RawMessage) (int64, error)
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// dbtx is the part of sqlx.DB and sqlx.Tx used by the PostgreSQL repositories, so that the
+// same repository code runs on the database or inside a transaction
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+}
+
+// Repos are the repositories of a unit of work. They all run in the same transaction.
+type Repos struct {
+	CIs               CIRepository
+	Relationships     RelationshipRepository
+	AuditLogs         AuditLogRepository
+	CITypes           CITypeRepository
+	RelationshipTypes RelationshipTypeRepository
+	Snapshots         SnapshotRepository
+	Webhooks          WebhookRepository
+	Outbox            OutboxRepository
+}
+
+// UnitOfWork runs a group of repository operations atomically
+type UnitOfWork interface {
+	// WithTx calls fn with repositories bound to a new transaction. The transaction is
+	// committed if fn returns nil and rolled back otherwise; fn's error is returned as is.
+	WithTx(ctx context.Context, fn func(tx Repos) error) error
+}
+
+// PostgresUnitOfWork implements the UnitOfWork interface for PostgreSQL
+type PostgresUnitOfWork struct {
+	db *sqlx.DB
+}
+
+// NewPostgresUnitOfWork creates a new PostgresUnitOfWork
+func NewPostgresUnitOfWork(db *sqlx.DB) *PostgresUnitOfWork {
+	return &PostgresUnitOfWork{db: db}
+}
+
+// WithTx calls fn with repositories bound to a new transaction
+func (u *PostgresUnitOfWork) WithTx(ctx context.Context, fn func(tx Repos) error) error {
+	return inTx(ctx, u.db, func(tx dbtx) error {
+		return fn(Repos{
+			CIs:               &CIPostgresRepository{db: tx},
+			Relationships:     &RelationshipPostgresRepository{db: tx},
+			AuditLogs:         &AuditLogPostgresRepository{db: tx},
+			CITypes:           &CITypePostgresRepository{db: tx},
+			RelationshipTypes: &RelationshipTypePostgresRepository{db: tx},
+			Snapshots:         &SnapshotPostgresRepository{db: tx},
+			Webhooks:          &WebhookPostgresRepository{db: tx},
+			Outbox:            &OutboxPostgresRepository{db: tx},
+		})
+	})
+}
+
+// streamRows runs a query and calls fn with each row as it is read, so that large results are
+// never held in memory. It stops at the first error fn returns.
+func streamRows[T any](ctx context.Context, db dbtx, query string, args []interface{}, fn func(*T) error) error {
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := new(T)
+		if err := rows.StructScan(row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// inTx calls fn in a transaction on db. When db is already a transaction, fn runs in it and
+// commits or rolls back with the rest of that transaction.
+func inTx(ctx context.Context, db dbtx, fn func(tx dbtx) error) error {
+	sqlxDB, ok := db.(*sqlx.DB)
+	if !ok {
+		return fn(db)
+	}
+
+	tx, err := sqlxDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,298 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// webhookDeliveryColumns are the columns of a webhook delivery
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+		next_attempt_at, last_attempt_at, response_status, last_error, created_at`
+
+// WebhookPostgresRepository implements the WebhookRepository interface for PostgreSQL
+type WebhookPostgresRepository struct {
+	db dbtx
+}
+
+// NewWebhookPostgresRepository creates a new WebhookPostgresRepository
+func NewWebhookPostgresRepository(db *sqlx.DB) *WebhookPostgresRepository {
+	return &WebhookPostgresRepository{db: db}
+}
+
+// CreateSubscription creates a new webhook subscription in the database
+func (r *WebhookPostgresRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (id, name, url, secret, event_types, ci_types, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		subscription.ID,
+		subscription.Name,
+		subscription.URL,
+		subscription.Secret,
+		subscription.EventTypes,
+		subscription.CITypes,
+		subscription.Active,
+		subscription.CreatedAt,
+		subscription.UpdatedAt,
+	)
+	return err
+}
+
+// GetSubscription retrieves a webhook subscription by ID
+func (r *WebhookPostgresRepository) GetSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error) {
+	query := `
+		SELECT id, name, url, secret, event_types, ci_types, active, created_at, updated_at
+		FROM webhook_subscriptions
+		WHERE id = $1
+	`
+
+	var subscription models.WebhookSubscription
+	err := r.db.GetContext(ctx, &subscription, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
+// ListSubscriptions retrieves all webhook subscriptions, ordered by name
+func (r *WebhookPostgresRepository) ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	query := `
+		SELECT id, name, url, secret, event_types, ci_types, active, created_at, updated_at
+		FROM webhook_subscriptions
+		ORDER BY name ASC, id ASC
+	`
+
+	subscriptions := []*models.WebhookSubscription{}
+	if err := r.db.SelectContext(ctx, &subscriptions, query); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// UpdateSubscription updates a webhook subscription in the database
+func (r *WebhookPostgresRepository) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	query := `
+		UPDATE webhook_subscriptions
+		SET name = $2, url = $3, secret = $4, event_types = $5, ci_types = $6, active = $7, updated_at = $8
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		subscription.ID,
+		subscription.Name,
+		subscription.URL,
+		subscription.Secret,
+		subscription.EventTypes,
+		subscription.CITypes,
+		subscription.Active,
+		subscription.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return expectRowsAffected(result, ErrWebhookNotFound)
+}
+
+// DeleteSubscription deletes a webhook subscription; its deliveries are deleted with it
+func (r *WebhookPostgresRepository) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM webhook_subscriptions WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	return expectRowsAffected(result, ErrWebhookNotFound)
+}
+
+// ListDeliveries retrieves a page of a subscription's deliveries, newest first
+func (r *WebhookPostgresRepository) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2::text = '' OR status = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	deliveries := []*models.WebhookDelivery{}
+	if err := r.db.SelectContext(ctx, &deliveries, query, subscriptionID, status, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// CountDeliveries counts a subscription's deliveries with the status, or all if it is empty
+func (r *WebhookPostgresRepository) CountDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2::text = '' OR status = $2)
+	`
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, subscriptionID, status); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetDelivery retrieves a webhook delivery by ID
+func (r *WebhookPostgresRepository) GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE id = $1
+	`
+
+	var delivery models.WebhookDelivery
+	err := r.db.GetContext(ctx, &delivery, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// Redeliver creates a pending delivery of the same event and payload as a delivery
+func (r *WebhookPostgresRepository) Redeliver(ctx context.Context, id uuid.UUID, now time.Time) (*models.WebhookDelivery, error) {
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, next_attempt_at, created_at)
+		SELECT subscription_id, event_id, event_type, payload, $2, $2
+		FROM webhook_deliveries
+		WHERE id = $1
+		RETURNING ` + webhookDeliveryColumns
+
+	var delivery models.WebhookDelivery
+	err := r.db.GetContext(ctx, &delivery, query, id, now)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// ClaimDueDeliveries leases up to limit due deliveries of active subscriptions. Deliveries
+// locked by another worker's claim are skipped rather than waited for.
+func (r *WebhookPostgresRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $2
+		FROM (
+			SELECT d.id
+			FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= $1 AND s.active
+			ORDER BY d.next_attempt_at ASC, d.id ASC
+			LIMIT $3
+			FOR UPDATE OF d SKIP LOCKED
+		) due
+		WHERE webhook_deliveries.id = due.id
+		RETURNING webhook_deliveries.id, subscription_id, event_id, event_type, payload, status, attempts,
+			next_attempt_at, last_attempt_at, response_status, last_error, created_at
+	`
+
+	deliveries := []*models.WebhookDelivery{}
+	if err := r.db.SelectContext(ctx, &deliveries, query, now, now.Add(lease), limit); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// UpdateDeliveryAttempt records the outcome of an attempt of a delivery
+func (r *WebhookPostgresRepository) UpdateDeliveryAttempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_attempt_at = $5, response_status = $6, last_error = $7
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		delivery.ID,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.LastAttemptAt,
+		delivery.ResponseStatus,
+		delivery.LastError,
+	)
+	if err != nil {
+		return err
+	}
+
+	return expectRowsAffected(result, ErrWebhookNotFound)
+}
+
+// enqueueWebhookDeliveries creates a pending delivery of the events recorded by audit logs for
+// each active subscription they match. An event matches a subscription's event types when the
+// list is empty or holds the event's type, "<entity type>.<action>", or "<entity type>.*". The
+// CI types only restrict events of CIs, by the CI's type in the audit log's details.
+func enqueueWebhookDeliveries(ctx context.Context, db dbtx, auditLogIDs []uuid.UUID) error {
+	if len(auditLogIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT s.id, a.id, a.entity_type || '.' || a.action, ` + auditLogEventPayload + `
+		FROM audit_logs a
+		JOIN webhook_subscriptions s ON s.active
+		WHERE a.id = ANY($1::uuid[])
+		AND (
+			cardinality(s.event_types) = 0
+			OR a.entity_type || '.' || a.action = ANY(s.event_types)
+			OR a.entity_type || '.*' = ANY(s.event_types)
+		)
+		AND (
+			cardinality(s.ci_types) = 0
+			OR a.entity_type <> 'configuration_item'
+			OR EXISTS (SELECT 1 FROM unnest(s.ci_types) t WHERE lower(t) = lower(a.details->>'type'))
+		)
+	`
+
+	ids := make([]string, len(auditLogIDs))
+	for i, id := range auditLogIDs {
+		ids[i] = id.String()
+	}
+
+	_, err := db.ExecContext(ctx, query, pq.Array(ids))
+	return err
+}
+
+// expectRowsAffected returns notFound when a statement affected no rows
+func expectRowsAffected(result sql.Result, notFound error) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound
+	}
+
+	return nil
+}
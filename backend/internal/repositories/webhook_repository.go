@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrWebhookNotFound is returned when a webhook subscription or delivery does not exist
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// WebhookRepository defines the interface for webhook subscription and delivery operations.
+// Deliveries are created with the audit logs of the events they carry.
+type WebhookRepository interface {
+	// CreateSubscription creates a new webhook subscription in the database
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+
+	// GetSubscription retrieves a webhook subscription by ID
+	GetSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error)
+
+	// ListSubscriptions retrieves all webhook subscriptions, ordered by name
+	ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error)
+
+	// UpdateSubscription updates a webhook subscription in the database
+	UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+
+	// DeleteSubscription deletes a webhook subscription and its deliveries
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+
+	// ListDeliveries retrieves a page of a subscription's deliveries, newest first. An empty
+	// status lists deliveries of every status.
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit, offset int) ([]*models.WebhookDelivery, error)
+
+	// CountDeliveries counts a subscription's deliveries with the status, or all if it is empty
+	CountDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string) (int, error)
+
+	// GetDelivery retrieves a webhook delivery by ID
+	GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error)
+
+	// Redeliver creates a pending delivery of the same event and payload as a delivery, due
+	// at now
+	Redeliver(ctx context.Context, id uuid.UUID, now time.Time) (*models.WebhookDelivery, error)
+
+	// ClaimDueDeliveries leases up to limit pending deliveries of active subscriptions that
+	// are due at now, oldest first, by moving their next attempt to the end of the lease. A
+	// delivery leased by one worker is not claimed by another until the lease ends, so a
+	// delivery whose worker stopped is attempted again.
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error)
+
+	// UpdateDeliveryAttempt records the outcome of an attempt: the status, attempts, next
+	// attempt, last attempt, response status and error of the delivery
+	UpdateDeliveryAttempt(ctx context.Context, delivery *models.WebhookDelivery) error
+}
@@ -6,10 +6,12 @@ import (
 
 	"github.com/cmdb-lite/backend/internal/auth"
 	"github.com/cmdb-lite/backend/internal/config"
+	"github.com/cmdb-lite/backend/internal/events"
 	"github.com/cmdb-lite/backend/internal/handlers"
 	"github.com/cmdb-lite/backend/internal/logging"
 	"github.com/cmdb-lite/backend/internal/metrics"
 	"github.com/cmdb-lite/backend/internal/middleware"
+	"github.com/cmdb-lite/backend/internal/pagination"
 	"github.com/cmdb-lite/backend/internal/repositories"
 	"github.com/cmdb-lite/backend/internal/tracing"
 	"github.com/gorilla/mux"
@@ -21,6 +23,7 @@ func SetupRouter(
 	jwtManager *auth.JWTManager,
 	passwordManager *auth.PasswordManager,
 	cfg *config.Config,
+	hub *events.Hub,
 ) *mux.Router {
 	// Create a new router
 	r := mux.NewRouter()
@@ -43,18 +46,37 @@ func SetupRouter(
 		}
 	}
 
+	// Sign pagination cursors with the configured secret
+	pagination.SetDefaultSecret(cfg.CursorSecret)
+
 	// Create repositories
 	userRepo := repositories.NewUserPostgresRepository(db.DB)
 	refreshTokenRepo := repositories.NewRefreshTokenPostgresRepository(db.DB)
 	ciRepo := repositories.NewCIPostgresRepository(db.DB)
 	relRepo := repositories.NewRelationshipPostgresRepository(db.DB)
 	auditRepo := repositories.NewAuditLogPostgresRepository(db.DB)
+	ciTypeRepo := repositories.NewCITypePostgresRepository(db.DB)
+	relTypeRepo := repositories.NewRelationshipTypePostgresRepository(db.DB)
+	snapshotRepo := repositories.NewSnapshotPostgresRepository(db.DB)
+	webhookRepo := repositories.NewWebhookPostgresRepository(db.DB)
+	eventRepo := repositories.NewEventPostgresRepository(db.DB)
+	uow := repositories.NewPostgresUnitOfWork(db.DB)
 
 	// Create handlers
 	authHandler := handlers.NewAuthHandler(userRepo, refreshTokenRepo, jwtManager, passwordManager)
-	ciHandler := handlers.NewCIHandler(ciRepo, relRepo, auditRepo)
-	relHandler := handlers.NewRelationshipHandler(relRepo, auditRepo)
+	ciHandler := handlers.NewCIHandler(ciRepo, relRepo, ciTypeRepo, uow)
+	ciTypeHandler := handlers.NewCITypeHandler(ciTypeRepo, ciRepo, uow)
+	relHandler := handlers.NewRelationshipHandler(relRepo, uow)
+	relTypeHandler := handlers.NewRelationshipTypeHandler(relTypeRepo, relRepo, ciTypeRepo, uow)
 	auditLogHandler := handlers.NewAuditLogHandler(auditRepo)
+	searchHandler := handlers.NewSearchHandler(ciRepo)
+	impactHandler := handlers.NewImpactHandler(ciRepo, relRepo, cfg.ImpactRelationshipTypes)
+	historyHandler := handlers.NewHistoryHandler(auditRepo)
+	graphHandler := handlers.NewGraphHandler(ciRepo, relRepo, relTypeRepo)
+	trashHandler := handlers.NewTrashHandler(ciRepo, uow)
+	snapshotHandler := handlers.NewSnapshotHandler(snapshotRepo, auditRepo, uow)
+	webhookHandler := handlers.NewWebhookHandler(webhookRepo, ciTypeRepo, uow)
+	eventHandler := handlers.NewEventHandler(eventRepo, hub)
 	metricsHandler := handlers.NewMetricsHandler()
 
 	// Apply common middleware
@@ -106,16 +128,45 @@ func SetupRouter(
 	ciAdminViewerRouter.Use(middleware.RBACMiddleware("admin", "viewer"))
 
 	ciAdminViewerRouter.HandleFunc("", ciHandler.GetAllCIs).Methods("GET")
+	ciAdminViewerRouter.HandleFunc("/search", ciHandler.SearchCIs).Methods("GET")
+	ciAdminViewerRouter.HandleFunc("/by-external-id/{source}/{external_id:.+}", ciHandler.GetCIByExternalID).Methods("GET")
 	ciAdminViewerRouter.HandleFunc("/{id}", ciHandler.GetCI).Methods("GET")
 	ciAdminViewerRouter.HandleFunc("/{id}/graph", ciHandler.GetCIGraph).Methods("GET")
+	ciAdminViewerRouter.HandleFunc("/{id}/external-ids", ciHandler.GetCIExternalIDs).Methods("GET")
+	ciAdminViewerRouter.HandleFunc("/{id}/impact", impactHandler.GetCIImpact).Methods("GET")
+	ciAdminViewerRouter.HandleFunc("/{id}/history", historyHandler.GetCIHistory).Methods("GET")
+	ciAdminViewerRouter.HandleFunc("/{id}/versions/{n}", historyHandler.GetCIVersion).Methods("GET")
 
 	// CI endpoints that require admin role
 	ciAdminRouter := ciRouter.NewRoute().Subrouter()
 	ciAdminRouter.Use(middleware.RBACMiddleware("admin"))
 
 	ciAdminRouter.HandleFunc("", ciHandler.CreateCI).Methods("POST")
+	ciAdminRouter.HandleFunc("/bulk", ciHandler.BulkCIs).Methods("POST")
+	ciAdminRouter.HandleFunc("/by-external-id/{source}/{external_id:.+}", ciHandler.PutCIByExternalID).Methods("PUT")
 	ciAdminRouter.HandleFunc("/{id}", ciHandler.UpdateCI).Methods("PUT")
+	ciAdminRouter.HandleFunc("/{id}", ciHandler.PatchCI).Methods("PATCH")
 	ciAdminRouter.HandleFunc("/{id}", ciHandler.DeleteCI).Methods("DELETE")
+	ciAdminRouter.HandleFunc("/{id}/restore", trashHandler.RestoreCI).Methods("POST")
+
+	// CI type endpoints (authentication required)
+	ciTypeRouter := apiV1.PathPrefix("/ci-types").Subrouter()
+	ciTypeRouter.Use(middleware.AuthMiddleware(jwtManager))
+
+	// CI type endpoints that require admin or viewer role
+	ciTypeAdminViewerRouter := ciTypeRouter.NewRoute().Subrouter()
+	ciTypeAdminViewerRouter.Use(middleware.RBACMiddleware("admin", "viewer"))
+
+	ciTypeAdminViewerRouter.HandleFunc("", ciTypeHandler.GetAllCITypes).Methods("GET")
+	ciTypeAdminViewerRouter.HandleFunc("/{id}", ciTypeHandler.GetCIType).Methods("GET")
+
+	// CI type endpoints that require admin role
+	ciTypeAdminRouter := ciTypeRouter.NewRoute().Subrouter()
+	ciTypeAdminRouter.Use(middleware.RBACMiddleware("admin"))
+
+	ciTypeAdminRouter.HandleFunc("", ciTypeHandler.CreateCIType).Methods("POST")
+	ciTypeAdminRouter.HandleFunc("/{id}", ciTypeHandler.UpdateCIType).Methods("PUT")
+	ciTypeAdminRouter.HandleFunc("/{id}", ciTypeHandler.DeleteCIType).Methods("DELETE")
 
 	// Relationship endpoints (authentication required)
 	relRouter := apiV1.PathPrefix("/relationships").Subrouter()
@@ -136,6 +187,25 @@ func SetupRouter(
 	relAdminRouter.HandleFunc("/{id}", relHandler.UpdateRelationship).Methods("PUT")
 	relAdminRouter.HandleFunc("/{id}", relHandler.DeleteRelationship).Methods("DELETE")
 
+	// Relationship type endpoints (authentication required)
+	relTypeRouter := apiV1.PathPrefix("/relationship-types").Subrouter()
+	relTypeRouter.Use(middleware.AuthMiddleware(jwtManager))
+
+	// Relationship type endpoints that require admin or viewer role
+	relTypeAdminViewerRouter := relTypeRouter.NewRoute().Subrouter()
+	relTypeAdminViewerRouter.Use(middleware.RBACMiddleware("admin", "viewer"))
+
+	relTypeAdminViewerRouter.HandleFunc("", relTypeHandler.GetAllRelationshipTypes).Methods("GET")
+	relTypeAdminViewerRouter.HandleFunc("/{id}", relTypeHandler.GetRelationshipType).Methods("GET")
+
+	// Relationship type endpoints that require admin role
+	relTypeAdminRouter := relTypeRouter.NewRoute().Subrouter()
+	relTypeAdminRouter.Use(middleware.RBACMiddleware("admin"))
+
+	relTypeAdminRouter.HandleFunc("", relTypeHandler.CreateRelationshipType).Methods("POST")
+	relTypeAdminRouter.HandleFunc("/{id}", relTypeHandler.UpdateRelationshipType).Methods("PUT")
+	relTypeAdminRouter.HandleFunc("/{id}", relTypeHandler.DeleteRelationshipType).Methods("DELETE")
+
 	// Audit log endpoints (authentication required)
 	auditRouter := apiV1.PathPrefix("/audit-logs").Subrouter()
 	auditRouter.Use(middleware.AuthMiddleware(jwtManager))
@@ -156,6 +226,63 @@ func SetupRouter(
 
 	auditAdminRouter.HandleFunc("/{id}", auditLogHandler.DeleteAuditLog).Methods("DELETE")
 
+	// Graph endpoints (authentication required, admin or viewer role)
+	graphRouter := apiV1.PathPrefix("/graph").Subrouter()
+	graphRouter.Use(middleware.AuthMiddleware(jwtManager))
+	graphRouter.Use(middleware.RBACMiddleware("admin", "viewer"))
+
+	graphRouter.HandleFunc("/paths", graphHandler.GetPaths).Methods("GET")
+	graphRouter.HandleFunc("/cycles", graphHandler.GetCycles).Methods("GET")
+
+	// Search endpoints (authentication required, admin or viewer role)
+	searchRouter := apiV1.PathPrefix("/search").Subrouter()
+	searchRouter.Use(middleware.AuthMiddleware(jwtManager))
+	searchRouter.Use(middleware.RBACMiddleware("admin", "viewer"))
+
+	searchRouter.HandleFunc("", searchHandler.Search).Methods("GET")
+
+	// Trash endpoints (authentication required, admin or viewer role)
+	trashRouter := apiV1.PathPrefix("/trash").Subrouter()
+	trashRouter.Use(middleware.AuthMiddleware(jwtManager))
+	trashRouter.Use(middleware.RBACMiddleware("admin", "viewer"))
+
+	trashRouter.HandleFunc("", trashHandler.GetTrash).Methods("GET")
+
+	// Import endpoints (authentication required, admin role)
+	importRouter := apiV1.PathPrefix("/import").Subrouter()
+	importRouter.Use(middleware.AuthMiddleware(jwtManager))
+	importRouter.Use(middleware.RBACMiddleware("admin"))
+
+	importRouter.HandleFunc("/cis", ciHandler.ImportCIs).Methods("POST")
+
+	// Admin endpoints (authentication required, admin role)
+	adminRouter := apiV1.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(middleware.AuthMiddleware(jwtManager))
+	adminRouter.Use(middleware.RBACMiddleware("admin"))
+
+	adminRouter.HandleFunc("/export", snapshotHandler.ExportSnapshot).Methods("GET")
+	adminRouter.HandleFunc("/import", snapshotHandler.ImportSnapshot).Methods("POST")
+
+	// Webhook endpoints (authentication required, admin role)
+	webhookRouter := apiV1.PathPrefix("/webhooks").Subrouter()
+	webhookRouter.Use(middleware.AuthMiddleware(jwtManager))
+	webhookRouter.Use(middleware.RBACMiddleware("admin"))
+
+	webhookRouter.HandleFunc("", webhookHandler.CreateWebhook).Methods("POST")
+	webhookRouter.HandleFunc("", webhookHandler.GetAllWebhooks).Methods("GET")
+	webhookRouter.HandleFunc("/{id}", webhookHandler.GetWebhook).Methods("GET")
+	webhookRouter.HandleFunc("/{id}", webhookHandler.UpdateWebhook).Methods("PUT")
+	webhookRouter.HandleFunc("/{id}", webhookHandler.DeleteWebhook).Methods("DELETE")
+	webhookRouter.HandleFunc("/{id}/deliveries", webhookHandler.GetWebhookDeliveries).Methods("GET")
+	webhookRouter.HandleFunc("/{id}/deliveries/{delivery_id}/redeliver", webhookHandler.RedeliverWebhook).Methods("POST")
+
+	// Event endpoints (authentication required, admin or viewer role)
+	eventRouter := apiV1.PathPrefix("/events").Subrouter()
+	eventRouter.Use(middleware.AuthMiddleware(jwtManager))
+	eventRouter.Use(middleware.RBACMiddleware("admin", "viewer"))
+
+	eventRouter.HandleFunc("/stream", eventHandler.StreamEvents).Methods("GET")
+
 	// User endpoints (authentication required)
 	userRouter := apiV1.PathPrefix("/users").Subrouter()
 	userRouter.Use(middleware.AuthMiddleware(jwtManager))
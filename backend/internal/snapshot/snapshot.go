@@ -0,0 +1,312 @@
+// Package snapshot reads and writes snapshot archives of the CMDB: gzipped tar archives holding
+// a manifest followed by one NDJSON file per table, with a JSON object per row.
+package snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FormatVersion is the version of the archive layout written by this package. Readers reject
+// archives of other versions.
+const FormatVersion = 1
+
+// ManifestName is the name of the manifest, the first file of an archive
+const ManifestName = "manifest.json"
+
+// MediaType is the media type of snapshot archives
+const MediaType = "application/gzip"
+
+// maxRecordSize is the largest row a file of an archive may hold, in bytes
+const maxRecordSize = 64 << 20
+
+// ErrInvalidArchive is returned when an archive cannot be read or fails its integrity checks.
+// Errors wrapping it describe the problem.
+var ErrInvalidArchive = errors.New("invalid snapshot archive")
+
+// Manifest describes an archive: the database schema its rows follow and the files it holds,
+// in the order they follow the manifest
+type Manifest struct {
+	ID             uuid.UUID `json:"id"`
+	FormatVersion  int       `json:"format_version"`
+	SchemaVersion  int64     `json:"schema_version"`
+	CreatedAt      time.Time `json:"created_at"`
+	CreatedBy      string    `json:"created_by"`
+	PasswordHashes bool      `json:"password_hashes"`
+	Files          []File    `json:"files"`
+}
+
+// File describes a file of an archive: the table its rows come from, the columns each row
+// has, the number of rows and the SHA-256 checksum of the file's content
+type File struct {
+	Name    string   `json:"name"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	Records int      `json:"records"`
+	SHA256  string   `json:"sha256"`
+}
+
+// FileName returns the name of the file holding a table's rows
+func FileName(table string) string {
+	return table + ".ndjson"
+}
+
+// Writer writes an archive. Files are written to temporary files first, since the manifest,
+// which comes first, needs their checksums; Close removes them.
+type Writer struct {
+	files []*FileWriter
+}
+
+// NewWriter creates a Writer
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// Create adds the file for a table's rows, which have the columns
+func (w *Writer) Create(table string, columns []string) (*FileWriter, error) {
+	temp, err := os.CreateTemp("", "cmdb-snapshot-*.ndjson")
+	if err != nil {
+		return nil, err
+	}
+
+	file := &FileWriter{
+		file: File{Name: FileName(table), Table: table, Columns: columns},
+		temp: temp,
+		hash: sha256.New(),
+	}
+	file.buffer = bufio.NewWriter(io.MultiWriter(temp, file.hash))
+	w.files = append(w.files, file)
+	return file, nil
+}
+
+// WriteTo writes the archive to out: the manifest, completed with the files, and then the
+// files in the order they were created
+func (w *Writer) WriteTo(out io.Writer, manifest Manifest) error {
+	manifest.FormatVersion = FormatVersion
+	manifest.Files = make([]File, len(w.files))
+	for i, file := range w.files {
+		if err := file.buffer.Flush(); err != nil {
+			return err
+		}
+		file.file.SHA256 = hex.EncodeToString(file.hash.Sum(nil))
+		manifest.Files[i] = file.file
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	if err := writeTarFile(tw, ManifestName, int64(len(data)), manifest.CreatedAt, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	for _, file := range w.files {
+		size, err := file.temp.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		if _, err := file.temp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := writeTarFile(tw, file.file.Name, size, manifest.CreatedAt, file.temp); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Close removes the temporary files
+func (w *Writer) Close() error {
+	var errs []error
+	for _, file := range w.files {
+		errs = append(errs, file.temp.Close(), os.Remove(file.temp.Name()))
+	}
+	return errors.Join(errs...)
+}
+
+// writeTarFile writes a file of size bytes read from r to an archive
+func writeTarFile(tw *tar.Writer, name string, size int64, modTime time.Time, r io.Reader) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0o644,
+		ModTime:  modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.CopyN(tw, r, size)
+	return err
+}
+
+// FileWriter writes the rows of a file
+type FileWriter struct {
+	file   File
+	temp   *os.File
+	hash   hash.Hash
+	buffer *bufio.Writer
+}
+
+// Write writes a row, a JSON object, as a line of the file
+func (f *FileWriter) Write(record json.RawMessage) error {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, record); err != nil {
+		return err
+	}
+	compact.WriteByte('\n')
+
+	if _, err := f.buffer.Write(compact.Bytes()); err != nil {
+		return err
+	}
+	f.file.Records++
+	return nil
+}
+
+// Reader reads an archive written by Writer, checking each file against the manifest as it
+// is read
+type Reader struct {
+	gz       *gzip.Reader
+	tar      *tar.Reader
+	manifest Manifest
+	next     int
+}
+
+// NewReader reads the manifest of an archive. It returns an error wrapping ErrInvalidArchive
+// if r is not an archive of FormatVersion.
+func NewReader(r io.Reader) (*Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, invalidArchive("the archive is not gzipped: %v", err)
+	}
+	reader := &Reader{gz: gz, tar: tar.NewReader(gz)}
+
+	header, err := reader.nextHeader()
+	if err != nil {
+		return nil, err
+	}
+	if header.Name != ManifestName {
+		return nil, invalidArchive("the archive does not start with %s", ManifestName)
+	}
+	if err := json.NewDecoder(reader.tar).Decode(&reader.manifest); err != nil {
+		return nil, invalidArchive("the manifest is not valid: %v", err)
+	}
+	if reader.manifest.FormatVersion != FormatVersion {
+		return nil, invalidArchive("format version %d is not supported", reader.manifest.FormatVersion)
+	}
+
+	return reader, nil
+}
+
+// Manifest returns the manifest of the archive
+func (r *Reader) Manifest() Manifest {
+	return r.manifest
+}
+
+// Next returns the next file of the archive, which must be the next one in the manifest. It
+// returns io.EOF after the last file. A file is only checked once its last row is read, so
+// read each file to the end.
+func (r *Reader) Next() (*FileReader, error) {
+	header, err := r.nextHeader()
+	if errors.Is(err, io.EOF) {
+		if r.next < len(r.manifest.Files) {
+			return nil, invalidArchive("%s is missing", r.manifest.Files[r.next].Name)
+		}
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if r.next >= len(r.manifest.Files) || header.Name != r.manifest.Files[r.next].Name {
+		return nil, invalidArchive("%s is not the next file in the manifest", header.Name)
+	}
+	file := r.manifest.Files[r.next]
+	r.next++
+
+	hash := sha256.New()
+	scanner := bufio.NewScanner(io.TeeReader(r.tar, hash))
+	scanner.Buffer(make([]byte, 0, 64<<10), maxRecordSize)
+	return &FileReader{File: file, scanner: scanner, hash: hash}, nil
+}
+
+// Close closes the archive's gzip stream
+func (r *Reader) Close() error {
+	return r.gz.Close()
+}
+
+// nextHeader returns the header of the next regular file, skipping directories that archive
+// tools may have added
+func (r *Reader) nextHeader() (*tar.Header, error) {
+	for {
+		header, err := r.tar.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, invalidArchive("the archive is not a tar archive: %v", err)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		header.Name = path.Clean(header.Name)
+		return header, nil
+	}
+}
+
+// FileReader reads the rows of a file of an archive
+type FileReader struct {
+	File    File
+	scanner *bufio.Scanner
+	hash    hash.Hash
+	records int
+}
+
+// Next returns the next row of the file. After the last row it checks the number of rows and
+// the checksum against the manifest and returns io.EOF if they match.
+func (f *FileReader) Next() (json.RawMessage, error) {
+	if !f.scanner.Scan() {
+		if err := f.scanner.Err(); err != nil {
+			return nil, invalidArchive("%s cannot be read: %v", f.File.Name, err)
+		}
+		if f.records != f.File.Records {
+			return nil, invalidArchive("%s has %d rows, the manifest says %d", f.File.Name, f.records, f.File.Records)
+		}
+		if checksum := hex.EncodeToString(f.hash.Sum(nil)); checksum != f.File.SHA256 {
+			return nil, invalidArchive("the checksum of %s does not match the manifest", f.File.Name)
+		}
+		return nil, io.EOF
+	}
+
+	f.records++
+	line := f.scanner.Bytes()
+	if !json.Valid(line) || bytes.IndexByte(bytes.TrimSpace(line), '{') != 0 {
+		return nil, invalidArchive("row %d of %s is not a JSON object", f.records, f.File.Name)
+	}
+	return append(json.RawMessage{}, line...), nil
+}
+
+// invalidArchive returns an error wrapping ErrInvalidArchive
+func invalidArchive(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidArchive, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,226 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeArchive writes an archive with a file per table holding the rows
+func writeArchive(t *testing.T, tables []string, rows map[string][]string) []byte {
+	t.Helper()
+
+	w := NewWriter()
+	defer w.Close()
+
+	for _, table := range tables {
+		file, err := w.Create(table, []string{"id", "name"})
+		require.NoError(t, err)
+		for _, row := range rows[table] {
+			require.NoError(t, file.Write(json.RawMessage(row)))
+		}
+	}
+
+	var archive bytes.Buffer
+	require.NoError(t, w.WriteTo(&archive, Manifest{
+		SchemaVersion: 20261016160000,
+		CreatedAt:     time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC),
+		CreatedBy:     "admin",
+	}))
+	return archive.Bytes()
+}
+
+// readArchive reads every row of an archive, stopping at the first error
+func readArchive(archive []byte) (Manifest, map[string][]string, error) {
+	reader, err := NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return Manifest{}, nil, err
+	}
+	defer reader.Close()
+
+	rows := make(map[string][]string)
+	for {
+		file, err := reader.Next()
+		if err == io.EOF {
+			return reader.Manifest(), rows, nil
+		}
+		if err != nil {
+			return Manifest{}, nil, err
+		}
+
+		for {
+			row, err := file.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return Manifest{}, nil, err
+			}
+			rows[file.File.Table] = append(rows[file.File.Table], string(row))
+		}
+	}
+}
+
+// rewriteArchive rewrites an archive, passing each file's name and content through edit.
+// Files for which edit returns false are left out.
+func rewriteArchive(t *testing.T, archive []byte, edit func(name string, data []byte) ([]byte, bool)) []byte {
+	t.Helper()
+
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+
+	var out bytes.Buffer
+	gw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gw)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+
+		data, keep := edit(header.Name, data)
+		if !keep {
+			continue
+		}
+		header.Size = int64(len(data))
+		require.NoError(t, tw.WriteHeader(header))
+		_, err = tw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return out.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	archive := writeArchive(t, []string{"users", "audit_logs"}, map[string][]string{
+		"users": {`{"id": 1, "name": "admin"}`, `{"id":2,"name":"viewer"}`},
+	})
+
+	manifest, rows, err := readArchive(archive)
+	require.NoError(t, err)
+
+	assert.Equal(t, FormatVersion, manifest.FormatVersion)
+	assert.Equal(t, int64(20261016160000), manifest.SchemaVersion)
+	assert.Equal(t, "admin", manifest.CreatedBy)
+	require.Len(t, manifest.Files, 2)
+	assert.Equal(t, "users.ndjson", manifest.Files[0].Name)
+	assert.Equal(t, "users", manifest.Files[0].Table)
+	assert.Equal(t, []string{"id", "name"}, manifest.Files[0].Columns)
+	assert.Equal(t, 2, manifest.Files[0].Records)
+	assert.Len(t, manifest.Files[0].SHA256, 64)
+	assert.Equal(t, "audit_logs.ndjson", manifest.Files[1].Name)
+	assert.Equal(t, 0, manifest.Files[1].Records)
+
+	// Rows are written compactly, one per line
+	assert.Equal(t, map[string][]string{
+		"users": {`{"id":1,"name":"admin"}`, `{"id":2,"name":"viewer"}`},
+	}, rows)
+}
+
+func TestWriter_InvalidRow(t *testing.T) {
+	w := NewWriter()
+	defer w.Close()
+
+	file, err := w.Create("users", []string{"id"})
+	require.NoError(t, err)
+	assert.Error(t, file.Write(json.RawMessage(`{"id":`)))
+}
+
+func TestReader_InvalidArchive(t *testing.T) {
+	archive := writeArchive(t, []string{"users", "audit_logs"}, map[string][]string{
+		"users": {`{"id":1,"name":"admin"}`},
+	})
+
+	tests := []struct {
+		name     string
+		archive  []byte
+		expected string
+	}{
+		{
+			name:     "Not gzipped",
+			archive:  []byte("id,name\n"),
+			expected: "the archive is not gzipped",
+		},
+		{
+			name: "No manifest",
+			archive: rewriteArchive(t, archive, func(name string, data []byte) ([]byte, bool) {
+				return data, name != ManifestName
+			}),
+			expected: "the archive does not start with manifest.json",
+		},
+		{
+			name: "Unsupported format version",
+			archive: rewriteArchive(t, archive, func(name string, data []byte) ([]byte, bool) {
+				if name == ManifestName {
+					data = bytes.Replace(data, []byte(`"format_version": 1`), []byte(`"format_version": 2`), 1)
+				}
+				return data, true
+			}),
+			expected: "format version 2 is not supported",
+		},
+		{
+			name: "Changed row",
+			archive: rewriteArchive(t, archive, func(name string, data []byte) ([]byte, bool) {
+				return bytes.Replace(data, []byte("admin"), []byte("root"), 1), true
+			}),
+			expected: "the checksum of users.ndjson does not match the manifest",
+		},
+		{
+			name: "Added row",
+			archive: rewriteArchive(t, archive, func(name string, data []byte) ([]byte, bool) {
+				if name == "users.ndjson" {
+					data = append(data, `{"id":2,"name":"root"}`+"\n"...)
+				}
+				return data, true
+			}),
+			expected: "users.ndjson has 2 rows, the manifest says 1",
+		},
+		{
+			name: "Row that is not an object",
+			archive: rewriteArchive(t, archive, func(name string, data []byte) ([]byte, bool) {
+				if name == "users.ndjson" {
+					data = []byte("[1]\n")
+				}
+				return data, true
+			}),
+			expected: "row 1 of users.ndjson is not a JSON object",
+		},
+		{
+			name: "Missing file",
+			archive: rewriteArchive(t, archive, func(name string, data []byte) ([]byte, bool) {
+				return data, name != "audit_logs.ndjson"
+			}),
+			expected: "audit_logs.ndjson is missing",
+		},
+		{
+			name: "Unexpected file",
+			archive: rewriteArchive(t, archive, func(name string, data []byte) ([]byte, bool) {
+				if name == "users.ndjson" {
+					return data, false
+				}
+				return data, true
+			}),
+			expected: "audit_logs.ndjson is not the next file in the manifest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := readArchive(tt.archive)
+			require.ErrorIs(t, err, ErrInvalidArchive)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
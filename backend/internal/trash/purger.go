@@ -0,0 +1,98 @@
+// Package trash permanently deletes CIs that have stayed in the trash longer than the
+// retention period.
+package trash
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/history"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// purgedBy is recorded as the author of the audit logs of purged CIs
+const purgedBy = "system"
+
+// Purger periodically purges the CIs deleted more than the retention period ago
+type Purger struct {
+	uow       repositories.UnitOfWork
+	retention time.Duration
+	interval  time.Duration
+}
+
+// NewPurger creates a new Purger that runs every interval
+func NewPurger(uow repositories.UnitOfWork, retention time.Duration, interval time.Duration) *Purger {
+	return &Purger{
+		uow:       uow,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Run purges the trash once and then every interval until the context is cancelled. A
+// retention period or interval that is not positive disables purging.
+func (p *Purger) Run(ctx context.Context) {
+	if p.retention <= 0 || p.interval <= 0 {
+		log.Println("Trash purging is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := p.Purge(ctx, time.Now()); err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d CIs from the trash", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge permanently deletes the CIs deleted more than the retention period before now and
+// records an audit log for each, all in one transaction. It returns the number of CIs purged.
+func (p *Purger) Purge(ctx context.Context, now time.Time) (int, error) {
+	var purged int
+	err := p.uow.WithTx(ctx, func(tx repositories.Repos) error {
+		cis, err := tx.CIs.PurgeDeleted(ctx, now.Add(-p.retention))
+		if err != nil {
+			return err
+		}
+
+		for _, ci := range cis {
+			err := tx.AuditLogs.Create(ctx, &models.AuditLog{
+				ID:         uuid.New(),
+				EntityType: "configuration_item",
+				EntityID:   ci.ID,
+				Action:     history.ActionPurge,
+				ChangedBy:  purgedBy,
+				ChangedAt:  now,
+				Details: models.JSONBMap{
+					"name":    ci.Name,
+					"type":    ci.Type,
+					"changes": []history.Change{},
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		purged = len(cis)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
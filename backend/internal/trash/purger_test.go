@@ -0,0 +1,123 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/history"
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCIRepository records the cutoff passed to PurgeDeleted and returns the configured CIs
+type fakeCIRepository struct {
+	repositories.CIRepository
+	purged []*models.CI
+	err    error
+	before time.Time
+}
+
+func (r *fakeCIRepository) PurgeDeleted(ctx context.Context, before time.Time) ([]*models.CI, error) {
+	r.before = before
+	return r.purged, r.err
+}
+
+// fakeAuditLogRepository collects the audit logs created
+type fakeAuditLogRepository struct {
+	repositories.AuditLogRepository
+	logs []*models.AuditLog
+	err  error
+}
+
+func (r *fakeAuditLogRepository) Create(ctx context.Context, auditLog *models.AuditLog) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.logs = append(r.logs, auditLog)
+	return nil
+}
+
+// fakeUnitOfWork runs fn with the fake repositories and keeps the audit logs only if fn
+// succeeds, like a rolled back transaction
+type fakeUnitOfWork struct {
+	ciRepo    *fakeCIRepository
+	auditRepo *fakeAuditLogRepository
+}
+
+func (u *fakeUnitOfWork) WithTx(ctx context.Context, fn func(tx repositories.Repos) error) error {
+	committed := u.auditRepo.logs
+	err := fn(repositories.Repos{CIs: u.ciRepo, AuditLogs: u.auditRepo})
+	if err != nil {
+		u.auditRepo.logs = committed
+	}
+	return err
+}
+
+func TestPurge(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	retention := 30 * 24 * time.Hour
+
+	tests := []struct {
+		name     string
+		purged   []*models.CI
+		err      error
+		auditErr error
+		expected int
+	}{
+		{
+			name: "Purges expired CIs",
+			purged: []*models.CI{
+				{ID: uuid.New(), Name: "web-01", Type: "server"},
+				{ID: uuid.New(), Name: "db-01", Type: "database"},
+			},
+			expected: 2,
+		},
+		{
+			name:     "Nothing to purge",
+			expected: 0,
+		},
+		{
+			name: "Repository error",
+			err:  errors.New("connection refused"),
+		},
+		{
+			name: "Audit log error rolls back the purge",
+			purged: []*models.CI{
+				{ID: uuid.New(), Name: "web-01", Type: "server"},
+			},
+			auditErr: errors.New("connection refused"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ciRepo := &fakeCIRepository{purged: tt.purged, err: tt.err}
+			auditRepo := &fakeAuditLogRepository{err: tt.auditErr}
+			purger := NewPurger(&fakeUnitOfWork{ciRepo: ciRepo, auditRepo: auditRepo}, retention, time.Hour)
+
+			purged, err := purger.Purge(context.Background(), now)
+			assert.Equal(t, now.Add(-retention), ciRepo.before)
+			if tt.err != nil || tt.auditErr != nil {
+				assert.Error(t, err)
+				assert.Zero(t, purged)
+				assert.Empty(t, auditRepo.logs)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, purged)
+			require.Len(t, auditRepo.logs, len(tt.purged))
+			for i, auditLog := range auditRepo.logs {
+				assert.Equal(t, tt.purged[i].ID, auditLog.EntityID)
+				assert.Equal(t, history.ActionPurge, auditLog.Action)
+				assert.Equal(t, purgedBy, auditLog.ChangedBy)
+				assert.Equal(t, tt.purged[i].Name, auditLog.Details["name"])
+			}
+		})
+	}
+}
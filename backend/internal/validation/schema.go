@@ -0,0 +1,150 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// schemaURL is the resource name used when compiling inline CI type schemas
+const schemaURL = "ci-type-schema.json"
+
+// CompileSchema compiles a JSON Schema document so it can be used to validate CI attributes
+func CompileSchema(schema map[string]interface{}) (*jsonschema.Schema, error) {
+	if schema == nil {
+		schema = map[string]interface{}{}
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema: %w", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaURL, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	compiled, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	return compiled, nil
+}
+
+// ValidateSchema checks that a CI type schema is a valid JSON Schema document
+func (v *Validator) ValidateSchema(schema map[string]interface{}) *models.ErrorResponse {
+	if _, err := CompileSchema(schema); err != nil {
+		return models.NewErrorResponse(
+			models.ErrorTypeValidation,
+			"Validation failed",
+			map[string]interface{}{"schema": []string{err.Error()}},
+		)
+	}
+	return nil
+}
+
+// ValidateAttributes validates CI attributes against a CI type's JSON Schema and returns
+// a standardized error response keyed by attribute path if validation fails
+func (v *Validator) ValidateAttributes(schema map[string]interface{}, attributes map[string]interface{}) *models.ErrorResponse {
+	compiled, err := CompileSchema(schema)
+	if err != nil {
+		return models.NewErrorResponse(
+			models.ErrorTypeValidation,
+			"Validation failed",
+			map[string]interface{}{"type": []string{"type has an invalid attribute schema"}},
+		)
+	}
+
+	// Normalize the attributes into plain JSON values so the schema validator sees
+	// the same shapes a client sent
+	instance, err := toJSONValue(attributes)
+	if err != nil {
+		return models.NewErrorResponse(
+			models.ErrorTypeValidation,
+			"Validation failed",
+			map[string]interface{}{"attributes": []string{err.Error()}},
+		)
+	}
+
+	err = compiled.Validate(instance)
+	if err == nil {
+		return nil
+	}
+
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return models.NewErrorResponse(
+			models.ErrorTypeValidation,
+			"Validation failed",
+			map[string]interface{}{"attributes": []string{err.Error()}},
+		)
+	}
+
+	errors := make(map[string]interface{})
+	for _, leaf := range leafErrors(validationErr) {
+		path := attributePath(leaf.InstanceLocation)
+		fieldErrors, _ := errors[path].([]string)
+		errors[path] = append(fieldErrors, leaf.Message)
+	}
+
+	return models.NewErrorResponse(
+		models.ErrorTypeValidation,
+		"Validation failed",
+		errors,
+	)
+}
+
+// toJSONValue round-trips attributes through encoding/json so that custom map types
+// and Go numeric types become the generic values expected by the schema validator
+func toJSONValue(attributes map[string]interface{}) (interface{}, error) {
+	if attributes == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, fmt.Errorf("attributes must be valid JSON: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("attributes must be valid JSON: %w", err)
+	}
+	return value, nil
+}
+
+// leafErrors flattens a validation error tree into the errors that carry the actual failure messages
+func leafErrors(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		leaves = append(leaves, leafErrors(cause)...)
+	}
+	return leaves
+}
+
+// attributePath converts a JSON pointer such as /disks/0/size into attributes.disks.0.size
+func attributePath(pointer string) string {
+	if pointer == "" || pointer == "/" {
+		return "attributes"
+	}
+
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		tokens[i] = strings.ReplaceAll(token, "~0", "~")
+	}
+	return "attributes." + strings.Join(tokens, ".")
+}
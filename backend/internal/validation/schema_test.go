@@ -0,0 +1,126 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidator_ValidateAttributes(t *testing.T) {
+	// Setup test data
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"cpu"},
+		"properties": map[string]interface{}{
+			"cpu": map[string]interface{}{"type": "integer", "minimum": 1},
+			"os":  map[string]interface{}{"type": "string"},
+			"disks": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"size": map[string]interface{}{"type": "number"}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		attributes     models.JSONBMap
+		expectedError  bool
+		expectedFields []string
+	}{
+		{
+			name:          "Conforming attributes",
+			attributes:    models.JSONBMap{"cpu": 4, "os": "linux"},
+			expectedError: false,
+		},
+		{
+			name:           "Wrong attribute type",
+			attributes:     models.JSONBMap{"cpu": "four"},
+			expectedError:  true,
+			expectedFields: []string{"attributes.cpu"},
+		},
+		{
+			name:           "Missing required attribute",
+			attributes:     models.JSONBMap{"os": "linux"},
+			expectedError:  true,
+			expectedFields: []string{"attributes"},
+		},
+		{
+			name:           "Nested attribute path",
+			attributes:     models.JSONBMap{"cpu": 2, "disks": []interface{}{map[string]interface{}{"size": "big"}}},
+			expectedError:  true,
+			expectedFields: []string{"attributes.disks.0.size"},
+		},
+		{
+			name:           "Nil attributes are validated as an empty object",
+			attributes:     nil,
+			expectedError:  true,
+			expectedFields: []string{"attributes"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewValidator()
+
+			errResponse := validator.ValidateAttributes(schema, tt.attributes)
+
+			if !tt.expectedError {
+				assert.Nil(t, errResponse)
+				return
+			}
+
+			require.NotNil(t, errResponse)
+			assert.Equal(t, string(models.ErrorTypeValidation), errResponse.Code)
+
+			details, ok := errResponse.Details.(map[string]interface{})
+			require.True(t, ok)
+			for _, field := range tt.expectedFields {
+				messages, ok := details[field].([]string)
+				require.True(t, ok, "expected errors for %s, got %v", field, details)
+				assert.NotEmpty(t, messages)
+			}
+		})
+	}
+}
+
+func TestValidator_ValidateSchema(t *testing.T) {
+	tests := []struct {
+		name          string
+		schema        map[string]interface{}
+		expectedError bool
+	}{
+		{
+			name:          "Valid schema",
+			schema:        map[string]interface{}{"type": "object"},
+			expectedError: false,
+		},
+		{
+			name:          "Nil schema",
+			schema:        nil,
+			expectedError: false,
+		},
+		{
+			name:          "Invalid schema",
+			schema:        map[string]interface{}{"type": 42},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewValidator()
+
+			errResponse := validator.ValidateSchema(tt.schema)
+
+			if tt.expectedError {
+				require.NotNil(t, errResponse)
+				details := errResponse.Details.(map[string]interface{})
+				assert.Contains(t, details, "schema")
+			} else {
+				assert.Nil(t, errResponse)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// batchSize is the number of deliveries claimed and attempted at once
+const batchSize = 50
+
+// leaseMargin is added to the request timeout to lease claimed deliveries, so that a delivery
+// is only claimed again once its attempt has surely ended
+const leaseMargin = time.Minute
+
+// maxResponseSize is the part of a response body read before the connection is reused
+const maxResponseSize = 64 << 10
+
+// userAgent identifies the CMDB to the receivers of deliveries
+const userAgent = "cmdb-lite-webhook/1.0"
+
+// Dispatcher periodically attempts the due webhook deliveries. Deliveries are leased while
+// they are attempted, so several dispatchers, e.g. one per replica, can run side by side.
+type Dispatcher struct {
+	repo        repositories.WebhookRepository
+	client      *http.Client
+	maxAttempts int
+	interval    time.Duration
+}
+
+// NewDispatcher creates a new Dispatcher that runs every interval, gives every request the
+// timeout and marks a delivery failed after maxAttempts attempts
+func NewDispatcher(repo repositories.WebhookRepository, timeout time.Duration, maxAttempts int, interval time.Duration) *Dispatcher {
+	return &Dispatcher{
+		repo: repo,
+		client: &http.Client{
+			Timeout: timeout,
+			// A redirect is a response like any other; following it could post the event to
+			// a URL nobody subscribed
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxAttempts: maxAttempts,
+		interval:    interval,
+	}
+}
+
+// Run attempts the due deliveries once and then every interval until the context is
+// cancelled. An interval or maximum number of attempts that is not positive disables
+// delivery.
+func (d *Dispatcher) Run(ctx context.Context) {
+	if d.interval <= 0 || d.maxAttempts <= 0 {
+		log.Println("Webhook delivery is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while full batches are due, so that a backlog drains without waiting
+		// an interval per batch
+		for {
+			attempted, err := d.DeliverDue(ctx, time.Now())
+			if err != nil {
+				log.Printf("Failed to deliver webhooks: %v", err)
+			}
+			if err != nil || attempted < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue claims a batch of the deliveries due at now and attempts them concurrently. A
+// delivery that gets a 2xx response succeeds; any other outcome is retried after a backoff
+// until the last attempt, when the delivery fails. It returns the number of deliveries
+// attempted.
+func (d *Dispatcher) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	deliveries, err := d.repo.ClaimDueDeliveries(ctx, now, d.client.Timeout+leaseMargin, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	subscriptions := make(map[uuid.UUID]*models.WebhookSubscription)
+	for _, delivery := range deliveries {
+		if _, ok := subscriptions[delivery.SubscriptionID]; ok {
+			continue
+		}
+		subscription, err := d.repo.GetSubscription(ctx, delivery.SubscriptionID)
+		if err != nil && !errors.Is(err, repositories.ErrWebhookNotFound) {
+			return 0, err
+		}
+		// A deleted subscription's deliveries are deleted with it
+		subscriptions[delivery.SubscriptionID] = subscription
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		errs      []error
+		attempted int
+	)
+	for _, delivery := range deliveries {
+		subscription := subscriptions[delivery.SubscriptionID]
+		if subscription == nil {
+			continue
+		}
+
+		attempted++
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			if err := d.deliver(ctx, subscription, delivery, now); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("delivery %s: %w", delivery.ID, err))
+				mu.Unlock()
+			}
+		}(delivery)
+	}
+	wg.Wait()
+
+	return attempted, errors.Join(errs...)
+}
+
+// deliver attempts a delivery and records the outcome. When the context is cancelled during
+// the attempt nothing is recorded, and the delivery is attempted again once its lease ends.
+func (d *Dispatcher) deliver(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) error {
+	status, err := d.post(ctx, subscription, delivery, now)
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = nil
+	delivery.LastError = ""
+
+	switch {
+	case err != nil:
+		delivery.LastError = err.Error()
+	case status < 200 || status > 299:
+		delivery.ResponseStatus = &status
+		delivery.LastError = fmt.Sprintf("unexpected response status %d", status)
+	default:
+		delivery.ResponseStatus = &status
+		delivery.Status = models.WebhookDeliverySucceeded
+		return d.repo.UpdateDeliveryAttempt(ctx, delivery)
+	}
+
+	if delivery.Attempts >= d.maxAttempts {
+		delivery.Status = models.WebhookDeliveryFailed
+	} else {
+		delivery.Status = models.WebhookDeliveryPending
+		delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+	}
+	return d.repo.UpdateDeliveryAttempt(ctx, delivery)
+}
+
+// post posts a delivery's payload to the subscription's URL and returns the response status
+func (d *Dispatcher) post(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderEventID, delivery.EventID.String())
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+	return resp.StatusCode, nil
+}
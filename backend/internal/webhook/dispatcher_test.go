@@ -0,0 +1,229 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cmdb-lite/backend/internal/models"
+	"github.com/cmdb-lite/backend/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWebhookRepository hands out the configured deliveries once and records the outcome of
+// each attempt
+type fakeWebhookRepository struct {
+	repositories.WebhookRepository
+	subscriptions map[uuid.UUID]*models.WebhookSubscription
+	due           []*models.WebhookDelivery
+	claimErr      error
+	lease         time.Duration
+	mu            sync.Mutex
+	updated       map[uuid.UUID]models.WebhookDelivery
+}
+
+func (r *fakeWebhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	r.lease = lease
+	due := r.due
+	r.due = nil
+	return due, r.claimErr
+}
+
+func (r *fakeWebhookRepository) GetSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error) {
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, repositories.ErrWebhookNotFound
+	}
+	return subscription, nil
+}
+
+func (r *fakeWebhookRepository) UpdateDeliveryAttempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.updated[delivery.ID] = *delivery
+	return nil
+}
+
+// receivedRequest is a delivery as the receiver saw it
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver starts a receiver that responds with the status and sends each request it gets
+// on the channel
+func newReceiver(t *testing.T, status int) (*httptest.Server, chan receivedRequest) {
+	received := make(chan receivedRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedRequest{header: r.Header.Clone(), body: body}
+		if status == http.StatusFound {
+			w.Header().Set("Location", "/elsewhere")
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+// newDelivery returns a pending delivery of a CI update to the subscription
+func newDelivery(subscriptionID uuid.UUID, attempts int) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		ID:             uuid.New(),
+		SubscriptionID: subscriptionID,
+		EventID:        uuid.New(),
+		EventType:      "configuration_item.update",
+		Payload:        json.RawMessage(`{"type":"configuration_item.update"}`),
+		Status:         models.WebhookDeliveryPending,
+		Attempts:       attempts,
+	}
+}
+
+func TestDeliverDue(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	const secret = "0123456789abcdef"
+
+	tests := []struct {
+		name             string
+		status           int
+		attempts         int
+		expectedStatus   string
+		expectedResponse int
+		expectedNext     time.Time
+	}{
+		{
+			name:             "Succeeds on a 2xx response",
+			status:           http.StatusNoContent,
+			expectedStatus:   models.WebhookDeliverySucceeded,
+			expectedResponse: http.StatusNoContent,
+		},
+		{
+			name:             "Retries after an error response",
+			status:           http.StatusInternalServerError,
+			attempts:         2,
+			expectedStatus:   models.WebhookDeliveryPending,
+			expectedResponse: http.StatusInternalServerError,
+			expectedNext:     now.Add(2 * time.Minute),
+		},
+		{
+			name:             "Fails after the last attempt",
+			status:           http.StatusBadGateway,
+			attempts:         4,
+			expectedStatus:   models.WebhookDeliveryFailed,
+			expectedResponse: http.StatusBadGateway,
+		},
+		{
+			name:             "Does not follow redirects",
+			status:           http.StatusFound,
+			expectedStatus:   models.WebhookDeliveryPending,
+			expectedResponse: http.StatusFound,
+			expectedNext:     now.Add(30 * time.Second),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, received := newReceiver(t, tt.status)
+			subscription := &models.WebhookSubscription{ID: uuid.New(), URL: server.URL + "/hook", Secret: secret, Active: true}
+			delivery := newDelivery(subscription.ID, tt.attempts)
+			repo := &fakeWebhookRepository{
+				subscriptions: map[uuid.UUID]*models.WebhookSubscription{subscription.ID: subscription},
+				due:           []*models.WebhookDelivery{delivery},
+				updated:       map[uuid.UUID]models.WebhookDelivery{},
+			}
+
+			dispatcher := NewDispatcher(repo, 5*time.Second, 5, time.Second)
+			attempted, err := dispatcher.DeliverDue(context.Background(), now)
+			require.NoError(t, err)
+			assert.Equal(t, 1, attempted)
+			assert.Equal(t, 5*time.Second+leaseMargin, repo.lease)
+
+			// The receiver gets the payload, signed with the subscription's secret
+			require.Len(t, received, 1)
+			request := <-received
+			assert.Equal(t, `{"type":"configuration_item.update"}`, string(request.body))
+			assert.Equal(t, "application/json", request.header.Get("Content-Type"))
+			assert.Equal(t, "configuration_item.update", request.header.Get(HeaderEvent))
+			assert.Equal(t, delivery.EventID.String(), request.header.Get(HeaderEventID))
+			assert.Equal(t, delivery.ID.String(), request.header.Get(HeaderDelivery))
+			assert.Equal(t, strconv.FormatInt(now.Unix(), 10), request.header.Get(HeaderTimestamp))
+			assert.True(t, Verify(secret, now.Unix(), request.body, request.header.Get(HeaderSignature)))
+
+			updated, ok := repo.updated[delivery.ID]
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedStatus, updated.Status)
+			assert.Equal(t, tt.attempts+1, updated.Attempts)
+			require.NotNil(t, updated.LastAttemptAt)
+			assert.Equal(t, now, *updated.LastAttemptAt)
+			require.NotNil(t, updated.ResponseStatus)
+			assert.Equal(t, tt.expectedResponse, *updated.ResponseStatus)
+			if tt.expectedStatus == models.WebhookDeliverySucceeded {
+				assert.Empty(t, updated.LastError)
+			} else {
+				assert.Contains(t, updated.LastError, strconv.Itoa(tt.status))
+			}
+			if tt.expectedStatus == models.WebhookDeliveryPending {
+				assert.Equal(t, tt.expectedNext, updated.NextAttemptAt)
+			}
+		})
+	}
+}
+
+func TestDeliverDue_ConnectionError(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	// A receiver that is gone refuses the connection
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	subscription := &models.WebhookSubscription{ID: uuid.New(), URL: url, Secret: "0123456789abcdef", Active: true}
+	delivery := newDelivery(subscription.ID, 0)
+	repo := &fakeWebhookRepository{
+		subscriptions: map[uuid.UUID]*models.WebhookSubscription{subscription.ID: subscription},
+		due:           []*models.WebhookDelivery{delivery},
+		updated:       map[uuid.UUID]models.WebhookDelivery{},
+	}
+
+	attempted, err := NewDispatcher(repo, 5*time.Second, 5, time.Second).DeliverDue(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+
+	updated := repo.updated[delivery.ID]
+	assert.Equal(t, models.WebhookDeliveryPending, updated.Status)
+	assert.Equal(t, 1, updated.Attempts)
+	assert.Nil(t, updated.ResponseStatus)
+	assert.NotEmpty(t, updated.LastError)
+	assert.Equal(t, now.Add(30*time.Second), updated.NextAttemptAt)
+}
+
+func TestDeliverDue_DeletedSubscription(t *testing.T) {
+	repo := &fakeWebhookRepository{
+		subscriptions: map[uuid.UUID]*models.WebhookSubscription{},
+		due:           []*models.WebhookDelivery{newDelivery(uuid.New(), 0)},
+		updated:       map[uuid.UUID]models.WebhookDelivery{},
+	}
+
+	attempted, err := NewDispatcher(repo, 5*time.Second, 5, time.Second).DeliverDue(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, attempted)
+	assert.Empty(t, repo.updated)
+}
+
+func TestDeliverDue_ClaimError(t *testing.T) {
+	claimErr := errors.New("connection refused")
+	repo := &fakeWebhookRepository{claimErr: claimErr, updated: map[uuid.UUID]models.WebhookDelivery{}}
+
+	attempted, err := NewDispatcher(repo, 5*time.Second, 5, time.Second).DeliverDue(context.Background(), time.Now())
+	assert.ErrorIs(t, err, claimErr)
+	assert.Equal(t, 0, attempted)
+}
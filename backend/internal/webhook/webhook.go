@@ -0,0 +1,114 @@
+// Package webhook delivers CMDB events to the URLs of webhook subscriptions. Events are the
+// changes recorded in the audit log; each is posted as JSON, signed with the subscription's
+// secret, and retried with exponential backoff until it is accepted or runs out of attempts.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of a delivery
+const (
+	// HeaderEvent is the event type, e.g. configuration_item.update
+	HeaderEvent = "X-CMDB-Event"
+	// HeaderEventID is the ID of the event; redeliveries of an event share it
+	HeaderEventID = "X-CMDB-Event-ID"
+	// HeaderDelivery is the ID of the delivery
+	HeaderDelivery = "X-CMDB-Delivery"
+	// HeaderTimestamp is the Unix time the delivery was attempted at
+	HeaderTimestamp = "X-CMDB-Timestamp"
+	// HeaderSignature is the Signature of the timestamp and body
+	HeaderSignature = "X-CMDB-Signature"
+)
+
+// signaturePrefix names the algorithm of a signature
+const signaturePrefix = "sha256="
+
+// Backoff of failed attempts
+const (
+	// initialBackoff is the wait after the first failed attempt
+	initialBackoff = 30 * time.Second
+	// maxBackoff caps the wait between attempts
+	maxBackoff = 6 * time.Hour
+)
+
+// EntityTypes are the types of entities whose changes are events
+var EntityTypes = []string{
+	"configuration_item",
+	"relationship",
+	"ci_type",
+	"relationship_type",
+	"webhook",
+	"snapshot",
+}
+
+// Actions are the changes recorded as events
+var Actions = []string{"create", "update", "delete", "restore", "purge", "export", "import"}
+
+// EventType returns the type of the event of an action on an entity type
+func EventType(entityType, action string) string {
+	return entityType + "." + action
+}
+
+// ValidEventType reports whether an event type a subscription lists is known: either
+// "<entity type>.<action>" or "<entity type>.*" for every action on an entity type
+func ValidEventType(eventType string) bool {
+	entityType, action, ok := strings.Cut(eventType, ".")
+	if !ok || !contains(EntityTypes, entityType) {
+		return false
+	}
+	return action == "*" || contains(Actions, action)
+}
+
+// Sign returns the signature of a delivery: the hex HMAC-SHA256, keyed with the secret, of the
+// Unix timestamp, a period and the body
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether a signature was made with the secret for the timestamp and body
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// NewSecret returns a random secret for signing deliveries
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Backoff returns how long to wait before the next attempt of a delivery that has failed
+// attempts times. The wait doubles with every attempt, up to a limit.
+func Backoff(attempts int) time.Duration {
+	backoff := initialBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return backoff
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
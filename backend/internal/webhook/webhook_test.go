@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidEventType(t *testing.T) {
+	tests := []struct {
+		eventType string
+		expected  bool
+	}{
+		{"configuration_item.update", true},
+		{"relationship.delete", true},
+		{"configuration_item.*", true},
+		{"snapshot.export", true},
+		{"configuration_item", false},
+		{"configuration_item.", false},
+		{"server.update", false},
+		{"configuration_item.rename", false},
+		{"*.update", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.eventType, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ValidEventType(tt.eventType))
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"configuration_item.create"}`)
+	signature := Sign("0123456789abcdef", 1792152000, body)
+
+	// The signature is stable for the same secret, timestamp and body
+	assert.Equal(t, signature, Sign("0123456789abcdef", 1792152000, body))
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, signature)
+
+	assert.True(t, Verify("0123456789abcdef", 1792152000, body, signature))
+	assert.False(t, Verify("fedcba9876543210", 1792152000, body, signature))
+	assert.False(t, Verify("0123456789abcdef", 1792152001, body, signature))
+	assert.False(t, Verify("0123456789abcdef", 1792152000, []byte(`{}`), signature))
+}
+
+func TestNewSecret(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 64)
+
+	other, err := NewSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 2*time.Minute, Backoff(3))
+	assert.Equal(t, 6*time.Hour, Backoff(15))
+	assert.Equal(t, 6*time.Hour, Backoff(1000))
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/cmdb-lite/backend/internal/auth"
 	"github.com/cmdb-lite/backend/internal/config"
+	"github.com/cmdb-lite/backend/internal/events"
 	"github.com/cmdb-lite/backend/internal/logging"
 	"github.com/cmdb-lite/backend/internal/repositories"
 	"github.com/cmdb-lite/backend/internal/router"
@@ -69,8 +70,12 @@ func main() {
 	// Create DB wrapper
 	dbWrapper := &repositories.DB{DB: db}
 
+	// Stream changes to clients
+	hub := events.NewHub(repositories.NewEventPostgresRepository(db))
+	go hub.Listen(context.Background(), cfg.DatabaseURL())
+
 	// Setup the router
-	r := router.SetupRouter(dbWrapper, jwtManager, passwordManager, cfg)
+	r := router.SetupRouter(dbWrapper, jwtManager, passwordManager, cfg, hub)
 
 	// Start the server
 	srv := &http.Server{
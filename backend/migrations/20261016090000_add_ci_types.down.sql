@@ -0,0 +1,11 @@
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop triggers
+DROP TRIGGER IF EXISTS update_ci_types_updated_at ON ci_types;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_ci_types_name_lower;
+
+-- Drop tables
+DROP TABLE IF EXISTS ci_types;
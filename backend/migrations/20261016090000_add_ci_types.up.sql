@@ -0,0 +1,36 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+-- CI Types table
+CREATE TABLE IF NOT EXISTS ci_types (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    schema JSONB NOT NULL DEFAULT '{"type": "object"}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Type names are unique regardless of case so "server" and "Server" cannot coexist
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ci_types_name_lower ON ci_types(LOWER(name));
+
+-- Register every type already in use with a permissive schema so existing CIs stay editable
+INSERT INTO ci_types (name, description, schema)
+SELECT DISTINCT ON (LOWER(type)) type, 'Registered from existing configuration items', '{"type": "object"}'
+FROM configuration_items
+ORDER BY LOWER(type), type
+ON CONFLICT DO NOTHING;
+
+-- Give the CIs whose type differs from the registered one only in case the registered
+-- spelling, since types are looked up by exact name. The CIs are otherwise unchanged, so they
+-- keep their updated_at.
+ALTER TABLE configuration_items DISABLE TRIGGER update_configuration_items_updated_at;
+UPDATE configuration_items ci
+SET type = ci_types.name
+FROM ci_types
+WHERE LOWER(ci.type) = LOWER(ci_types.name) AND ci.type <> ci_types.name;
+ALTER TABLE configuration_items ENABLE TRIGGER update_configuration_items_updated_at;
+
+-- Apply updated_at trigger to ci_types
+CREATE TRIGGER update_ci_types_updated_at BEFORE UPDATE ON ci_types
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,13 @@
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_configuration_items_search_vector;
+
+-- Drop columns
+ALTER TABLE configuration_items DROP COLUMN IF EXISTS search_vector;
+
+-- Drop functions
+DROP FUNCTION IF EXISTS ci_search_vector(TEXT, TEXT, TEXT[], JSONB);
+DROP FUNCTION IF EXISTS ci_attribute_text(JSONB);
+DROP FUNCTION IF EXISTS ci_search_lexemes(TEXT);
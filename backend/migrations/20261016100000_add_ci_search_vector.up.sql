@@ -0,0 +1,40 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Lexemes for a piece of text: the words as the simple parser sees them plus every
+-- alphanumeric fragment, so "web-01.prod.example.com" can be found by "prod" or "example"
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION ci_search_lexemes(p_text TEXT)
+RETURNS tsvector AS $$
+    SELECT to_tsvector('simple', COALESCE(p_text, '')) ||
+           to_tsvector('simple', regexp_replace(COALESCE(p_text, ''), '[^[:alnum:]]+', ' ', 'g'));
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+-- Every string value in an attributes document, space separated
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION ci_attribute_text(p_attributes JSONB)
+RETURNS TEXT AS $$
+    SELECT COALESCE(string_agg(value #>> '{}', ' '), '')
+    FROM jsonb_path_query(COALESCE(p_attributes, '{}'), 'strict $.** ? (@.type() == "string")') AS value;
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+-- Weighted search document for a CI: name ranks highest, then type and tags, then attributes
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION ci_search_vector(p_name TEXT, p_type TEXT, p_tags TEXT[], p_attributes JSONB)
+RETURNS tsvector AS $$
+    SELECT setweight(ci_search_lexemes(p_name), 'A') ||
+           setweight(ci_search_lexemes(p_type), 'B') ||
+           setweight(ci_search_lexemes(array_to_string(p_tags, ' ')), 'B') ||
+           setweight(ci_search_lexemes(ci_attribute_text(p_attributes)), 'C');
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+-- Search document column, written by the repository on create and update
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+UPDATE configuration_items
+SET search_vector = ci_search_vector(name, type, tags, attributes);
+
+CREATE INDEX IF NOT EXISTS idx_configuration_items_search_vector ON configuration_items USING GIN(search_vector);
@@ -0,0 +1,30 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Relationship Types table
+CREATE TABLE IF NOT EXISTS relationship_types (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    inverse_name VARCHAR(100) NOT NULL DEFAULT '',
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    allowed_source_types TEXT[] NOT NULL DEFAULT '{}',
+    allowed_target_types TEXT[] NOT NULL DEFAULT '{}',
+    cardinality VARCHAR(3) NOT NULL DEFAULT 'N:M' CHECK (cardinality IN ('1:1', '1:N', 'N:M')),
+    acyclic BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Type names are unique regardless of case; the handler also keeps names and inverse names apart
+CREATE UNIQUE INDEX IF NOT EXISTS idx_relationship_types_name_lower ON relationship_types(LOWER(name));
+
+-- Register every type already in use without restrictions so existing relationships stay editable
+INSERT INTO relationship_types (name, description)
+SELECT DISTINCT ON (LOWER(type)) type, 'Registered from existing relationships'
+FROM relationships
+ORDER BY LOWER(type), type
+ON CONFLICT DO NOTHING;
+
+-- Apply updated_at trigger to relationship_types
+CREATE TRIGGER update_relationship_types_updated_at BEFORE UPDATE ON relationship_types
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
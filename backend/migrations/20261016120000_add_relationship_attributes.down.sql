@@ -0,0 +1,12 @@
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop triggers
+DROP TRIGGER IF EXISTS update_relationships_updated_at ON relationships;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_relationships_attributes;
+
+-- Drop columns
+ALTER TABLE relationships DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE relationships DROP COLUMN IF EXISTS attributes;
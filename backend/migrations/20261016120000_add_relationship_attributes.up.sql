@@ -0,0 +1,16 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Relationship attributes (port, protocol, bandwidth, ...) and modification time
+ALTER TABLE relationships ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE relationships ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+-- Existing relationships have not changed since they were created
+UPDATE relationships SET updated_at = created_at;
+
+-- Index attributes for containment and path filters
+CREATE INDEX IF NOT EXISTS idx_relationships_attributes ON relationships USING GIN (attributes);
+
+-- Apply updated_at trigger to relationships
+CREATE TRIGGER update_relationships_updated_at BEFORE UPDATE ON relationships
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,22 @@
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop triggers
+DROP TRIGGER IF EXISTS record_relationships_history ON relationships;
+DROP TRIGGER IF EXISTS record_configuration_items_history ON configuration_items;
+
+-- Drop functions
+DROP FUNCTION IF EXISTS record_relationship_history();
+DROP FUNCTION IF EXISTS record_configuration_item_history();
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_relationships_history_validity;
+DROP INDEX IF EXISTS idx_relationships_history_target_id;
+DROP INDEX IF EXISTS idx_relationships_history_source_id;
+DROP INDEX IF EXISTS idx_relationships_history_id;
+DROP INDEX IF EXISTS idx_configuration_items_history_validity;
+DROP INDEX IF EXISTS idx_configuration_items_history_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS relationships_history;
+DROP TABLE IF EXISTS configuration_items_history;
@@ -0,0 +1,91 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Configuration item history: every version of a CI with the period it was current,
+-- from valid_from up to but excluding valid_to (NULL while it is still current)
+CREATE TABLE IF NOT EXISTS configuration_items_history (
+    history_id BIGSERIAL PRIMARY KEY,
+    id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    attributes JSONB,
+    tags TEXT[],
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    valid_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    valid_to TIMESTAMP WITH TIME ZONE
+);
+
+-- Relationship history, with the same validity period
+CREATE TABLE IF NOT EXISTS relationships_history (
+    history_id BIGSERIAL PRIMARY KEY,
+    id UUID NOT NULL,
+    source_id UUID NOT NULL,
+    target_id UUID NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    attributes JSONB,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    valid_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    valid_to TIMESTAMP WITH TIME ZONE
+);
+
+-- Create indexes for point-in-time lookups
+CREATE INDEX IF NOT EXISTS idx_configuration_items_history_id ON configuration_items_history(id, valid_from);
+CREATE INDEX IF NOT EXISTS idx_configuration_items_history_validity ON configuration_items_history(valid_from, valid_to);
+CREATE INDEX IF NOT EXISTS idx_relationships_history_id ON relationships_history(id, valid_from);
+CREATE INDEX IF NOT EXISTS idx_relationships_history_source_id ON relationships_history(source_id);
+CREATE INDEX IF NOT EXISTS idx_relationships_history_target_id ON relationships_history(target_id);
+CREATE INDEX IF NOT EXISTS idx_relationships_history_validity ON relationships_history(valid_from, valid_to);
+
+-- Close the current version of a changed or deleted CI and record the new one
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_configuration_item_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE configuration_items_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO configuration_items_history (id, name, type, attributes, tags, created_at, updated_at, valid_from)
+        VALUES (NEW.id, NEW.name, NEW.type, NEW.attributes, NEW.tags, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Close the current version of a changed or deleted relationship and record the new one
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_relationship_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE relationships_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO relationships_history (id, source_id, target_id, type, attributes, created_at, updated_at, valid_from)
+        VALUES (NEW.id, NEW.source_id, NEW.target_id, NEW.type, NEW.attributes, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Seed the history with the current rows; their earlier versions are unknown, so each row
+-- is taken to have been current since it was created
+INSERT INTO configuration_items_history (id, name, type, attributes, tags, created_at, updated_at, valid_from)
+SELECT id, name, type, attributes, tags, created_at, updated_at, COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM configuration_items;
+
+INSERT INTO relationships_history (id, source_id, target_id, type, attributes, created_at, updated_at, valid_from)
+SELECT id, source_id, target_id, type, attributes, created_at, updated_at, COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM relationships;
+
+-- Apply history triggers
+CREATE TRIGGER record_configuration_items_history AFTER INSERT OR UPDATE OR DELETE ON configuration_items
+    FOR EACH ROW EXECUTE FUNCTION record_configuration_item_history();
+CREATE TRIGGER record_relationships_history AFTER INSERT OR UPDATE OR DELETE ON relationships
+    FOR EACH ROW EXECUTE FUNCTION record_relationship_history();
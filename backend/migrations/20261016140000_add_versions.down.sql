@@ -0,0 +1,43 @@
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Restore the history functions without versions
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_configuration_item_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE configuration_items_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO configuration_items_history (id, name, type, attributes, tags, created_at, updated_at, valid_from)
+        VALUES (NEW.id, NEW.name, NEW.type, NEW.attributes, NEW.tags, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_relationship_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE relationships_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO relationships_history (id, source_id, target_id, type, attributes, created_at, updated_at, valid_from)
+        VALUES (NEW.id, NEW.source_id, NEW.target_id, NEW.type, NEW.attributes, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Drop columns
+ALTER TABLE relationships_history DROP COLUMN IF EXISTS version;
+ALTER TABLE configuration_items_history DROP COLUMN IF EXISTS version;
+ALTER TABLE relationships DROP COLUMN IF EXISTS version;
+ALTER TABLE configuration_items DROP COLUMN IF EXISTS version;
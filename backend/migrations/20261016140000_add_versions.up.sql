@@ -0,0 +1,47 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Version numbers for optimistic concurrency control; each update increments the version
+-- and is only applied if the client read the current one
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE relationships ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- Keep the version of each historical row, so point-in-time queries return it too
+ALTER TABLE configuration_items_history ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE relationships_history ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- Record the version in the configuration item history
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_configuration_item_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE configuration_items_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO configuration_items_history (id, name, type, attributes, tags, version, created_at, updated_at, valid_from)
+        VALUES (NEW.id, NEW.name, NEW.type, NEW.attributes, NEW.tags, NEW.version, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Record the version in the relationship history
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_relationship_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE relationships_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO relationships_history (id, source_id, target_id, type, attributes, version, created_at, updated_at, valid_from)
+        VALUES (NEW.id, NEW.source_id, NEW.target_id, NEW.type, NEW.attributes, NEW.version, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
@@ -0,0 +1,53 @@
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Empty the trash, which cannot be represented without deleted_at
+DELETE FROM relationships WHERE deleted_at IS NOT NULL;
+DELETE FROM configuration_items WHERE deleted_at IS NOT NULL;
+
+-- Restore the history functions without the trash
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_configuration_item_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE configuration_items_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO configuration_items_history (id, name, type, attributes, tags, version, created_at, updated_at, valid_from)
+        VALUES (NEW.id, NEW.name, NEW.type, NEW.attributes, NEW.tags, NEW.version, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_relationship_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE relationships_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO relationships_history (id, source_id, target_id, type, attributes, version, created_at, updated_at, valid_from)
+        VALUES (NEW.id, NEW.source_id, NEW.target_id, NEW.type, NEW.attributes, NEW.version, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_relationships_deleted_at;
+DROP INDEX IF EXISTS idx_configuration_items_deleted_at;
+DROP INDEX IF EXISTS idx_relationships_source_target_type;
+
+-- Restore the unique constraint
+ALTER TABLE relationships ADD CONSTRAINT relationships_source_id_target_id_type_key UNIQUE (source_id, target_id, type);
+
+-- Drop columns
+ALTER TABLE relationships DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE configuration_items DROP COLUMN IF EXISTS deleted_at;
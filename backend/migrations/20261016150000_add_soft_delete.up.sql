@@ -0,0 +1,52 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Deleted CIs and the relationships removed with them stay in the trash until they are
+-- restored or purged; live rows have no deleted_at
+ALTER TABLE configuration_items ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE relationships ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Relationships in the trash must not stop the same relationship from being created again
+ALTER TABLE relationships DROP CONSTRAINT IF EXISTS relationships_source_id_target_id_type_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_relationships_source_target_type ON relationships(source_id, target_id, type) WHERE deleted_at IS NULL;
+
+-- Create indexes for the trash and the purge job
+CREATE INDEX IF NOT EXISTS idx_configuration_items_deleted_at ON configuration_items(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_relationships_deleted_at ON relationships(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Moving a CI to the trash ends its current version like a delete, and restoring it starts
+-- a new one
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_configuration_item_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE configuration_items_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.deleted_at IS NULL THEN
+        INSERT INTO configuration_items_history (id, name, type, attributes, tags, version, created_at, updated_at, valid_from)
+        VALUES (NEW.id, NEW.name, NEW.type, NEW.attributes, NEW.tags, NEW.version, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- The same for relationships
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_relationship_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE relationships_history SET valid_to = CURRENT_TIMESTAMP
+        WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.deleted_at IS NULL THEN
+        INSERT INTO relationships_history (id, source_id, target_id, type, attributes, version, created_at, updated_at, valid_from)
+        VALUES (NEW.id, NEW.source_id, NEW.target_id, NEW.type, NEW.attributes, NEW.version, NEW.created_at, NEW.updated_at, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
@@ -0,0 +1,8 @@
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_ci_external_ids_ci_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS ci_external_ids;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+-- External identifiers table: the identifiers CIs have in other systems, such as a hostname
+-- or a cloud instance ID. Each identifier belongs to one CI; a CI can have several.
+CREATE TABLE IF NOT EXISTS ci_external_ids (
+    source VARCHAR(100) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    ci_id UUID NOT NULL REFERENCES configuration_items(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (source, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ci_external_ids_ci_id ON ci_external_ids(ci_id);
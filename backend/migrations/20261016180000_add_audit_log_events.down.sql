@@ -0,0 +1,17 @@
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Drop trigger
+DROP TRIGGER IF EXISTS number_audit_logs_event ON audit_logs;
+
+-- Drop function
+DROP FUNCTION IF EXISTS number_audit_log_event();
+
+-- Drop index
+DROP INDEX IF EXISTS idx_audit_logs_seq;
+
+-- Drop column
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS seq;
+
+-- Drop sequence
+DROP SEQUENCE IF EXISTS audit_logs_seq;
@@ -0,0 +1,47 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+-- Number audit logs in the order their transactions commit, so that clients of the change
+-- feed can resume after the last event they received. Numbers are not taken on insert:
+-- transactions commit out of the order of their inserts, and a client resuming after a number
+-- would miss the events of a transaction that took a lower number but committed later.
+CREATE SEQUENCE IF NOT EXISTS audit_logs_seq;
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS seq BIGINT;
+
+-- Number the existing audit logs in the order they were recorded
+UPDATE audit_logs SET seq = numbered.seq
+FROM (SELECT id, row_number() OVER (ORDER BY changed_at, id) AS seq FROM audit_logs) numbered
+WHERE audit_logs.id = numbered.id;
+SELECT setval('audit_logs_seq', COALESCE(MAX(seq), 0) + 1, false) FROM audit_logs;
+
+-- Create index for reading the change feed
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_seq ON audit_logs(seq);
+
+-- Number an audit log as its transaction commits and notify the listeners of the change feed
+-- of changes to CIs, relationships and users. The transaction lock is held until the commit is
+-- visible, so a transaction that commits later always takes higher numbers. The notification
+-- is sent when the transaction commits and carries the audit log's number. A transaction that
+-- restores a snapshot sets cmdb.restoring, so that the restored history is not sent as changes.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION number_audit_log_event()
+RETURNS TRIGGER AS $$
+DECLARE
+    next_seq BIGINT;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('audit_logs_seq'));
+    next_seq := nextval('audit_logs_seq');
+    UPDATE audit_logs SET seq = next_seq WHERE id = NEW.id;
+
+    IF NEW.entity_type IN ('configuration_item', 'relationship', 'user')
+        AND current_setting('cmdb.restoring', true) IS DISTINCT FROM 'on' THEN
+        PERFORM pg_notify('cmdb_events', next_seq::text);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Apply the numbering trigger at commit time
+CREATE CONSTRAINT TRIGGER number_audit_logs_event AFTER INSERT ON audit_logs
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION number_audit_log_event();
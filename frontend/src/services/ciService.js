@@ -34,10 +34,12 @@ class CIService {
     }
   }
 
-  // Update an existing CI
-  async updateCI(id, ciData) {
+  // Update an existing CI; version is the version being updated, sent as If-Match
+  async updateCI(id, ciData, version) {
     try {
-      const response = await apiClient.put(`/cis/${id}`, ciData)
+      const response = await apiClient.put(`/cis/${id}`, ciData, {
+        headers: { 'If-Match': `"${version}"` }
+      })
       return response.data
     } catch (error) {
       console.error(`Error updating CI with ID ${id}:`, error)
@@ -45,10 +47,12 @@ class CIService {
     }
   }
 
-  // Delete a CI
-  async deleteCI(id) {
+  // Delete a CI; version is the current version, sent as If-Match
+  async deleteCI(id, version) {
     try {
-      await apiClient.delete(`/cis/${id}`)
+      await apiClient.delete(`/cis/${id}`, {
+        headers: { 'If-Match': `"${version}"` }
+      })
       return { success: true }
     } catch (error) {
       console.error(`Error deleting CI with ID ${id}:`, error)
@@ -34,10 +34,12 @@ class RelationshipService {
     }
   }
 
-  // Update an existing relationship
-  async updateRelationship(id, relationshipData) {
+  // Update an existing relationship; version is the version being updated, sent as If-Match
+  async updateRelationship(id, relationshipData, version) {
     try {
-      const response = await apiClient.put(`/relationships/${id}`, relationshipData)
+      const response = await apiClient.put(`/relationships/${id}`, relationshipData, {
+        headers: { 'If-Match': `"${version}"` }
+      })
       return response.data
     } catch (error) {
       console.error(`Error updating relationship with ID ${id}:`, error)
@@ -45,10 +47,12 @@ class RelationshipService {
     }
   }
 
-  // Delete a relationship
-  async deleteRelationship(id) {
+  // Delete a relationship; version is the current version, sent as If-Match
+  async deleteRelationship(id, version) {
     try {
-      await apiClient.delete(`/relationships/${id}`)
+      await apiClient.delete(`/relationships/${id}`, {
+        headers: { 'If-Match': `"${version}"` }
+      })
       return { success: true }
     } catch (error) {
       console.error(`Error deleting relationship with ID ${id}:`, error)
@@ -161,7 +161,7 @@ export const useCIStore = defineStore('ci', {
       this.error = null
       
       try {
-        const updatedCI = await ciService.updateCI(id, ciData)
+        const updatedCI = await ciService.updateCI(id, ciData, this.ciVersion(id))
         
         // Update CI in the list
         const index = this.cis.findIndex(ci => ci.id === id)
@@ -189,7 +189,7 @@ export const useCIStore = defineStore('ci', {
       this.error = null
       
       try {
-        await ciService.deleteCI(id)
+        await ciService.deleteCI(id, this.ciVersion(id))
         
         // Remove CI from the list
         this.cis = this.cis.filter(ci => ci.id !== id)
@@ -245,7 +245,7 @@ export const useCIStore = defineStore('ci', {
       this.error = null
       
       try {
-        const updatedRelationship = await relationshipService.updateRelationship(id, relationshipData)
+        const updatedRelationship = await relationshipService.updateRelationship(id, relationshipData, this.relationshipVersion(id))
         
         // Update relationship in the list
         const index = this.relationships.findIndex(rel => rel.id === id)
@@ -268,7 +268,7 @@ export const useCIStore = defineStore('ci', {
       this.error = null
       
       try {
-        await relationshipService.deleteRelationship(id)
+        await relationshipService.deleteRelationship(id, this.relationshipVersion(id))
         
         // Remove relationship from the list
         this.relationships = this.relationships.filter(rel => rel.id !== id)
@@ -283,6 +283,19 @@ export const useCIStore = defineStore('ci', {
       }
     },
     
+    // The version of a loaded CI, sent as If-Match so concurrent edits are detected
+    ciVersion(id) {
+      if (this.currentCI && this.currentCI.id === id) {
+        return this.currentCI.version
+      }
+      return this.cis.find(ci => ci.id === id)?.version
+    },
+    
+    // The version of a loaded relationship, sent as If-Match so concurrent edits are detected
+    relationshipVersion(id) {
+      return this.relationships.find(rel => rel.id === id)?.version
+    },
+    
     setFilters(filters) {
       this.filters = { ...this.filters, ...filters }
       this.pagination.page = 1 // Reset to first page when filters change